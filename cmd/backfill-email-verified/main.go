@@ -0,0 +1,41 @@
+// Command backfill-email-verified menandai akun yang dibuat sebelum verifikasi email ada sebagai
+// terverifikasi, agar tidak terblokir RequireVerifiedEmail saat checkout atau mendaftar sebagai seller.
+// Hanya user tanpa field email_verified yang diubah; akun baru selalu menyimpan field ini, sehingga
+// akun yang memang belum verifikasi tidak ikut tersentuh. Aman dijalankan berulang kali.
+//
+//	go run ./cmd/backfill-email-verified [-dry-run]
+package main
+
+import (
+	"be_ecommerce/config"
+	"context"
+	"flag"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report how many users would change")
+	flag.Parse()
+
+	config.CreateDBConnection()
+	users := config.MongoClient.Database("ecommerce").Collection("users")
+	ctx := context.Background()
+	filter := bson.M{"email_verified": bson.M{"$exists": false}}
+
+	if *dryRun {
+		count, err := users.CountDocuments(ctx, filter)
+		if err != nil {
+			log.Fatalf("Failed to count users: %v", err)
+		}
+		log.Printf("[dry-run] %d users would be marked as verified", count)
+		return
+	}
+
+	result, err := users.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"email_verified": true}})
+	if err != nil {
+		log.Fatalf("Failed to backfill email_verified: %v", err)
+	}
+	log.Printf("Marked %d existing users as verified", result.ModifiedCount)
+}
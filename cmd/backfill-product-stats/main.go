@@ -0,0 +1,97 @@
+// Command backfill-product-stats mengisi field rating, reviews dan sold pada produk yang sudah ada,
+// dipakai sekali setelah GET /products mendukung filter rating dan urutan best_selling.
+// Aman dijalankan berulang: nilai dihitung ulang dari koleksi reviews dan orders.
+//
+//	go run ./cmd/backfill-product-stats
+package main
+
+import (
+	"be_ecommerce/config"
+	"be_ecommerce/model"
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func main() {
+	config.CreateDBConnection()
+	db := config.MongoClient.Database("ecommerce")
+	products := db.Collection("products")
+	ctx := context.Background()
+
+	// Mulai dari nol agar produk tanpa review atau penjualan tetap bisa diurutkan
+	result, err := products.UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{"rating": 0.0, "reviews": 0, "sold": 0}})
+	if err != nil {
+		log.Fatalf("Failed to reset product stats: %v", err)
+	}
+	log.Printf("Reset stats of %d products", result.ModifiedCount)
+
+	// Rata-rata rating dan jumlah review per produk
+	cursor, err := db.Collection("reviews").Aggregate(ctx, bson.A{
+		bson.M{"$group": bson.M{
+			"_id":     "$product_id",
+			"rating":  bson.M{"$avg": "$rating"},
+			"reviews": bson.M{"$sum": 1},
+		}},
+	})
+	if err != nil {
+		log.Fatalf("Failed to aggregate reviews: %v", err)
+	}
+	var ratings []struct {
+		ProductID primitive.ObjectID `bson:"_id"`
+		Rating    float64            `bson:"rating"`
+		Reviews   int                `bson:"reviews"`
+	}
+	if err := cursor.All(ctx, &ratings); err != nil {
+		log.Fatalf("Failed to read review stats: %v", err)
+	}
+	for _, r := range ratings {
+		_, err := products.UpdateOne(ctx, bson.M{"_id": r.ProductID}, bson.M{"$set": bson.M{"rating": r.Rating, "reviews": r.Reviews}})
+		if err != nil {
+			log.Fatalf("Failed to update rating of product %s: %v", r.ProductID.Hex(), err)
+		}
+	}
+	log.Printf("Updated ratings of %d products", len(ratings))
+
+	// sold mengikuti stok: unit di order yang tidak dibatalkan, dikurangi unit refund yang dikembalikan ke stok.
+	// Order lama berstatus "Pending" tidak pernah mengurangi stok sehingga tidak dihitung.
+	cursor, err = db.Collection("orders").Find(ctx, bson.M{"status": bson.M{"$nin": bson.A{
+		model.OrderStatusCancelled, model.OrderStatusLegacyPending,
+	}}})
+	if err != nil {
+		log.Fatalf("Failed to fetch orders: %v", err)
+	}
+	sold := map[primitive.ObjectID]int{}
+	for cursor.Next(ctx) {
+		var order model.Order
+		if err := cursor.Decode(&order); err != nil {
+			log.Fatalf("Failed to decode order: %v", err)
+		}
+		for _, item := range order.Items {
+			sold[item.ProductID] += item.Quantity
+		}
+		for _, refund := range order.Refunds {
+			for _, item := range refund.Items {
+				if item.Restocked {
+					sold[item.ProductID] -= item.Quantity
+				}
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		log.Fatalf("Failed to read orders: %v", err)
+	}
+	cursor.Close(ctx)
+
+	for productID, quantity := range sold {
+		if quantity <= 0 {
+			continue
+		}
+		if _, err := products.UpdateOne(ctx, bson.M{"_id": productID}, bson.M{"$set": bson.M{"sold": quantity}}); err != nil {
+			log.Fatalf("Failed to update sold of product %s: %v", productID.Hex(), err)
+		}
+	}
+	log.Printf("Updated sold counts of %d products", len(sold))
+}
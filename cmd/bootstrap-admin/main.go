@@ -0,0 +1,73 @@
+// Command bootstrap-admin membuat akun admin pertama dari environment variable.
+// Hanya berjalan jika database belum memiliki admin sama sekali.
+//
+// Environment: ADMIN_USERNAME (opsional, default "admin"), ADMIN_EMAIL, ADMIN_PASSWORD
+//
+//	go run ./cmd/bootstrap-admin
+package main
+
+import (
+	"be_ecommerce/config"
+	"be_ecommerce/model"
+	"be_ecommerce/utils"
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func main() {
+	config.CreateDBConnection()
+
+	username := os.Getenv("ADMIN_USERNAME")
+	if username == "" {
+		username = "admin"
+	}
+	email := utils.NormalizeEmail(os.Getenv("ADMIN_EMAIL"))
+	password := os.Getenv("ADMIN_PASSWORD")
+	if email == "" || password == "" {
+		log.Fatal("ADMIN_EMAIL and ADMIN_PASSWORD must be set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := config.MongoClient.Database("ecommerce").Collection("users")
+
+	// Jangan membuat admin baru jika sudah ada admin
+	adminCount, err := collection.CountDocuments(ctx, bson.M{"roles": "admin"})
+	if err != nil {
+		log.Fatalf("Error checking existing admins: %v", err)
+	}
+	if adminCount > 0 {
+		log.Println("An admin account already exists, nothing to do")
+		return
+	}
+
+	if collection.FindOne(ctx, bson.M{"email": email}).Err() == nil {
+		log.Fatalf("Email %s is already registered to a non-admin account", email)
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		log.Fatalf("Error hashing password: %v", err)
+	}
+
+	admin := model.User{
+		ID:       primitive.NewObjectID(),
+		Username: username,
+		Email:    email,
+		Password: hashedPassword,
+		Roles:    []string{"admin"},
+
+		EmailVerified: true,
+	}
+	if _, err := collection.InsertOne(ctx, admin); err != nil {
+		log.Fatalf("Error creating admin: %v", err)
+	}
+
+	log.Printf("Admin account created: %s (%s)", email, admin.ID.Hex())
+}
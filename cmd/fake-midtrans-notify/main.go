@@ -0,0 +1,73 @@
+// Command fake-midtrans-notify mengirim HTTP notification bertanda tangan seperti Midtrans
+// ke server lokal, untuk menguji webhook pembayaran tanpa transaksi sandbox sungguhan.
+//
+// -order adalah payment_reference checkout ("checkout-<id>") dan -amount harus sama dengan total checkout.
+// Signature dibuat dengan MIDTRANS_SERVER_KEY yang sama dengan server.
+//
+//	go run ./cmd/fake-midtrans-notify -order checkout-<id> -amount 24000 [-status settlement] [-fraud accept]
+package main
+
+import (
+	"be_ecommerce/services"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+func main() {
+	url := flag.String("url", "http://localhost:3000/payments/midtrans/notification", "webhook URL")
+	orderID := flag.String("order", "", "Midtrans order_id (payment_reference checkout)")
+	amount := flag.String("amount", "", "gross_amount, contoh: 24000")
+	status := flag.String("status", "settlement", "transaction_status: capture, settlement, pending, deny, cancel, expire, failure, refund")
+	fraud := flag.String("fraud", "accept", "fraud_status: accept, challenge, deny")
+	flag.Parse()
+
+	if *orderID == "" || *amount == "" {
+		log.Fatal("-order and -amount are required")
+	}
+	serverKey := os.Getenv("MIDTRANS_SERVER_KEY")
+	if serverKey == "" {
+		log.Fatal("MIDTRANS_SERVER_KEY must be set")
+	}
+
+	// Midtrans mengirim gross_amount dengan dua angka desimal
+	grossAmount := *amount + ".00"
+	statusCode := "200"
+	switch *status {
+	case "pending":
+		statusCode = "201"
+	case "deny", "cancel", "expire", "failure":
+		statusCode = "202"
+	}
+
+	notification := services.MidtransNotification{
+		TransactionID:     "fake-" + time.Now().Format("20060102150405"),
+		TransactionStatus: *status,
+		TransactionTime:   time.Now().Format("2006-01-02 15:04:05"),
+		FraudStatus:       *fraud,
+		PaymentType:       "bank_transfer",
+		OrderID:           *orderID,
+		StatusCode:        statusCode,
+		GrossAmount:       grossAmount,
+		SignatureKey:      services.MidtransSignature(serverKey, *orderID, statusCode, grossAmount),
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		log.Fatalf("Error encoding notification: %v", err)
+	}
+
+	resp, err := http.Post(*url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Fatalf("Error sending notification: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	log.Printf("%s %s", resp.Status, respBody)
+}
@@ -0,0 +1,92 @@
+// Command migrate-kyc-photos memindahkan foto KYC seller dari uploads/seller_photos (disajikan publik
+// oleh app.Static) ke bucket privat STORAGE_BACKEND, lalu mengubah store_info.photo_path menjadi key-nya.
+// File lokal dihapus setelah berhasil disalin kecuali -keep. Aman dijalankan berulang kali.
+//
+//	go run ./cmd/migrate-kyc-photos [-dry-run] [-keep]
+package main
+
+import (
+	"be_ecommerce/config"
+	"be_ecommerce/model"
+	"be_ecommerce/storage"
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const legacyPrefix = "uploads/"
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report what would change")
+	keep := flag.Bool("keep", false, "keep the local files after copying them")
+	flag.Parse()
+
+	config.CreateDBConnection()
+	stores, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure storage: %v", err)
+	}
+
+	ctx := context.Background()
+	users := config.MongoClient.Database("ecommerce").Collection("users")
+
+	cursor, err := users.Find(ctx, bson.M{"store_info.photo_path": bson.M{"$regex": "^" + legacyPrefix + "seller_photos/"}})
+	if err != nil {
+		log.Fatalf("Error fetching sellers: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var migrated, failed int
+	for cursor.Next(ctx) {
+		var user model.User
+		if err := cursor.Decode(&user); err != nil || user.StoreInfo == nil {
+			log.Printf("Skipping undecodable user: %v", err)
+			continue
+		}
+		path := user.StoreInfo.PhotoPath
+		key := strings.TrimPrefix(path, legacyPrefix)
+
+		if *dryRun {
+			log.Printf("[dry-run] user %s: %s -> private %s", user.ID.Hex(), path, key)
+			migrated++
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			// File sudah dipindahkan pada run sebelumnya yang gagal mengubah photo_path
+			if exists, _ := stores.Private.Exists(ctx, key); !exists {
+				log.Printf("Photo of user %s is missing: %s", user.ID.Hex(), path)
+				failed++
+				continue
+			}
+		} else if err != nil {
+			log.Printf("Error reading photo of user %s: %v", user.ID.Hex(), err)
+			failed++
+			continue
+		} else if err := stores.Private.Put(ctx, key, data, http.DetectContentType(data)); err != nil {
+			log.Printf("Error copying photo of user %s: %v", user.ID.Hex(), err)
+			failed++
+			continue
+		}
+
+		if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"store_info.photo_path": key}}); err != nil {
+			log.Printf("Error updating photo_path of user %s: %v", user.ID.Hex(), err)
+			failed++
+			continue
+		}
+		if !*keep {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("Error removing %s: %v", path, err)
+			}
+		}
+		migrated++
+	}
+
+	log.Printf("KYC photos migrated: %d, failed: %d", migrated, failed)
+}
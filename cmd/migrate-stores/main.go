@@ -0,0 +1,165 @@
+// Command migrate-stores memindahkan data toko lama ke koleksi stores dan memperbaiki referensinya.
+//
+// Sebelumnya products.seller_id dan orders.seller_id berisi _id user, sedangkan users.seller_id
+// berisi ObjectID acak dari BecomeSeller. Setelah migrasi, ketiganya merujuk ke _id toko.
+// Aman dijalankan berulang kali.
+//
+//	go run ./cmd/migrate-stores [-dry-run]
+package main
+
+import (
+	"be_ecommerce/config"
+	"be_ecommerce/model"
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report what would change")
+	flag.Parse()
+
+	config.CreateDBConnection()
+
+	ctx := context.Background()
+	db := config.MongoClient.Database("ecommerce")
+	users := db.Collection("users")
+	stores := db.Collection("stores")
+	products := db.Collection("products")
+	orders := db.Collection("orders")
+
+	// Semua user yang pernah mengajukan toko atau memiliki role seller
+	cursor, err := users.Find(ctx, bson.M{"$or": []bson.M{
+		{"roles": "seller"},
+		{"store_info": bson.M{"$exists": true}},
+		{"store_status": bson.M{"$exists": true}},
+	}})
+	if err != nil {
+		log.Fatalf("Error fetching sellers: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var migrated, productCount, orderCount int64
+	for cursor.Next(ctx) {
+		var user model.User
+		if err := cursor.Decode(&user); err != nil {
+			log.Printf("Skipping undecodable user: %v", err)
+			continue
+		}
+
+		// Pakai toko yang sudah ada untuk user ini, lalu seller_id lama, baru ID baru
+		var existing model.Store
+		storeID := primitive.NewObjectID()
+		if err := stores.FindOne(ctx, bson.M{"owner_id": user.ID}).Decode(&existing); err == nil {
+			storeID = existing.ID
+		} else if user.SellerID != nil {
+			storeID = *user.SellerID
+		}
+
+		status := "pending"
+		if user.StoreStatus != nil {
+			status = *user.StoreStatus
+		} else if contains(user.Roles, "seller") {
+			// Seller lama yang dibuat langsung tanpa pengajuan dianggap sudah disetujui
+			status = "approved"
+		}
+
+		var name, address string
+		if user.StoreInfo != nil {
+			name = user.StoreInfo.StoreName
+			address = user.StoreInfo.FullAddress
+		}
+
+		productFilter := bson.M{"seller_id": user.ID}
+		orderFilter := bson.M{"seller_id": user.ID}
+
+		if *dryRun {
+			p, _ := products.CountDocuments(ctx, productFilter)
+			o, _ := orders.CountDocuments(ctx, orderFilter)
+			log.Printf("[dry-run] user %s -> store %s (%s): %d products, %d orders", user.ID.Hex(), storeID.Hex(), status, p, o)
+			productCount += p
+			orderCount += o
+			migrated++
+			continue
+		}
+
+		now := time.Now()
+		_, err := stores.UpdateOne(ctx, bson.M{"_id": storeID}, bson.M{
+			"$set": bson.M{"owner_id": user.ID, "status": status, "updated_at": now},
+			"$setOnInsert": bson.M{
+				"store_name":   name,
+				"full_address": address,
+				"created_at":   now,
+			},
+		}, options.Update().SetUpsert(true))
+		if err != nil {
+			log.Printf("Error saving store for user %s: %v", user.ID.Hex(), err)
+			continue
+		}
+
+		if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"seller_id": storeID}}); err != nil {
+			log.Printf("Error updating seller_id for user %s: %v", user.ID.Hex(), err)
+			continue
+		}
+
+		// Produk dan order lama yang masih menunjuk ke _id user dipindahkan ke _id toko
+		p, err := products.UpdateMany(ctx, productFilter, bson.M{"$set": bson.M{"seller_id": storeID}})
+		if err != nil {
+			log.Printf("Error updating products for user %s: %v", user.ID.Hex(), err)
+		} else {
+			productCount += p.ModifiedCount
+		}
+
+		o, err := orders.UpdateMany(ctx, orderFilter, bson.M{"$set": bson.M{"seller_id": storeID}})
+		if err != nil {
+			log.Printf("Error updating orders for user %s: %v", user.ID.Hex(), err)
+		} else {
+			orderCount += o.ModifiedCount
+		}
+		if _, err := orders.UpdateMany(ctx,
+			bson.M{"items.seller_id": user.ID},
+			bson.M{"$set": bson.M{"items.$[item].seller_id": storeID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"item.seller_id": user.ID}}}),
+		); err != nil {
+			log.Printf("Error updating order items for user %s: %v", user.ID.Hex(), err)
+		}
+
+		migrated++
+	}
+
+	log.Printf("Stores migrated: %d, products updated: %d, orders updated: %d", migrated, productCount, orderCount)
+
+	// Laporkan produk yang tidak merujuk ke toko mana pun agar bisa diperbaiki manual
+	orphans, err := products.Aggregate(ctx, bson.A{
+		bson.M{"$lookup": bson.M{"from": "stores", "localField": "seller_id", "foreignField": "_id", "as": "store"}},
+		bson.M{"$match": bson.M{"store": bson.M{"$size": 0}}},
+		bson.M{"$project": bson.M{"_id": 1, "seller_id": 1}},
+	})
+	if err != nil {
+		log.Fatalf("Error checking orphan products: %v", err)
+	}
+	defer orphans.Close(ctx)
+	for orphans.Next(ctx) {
+		var product struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			SellerID primitive.ObjectID `bson:"seller_id"`
+		}
+		if err := orphans.Decode(&product); err == nil {
+			log.Printf("Product %s references unknown store %s", product.ID.Hex(), product.SellerID.Hex())
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+// Command normalize-emails mengubah email user yang tersimpan sebelum email dinormalisasi menjadi lowercase
+// tanpa spasi, agar cocok dengan login dan unique index users.email. Email yang bentuk lowercase-nya sudah
+// dipakai akun lain tidak diubah dan dilaporkan untuk digabung manual; unique index baru bisa dibuat
+// EnsureIndexes setelah semua konflik selesai. Aman dijalankan berulang kali.
+//
+//	go run ./cmd/normalize-emails [-dry-run]
+package main
+
+import (
+	"be_ecommerce/config"
+	"be_ecommerce/utils"
+	"context"
+	"flag"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report what would change")
+	flag.Parse()
+
+	config.CreateDBConnection()
+	users := config.MongoClient.Database("ecommerce").Collection("users")
+	ctx := context.Background()
+
+	// Huruf besar atau spasi di awal/akhir
+	filter := bson.M{"email": bson.M{"$regex": `[A-Z]|^\s|\s$`}}
+	cursor, err := users.Find(ctx, filter, options.Find().SetProjection(bson.M{"email": 1}))
+	if err != nil {
+		log.Fatalf("Error fetching users: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var normalized, conflicts int
+	for cursor.Next(ctx) {
+		var user struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Email string             `bson:"email"`
+		}
+		if err := cursor.Decode(&user); err != nil {
+			log.Printf("Skipping undecodable user: %v", err)
+			continue
+		}
+		email := utils.NormalizeEmail(user.Email)
+
+		taken, err := users.CountDocuments(ctx, bson.M{"email": email, "_id": bson.M{"$ne": user.ID}})
+		if err != nil {
+			log.Fatalf("Error checking email of user %s: %v", user.ID.Hex(), err)
+		}
+		if taken > 0 {
+			log.Printf("Conflict: user %s has %s, which another account already uses", user.ID.Hex(), utils.MaskEmail(email))
+			conflicts++
+			continue
+		}
+
+		if *dryRun {
+			log.Printf("[dry-run] user %s: %s -> %s", user.ID.Hex(), utils.MaskEmail(user.Email), utils.MaskEmail(email))
+			normalized++
+			continue
+		}
+		if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"email": email}}); err != nil {
+			log.Printf("Error updating email of user %s: %v", user.ID.Hex(), err)
+			continue
+		}
+		normalized++
+	}
+
+	log.Printf("Emails normalized: %d, conflicts: %d", normalized, conflicts)
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes membuat index yang dibutuhkan aplikasi jika belum ada
+func EnsureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db := MongoClient.Database("ecommerce")
+
+	indexes := map[string][]mongo.IndexModel{
+		"refresh_tokens": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			// Hapus otomatis refresh token yang sudah kedaluwarsa
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"products": {
+			// Pencarian teks q di GET /products
+			{Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}}, Options: options.Index().SetName("products_text")},
+			// Urutan katalog; _id memecah seri untuk pagination cursor
+			{Keys: bson.D{{Key: "price", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "rating", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "sold", Value: -1}, {Key: "_id", Value: -1}}},
+			// Filter kategori dan toko yang paling sering dipakai, diurutkan terbaru atau harga
+			{Keys: bson.D{{Key: "category_id", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "category_id", Value: 1}, {Key: "price", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "sub_category_id", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "seller_id", Value: 1}, {Key: "_id", Value: -1}}},
+			// File galeri dihapus hanya jika tidak ada produk lain yang memakai hash yang sama
+			{Keys: bson.D{{Key: "images.hash", Value: 1}}},
+			// SKU varian unik di semua produk; produk tanpa varian tidak masuk index
+			{Keys: bson.D{{Key: "variants.sku", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		},
+		"users": {
+			// Email disimpan lowercase (utils.NormalizeEmail); satu email hanya untuk satu akun
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"stores": {
+			// Satu user hanya memiliki satu toko
+			{Keys: bson.D{{Key: "owner_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"favorites": {
+			// Satu dokumen favorit per user; upsert $addToSet yang bersamaan tidak membuat duplikat
+			{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"orders": {
+			// Order anak dicari lewat checkout induknya
+			{Keys: bson.D{{Key: "checkout_id", Value: 1}}},
+		},
+		"checkouts": {
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			// Job kedaluwarsa pembayaran mencari checkout AwaitingPayment terlama
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+			// Notification Midtrans mencari checkout lewat order_id Midtrans
+			{Keys: bson.D{{Key: "payment_reference", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		},
+		"returns": {
+			{Keys: bson.D{{Key: "order_id", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			{Keys: bson.D{{Key: "seller_id", Value: 1}, {Key: "status", Value: 1}}},
+			{Keys: bson.D{{Key: "status", Value: 1}}}, // Antrian eskalasi admin
+		},
+		"payment_notifications": {
+			{Keys: bson.D{{Key: "payment_reference", Value: 1}, {Key: "received_at", Value: 1}}},
+		},
+		"idempotency_keys": {
+			// Respons yang disimpan untuk Idempotency-Key dihapus setelah masa retensi
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"login_attempts": {
+			// Hitungan kegagalan login/OTP dibersihkan otomatis setelah jendela berakhir
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			log.Printf("Warning: failed to create indexes for %s: %v", collection, err)
+		}
+	}
+}
@@ -10,6 +10,7 @@ require (
 
 require (
 	github.com/golang-jwt/jwt/v4 v4.5.1
+	golang.org/x/image v0.18.0
 	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
 )
 
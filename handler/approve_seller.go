@@ -1,19 +1,17 @@
 package handler
 
 import (
-	"be_ecommerce/config"
-	"be_ecommerce/model"
+	"be_ecommerce/repository"
 	"context"
+	"log"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // ApproveSeller allows an admin to approve or reject a seller application
-func ApproveSeller(c *fiber.Ctx) error {
+func (h *Handler) ApproveSeller(c *fiber.Ctx) error {
 	var request struct {
 		UserID string `json:"user_id"` // ID pengguna
 		Status string `json:"status"`  // "approved" atau "rejected"
@@ -45,9 +43,7 @@ func ApproveSeller(c *fiber.Ctx) error {
 	}
 
 	// Temukan user di database
-	collection := config.MongoClient.Database("ecommerce").Collection("users")
-	var user model.User
-	err = collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&user)
+	user, err := h.repos.Users.FindByID(c.Context(), objectID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"status":  "error",
@@ -56,7 +52,7 @@ func ApproveSeller(c *fiber.Ctx) error {
 	}
 
 	// Update role dan store_status
-	update := bson.M{"store_status": request.Status}
+	update := repository.Fields{"store_status": request.Status}
 
 	// Jika disetujui, tambahkan role "seller" jika belum ada
 	if request.Status == "approved" {
@@ -70,7 +66,7 @@ func ApproveSeller(c *fiber.Ctx) error {
 	update["roles"] = user.Roles
 
 	// Perbarui data user di database
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": user.ID}, bson.M{"$set": update})
+	err = h.repos.Users.Update(c.Context(), user.ID, nil, update)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
@@ -78,6 +74,15 @@ func ApproveSeller(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := h.setStoreStatus(context.Background(), user.ID, request.Status); err != nil {
+		log.Println("Error updating store status:", err)
+	}
+
+	// Cabut access token lama agar role baru langsung berlaku
+	if err := h.revokeUserTokens(context.Background(), user.ID, false); err != nil {
+		log.Println("Error revoking tokens after seller review:", err)
+	}
+
 	// Tentukan pesan berdasarkan status
 	message := "Application rejected"
 	if request.Status == "approved" {
@@ -95,7 +100,7 @@ type RejectRequest struct {
 	Status string `json:"status"`
 }
 
-func RejectSeller(c *fiber.Ctx) error {
+func (h *Handler) RejectSeller(c *fiber.Ctx) error {
 	var req RejectRequest
 
 	// Parse request body
@@ -128,14 +133,10 @@ func RejectSeller(c *fiber.Ctx) error {
 		})
 	}
 
-	// Connect to the database
-	collection := config.MongoClient.Database("ecommerce").Collection("users")
-
 	// Find the user in the database
-	var user model.User
-	err = collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&user)
+	_, err = h.repos.Users.FindByID(c.Context(), objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == repository.ErrNotFound {
 			return c.Status(http.StatusNotFound).JSON(fiber.Map{
 				"message": "User not found",
 			})
@@ -147,12 +148,12 @@ func RejectSeller(c *fiber.Ctx) error {
 	}
 
 	// Ensure "seller" role is not removed but update the store_status
-	update := bson.M{
+	update := repository.Fields{
 		"store_status": req.Status,
 	}
 
 	// Update the user in the database
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, bson.M{"$set": update})
+	err = h.repos.Users.Update(c.Context(), objectID, nil, update)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update user status",
@@ -160,6 +161,15 @@ func RejectSeller(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := h.setStoreStatus(context.Background(), objectID, req.Status); err != nil {
+		log.Println("Error updating store status:", err)
+	}
+
+	// Cabut access token lama agar status toko baru langsung berlaku
+	if err := h.revokeUserTokens(context.Background(), objectID, false); err != nil {
+		log.Println("Error revoking tokens after seller rejection:", err)
+	}
+
 	// Respond with success
 	return c.JSON(fiber.Map{
 		"message": "Application rejected, user status updated",
@@ -1,75 +1,153 @@
 package handler
 
 import (
-	"be_ecommerce/config"
+	"be_ecommerce/middleware"
 	"be_ecommerce/model"
+	"be_ecommerce/repository"
 	"be_ecommerce/utils"
 	"context"
-	"fmt"
+	"errors"
+	"log"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Register handles user registration
-func Register(c *fiber.Ctx) error {
-	var user model.User
-	if err := c.BodyParser(&user); err != nil {
+// Register handles user registration.
+// Role tidak diambil dari client: pendaftaran publik selalu membuat customer.
+func (h *Handler) Register(c *fiber.Ctx) error {
+	var req model.RegisterRequest
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Error parsing request body",
 		})
 	}
 
-	// Validasi email
-	collection := config.MongoClient.Database("ecommerce").Collection("users")
-	existingUser := collection.FindOne(context.Background(), bson.M{"email": user.Email})
-	if existingUser.Err() == nil {
+	user, err := h.createUser(c.Context(), req, []string{"customer"}, false)
+	if err != nil {
+		return writeCreateUserError(c, err)
+	}
+
+	// Kirim token verifikasi email; jika gagal user masih bisa meminta kirim ulang
+	if err := h.sendVerificationEmail(c.Context(), user); err != nil {
+		log.Println("Error sending verification email:", err)
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "User registered successfully, but the verification email could not be sent. Please request a new one.",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "User registered successfully, please check your email to verify your account",
+	})
+}
+
+// ProvisionUser memungkinkan admin membuat akun staff (admin) atau customer
+func (h *Handler) ProvisionUser(c *fiber.Ctx) error {
+	var req struct {
+		model.RegisterRequest
+		Roles []string `json:"roles"`
+	}
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Email already registered",
+			"message": "Error parsing request body",
 		})
 	}
 
+	// Seller tetap harus melalui pengajuan toko, bukan dibuat langsung
+	if len(req.Roles) == 0 {
+		req.Roles = []string{"admin"}
+	}
+	validRoles := map[string]bool{"admin": true, "customer": true}
+	for _, role := range req.Roles {
+		if !validRoles[role] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid role provided",
+			})
+		}
+	}
+
+	// Akun yang dibuat admin dianggap sudah terverifikasi
+	user, err := h.createUser(c.Context(), req.RegisterRequest, req.Roles, true)
+	if err != nil {
+		return writeCreateUserError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "User provisioned successfully",
+		"user_id": user.ID.Hex(),
+		"roles":   user.Roles,
+	})
+}
+
+var (
+	errEmailRegistered = errors.New("email already registered")
+	errInvalidPayload  = errors.New("username, email and password are required")
+)
+
+// createUser memvalidasi data pendaftaran lalu menyimpan user baru dengan role yang ditentukan server
+func (h *Handler) createUser(ctx context.Context, req model.RegisterRequest, roles []string, emailVerified bool) (model.User, error) {
+	req.Email = utils.NormalizeEmail(req.Email)
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" || req.Email == "" || req.Password == "" {
+		return model.User{}, errInvalidPayload
+	}
+
+	// Validasi email; unique index users.email tetap menolak pendaftaran bersamaan dengan email yang sama
+	if _, err := h.repos.Users.FindByEmail(ctx, req.Email); err == nil {
+		return model.User{}, errEmailRegistered
+	}
+
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Error hashing password",
-		})
+		return model.User{}, err
 	}
-	user.Password = string(hashedPassword)
 
-	// Validasi dan tetapkan role
-	if len(user.Roles) == 0 {
-		user.Roles = []string{"customer"}
-	} else {
-		validRoles := map[string]bool{"admin": true, "seller": true, "customer": true}
-		for _, role := range user.Roles {
-			if !validRoles[role] {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-					"message": "Invalid role provided",
-				})
-			}
-		}
+	user := model.User{
+		ID:       primitive.NewObjectID(),
+		Username: req.Username,
+		Email:    req.Email,
+		Password: string(hashedPassword),
+		Roles:    roles,
+
+		EmailVerified: emailVerified,
 	}
 
 	// Simpan pengguna ke database
-	user.ID = primitive.NewObjectID()
-	_, err = collection.InsertOne(context.Background(), user)
-	if err != nil {
+	if err := h.repos.Users.Create(ctx, &user); err == repository.ErrDuplicateEmail {
+		return model.User{}, errEmailRegistered
+	} else if err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// writeCreateUserError memetakan error createUser ke respons HTTP
+func writeCreateUserError(c *fiber.Ctx, err error) error {
+	switch err {
+	case errInvalidPayload:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Username, email and password are required",
+		})
+	case errEmailRegistered:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Email already registered",
+		})
+	default:
+		log.Println("Error creating user:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error saving user to database",
 		})
 	}
-
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "User registered successfully",
-	})
 }
 
+// dummyPasswordHash adalah hash bcrypt (cost default) yang dibandingkan saat email tidak terdaftar
+var dummyPasswordHash = []byte("$2a$10$ClKoc4wSSzOWtGuXbFFRxOfd0Vyaf6Icmdl6KLsglpGCul7WmKj.i")
+
 // Login handles user login
-func Login(c *fiber.Ctx) error {
+func (h *Handler) Login(c *fiber.Ctx) error {
 	var req model.LoginRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -77,53 +155,90 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	fmt.Println("Email:", req.Email)
-	fmt.Println("Password:", req.Password)
+	// Tolak lebih awal jika akun atau IP sedang dikunci karena terlalu banyak percobaan gagal
+	emailKey := "login-email:" + utils.NormalizeEmail(req.Email)
+	ipKey := "login-ip:" + c.IP()
+	if wait := h.lockedFor(c.Context(), emailKey, ipKey); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
 
 	// Cari user di database
-	var user model.User
-	collection := config.MongoClient.Database("ecommerce").Collection("users")
-	err := collection.FindOne(c.Context(), bson.M{"email": req.Email}).Decode(&user)
+	user, err := h.repos.Users.FindByEmail(c.Context(), req.Email)
 	if err != nil {
-		fmt.Println("User not found for email:", req.Email)
+		// bcrypt tetap dijalankan dan percobaan tetap dihitung, sehingga waktu respons dan
+		// lockout untuk email yang tidak terdaftar sama dengan password yang salah
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
+		h.recordFailure(c.Context(), emailKey, accountAttemptPolicy)
+		h.recordFailure(c.Context(), ipKey, ipAttemptPolicy)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"message": "Invalid email or password",
 		})
 	}
 
-	fmt.Println("User found:", user.Email, "Roles:", user.Roles)
-
 	// Verifikasi password
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		fmt.Println("Password mismatch for email:", req.Email)
+		log.Println("Failed login attempt for:", utils.MaskEmail(user.Email), "from IP:", c.IP())
+		h.recordFailure(c.Context(), emailKey, accountAttemptPolicy)
+		h.recordFailure(c.Context(), ipKey, ipAttemptPolicy)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"message": "Invalid email or password",
 		})
 	}
+	h.resetAttempts(c.Context(), emailKey)
+
+	// Tolak login akun yang sedang di-suspend
+	if user.IsSuspended() {
+		return c.Status(fiber.StatusForbidden).JSON(suspendedResponse(user.Suspension))
+	}
+
+	// Akun dengan 2FA aktif harus memasukkan kode TOTP terlebih dahulu lewat /login/2fa
+	if user.TwoFactorEnabled {
+		challenge, err := utils.GenerateChallengeJWT(user.ID.Hex(), user.TokenVersion, user.ChallengeVersion)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Could not generate token",
+			})
+		}
+		return c.JSON(fiber.Map{
+			"status":          "two_factor_required",
+			"message":         "Enter the code from your authenticator app",
+			"challenge_token": challenge,
+		})
+	}
+
+	return h.completeLogin(c, user, false)
+}
 
+// completeLogin menerbitkan access token dan refresh token lalu mengirim respons login
+func (h *Handler) completeLogin(c *fiber.Ctx, user model.User, mfa bool) error {
 	// Generate token, tambahkan seller_id jika ada
 	var sellerID string
 	if user.SellerID != nil {
 		sellerID = user.SellerID.Hex()
 	}
 
-	token, err := utils.GenerateJWT(user.ID.Hex(), user.Roles[0], sellerID)
+	// Pastikan user memiliki minimal satu role
+	if len(user.Roles) == 0 {
+		user.Roles = []string{"customer"}
+	}
+
+	token, refreshToken, _, err := h.issueTokens(c.Context(), user, primitive.NilObjectID, mfa)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Could not generate token",
 		})
 	}
 
-	fmt.Println("Generated token:", token)
-
 	// Response login
 	response := fiber.Map{
-		"status":  "success",
-		"message": "Login successful",
-		"role":    user.Roles[0],
-		"token":   token,
-		"user_id": user.ID.Hex(),
+		"status":        "success",
+		"message":       "Login successful",
+		"role":          user.Roles[0],
+		"roles":         user.Roles,
+		"token":         token,
+		"refresh_token": refreshToken,
+		"user_id":       user.ID.Hex(),
 	}
 
 	// Jika user memiliki seller_id, tambahkan seller info
@@ -131,5 +246,27 @@ func Login(c *fiber.Ctx) error {
 		response["seller_id"] = sellerID
 	}
 
+	// Beri tahu client jika role user mewajibkan 2FA tetapi belum diaktifkan
+	if !mfa {
+		for _, role := range user.Roles {
+			if middleware.RoleRequiresMFA(role) {
+				response["two_factor_setup_required"] = true
+				break
+			}
+		}
+	}
+
 	return c.JSON(response)
-}
\ No newline at end of file
+}
+
+// suspendedResponse membuat body respons untuk akun yang di-suspend
+func suspendedResponse(suspension *model.Suspension) fiber.Map {
+	response := fiber.Map{
+		"message": "Account is suspended",
+		"reason":  suspension.Reason,
+	}
+	if suspension.ExpiresAt != nil {
+		response["suspended_until"] = suspension.ExpiresAt
+	}
+	return response
+}
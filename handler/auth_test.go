@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/services"
+	"context"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestDummyPasswordHashCostsTheSameAsRealHashes(t *testing.T) {
+	cost, err := bcrypt.Cost(dummyPasswordHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost != bcrypt.DefaultCost {
+		t.Errorf("cost = %d, want %d so unknown emails take as long as wrong passwords", cost, bcrypt.DefaultCost)
+	}
+}
+
+func TestCreateUserNormalizesEmail(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	ctx := context.Background()
+
+	user, err := env.h.createUser(ctx, model.RegisterRequest{Username: "budi", Email: " Budi@Example.com ", Password: "secret"}, []string{"customer"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "budi@example.com" {
+		t.Errorf("email = %q, want budi@example.com", user.Email)
+	}
+	if _, err := env.repos.Users.FindByEmail(ctx, "BUDI@example.COM"); err != nil {
+		t.Errorf("lookup with different case: %v", err)
+	}
+
+	_, err = env.h.createUser(ctx, model.RegisterRequest{Username: "budi2", Email: "budi@EXAMPLE.com", Password: "secret"}, []string{"customer"}, false)
+	if err != errEmailRegistered {
+		t.Errorf("err = %v, want errEmailRegistered", err)
+	}
+}
@@ -1,59 +1,28 @@
 package handler
 
 import (
-	"be_ecommerce/config"
-	"be_ecommerce/utils"
+	"be_ecommerce/imaging"
+	"be_ecommerce/middleware"
+	"be_ecommerce/repository"
 	"context"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // BecomeSeller handles requests for a user to become a seller
-func BecomeSeller(c *fiber.Ctx) error {
-	// Ambil token dari header Authorization
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Missing authorization token",
-		})
-	}
-
-	// Hapus prefix "Bearer " dari token
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-
-	// Verifikasi dan parsing token
-	claims, err := utils.ParseToken(token)
+func (h *Handler) BecomeSeller(c *fiber.Ctx) error {
+	// Ambil user_id dari token yang sudah diverifikasi middleware
+	objectID, err := middleware.CurrentUserID(c)
 	if err != nil {
-		log.Println("Invalid token:", err)
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Invalid token",
-		})
-	}
-
-	// Ambil user_id dari token
-	userID, ok := claims["user_id"].(string)
-	if !ok || userID == "" {
-		log.Println("User ID not found in token claims")
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
 			"message": "Invalid token: user_id missing",
 		})
 	}
-
-	// Konversi user_id ke ObjectID MongoDB
-	objectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		log.Println("Invalid user ID format:", userID)
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid user ID format",
-		})
-	}
+	userID := objectID.Hex()
 
 	// Ambil data dari form-data
 	storeName := c.FormValue("store_name")
@@ -74,73 +43,76 @@ func BecomeSeller(c *fiber.Ctx) error {
 		})
 	}
 
-	// Tentukan direktori upload
-	uploadDir := "uploads/seller_photos"
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(uploadDir, 0755); err != nil {
-			log.Println("Error creating upload directory:", err)
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"message": "Failed to prepare upload directory",
-			})
-		}
+	// Foto KYC disimpan di bucket privat; photo_path berisi key, bukan URL publik
+	photo, err := readUpload(file, imaging.MaxFileSize)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Photo must not be larger than 5 MB",
+		})
 	}
-
-	// Tentukan nama file dan simpan file
-	photoPath := filepath.Join(uploadDir, userID+".jpg")
-	if err := c.SaveFile(file, photoPath); err != nil {
+	contentType := http.DetectContentType(photo)
+	if !strings.HasPrefix(contentType, "image/") {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Photo must be an image",
+		})
+	}
+	photoPath := "seller_photos/" + userID + ".jpg"
+	if err := h.blobs.Private.Put(c.Context(), photoPath, photo, contentType); err != nil {
 		log.Println("Error saving photo file:", err)
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to save photo",
 		})
 	}
 
-	// Buat seller_id baru
-	sellerID := primitive.NewObjectID()
+	user, err := h.repos.Users.FindByID(c.Context(), objectID)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "User not found",
+		})
+	}
 
-	// Filter untuk mencari user berdasarkan ID
-	filter := bson.M{"_id": objectID}
+	// Buat (atau pakai ulang) toko milik user; seller_id selalu merujuk ke _id toko
+	sellerID, err := h.saveStoreApplication(context.Background(), user, storeName, fullAddress)
+	if err != nil {
+		log.Println("Error saving store:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to create store",
+		})
+	}
 
-	// Update data user menjadi seller
-	update := bson.M{
-		"$set": bson.M{
-			"store_info": bson.M{
-				"store_name":   storeName,
-				"full_address": fullAddress,
-				"nik":          nik,
-				"photo_path":   photoPath,
-			},
-			"store_status": "pending",  // 🔹 Toko baru dibuat, status default "pending"
-			"seller_id":    sellerID, // 🔹 Tambahkan seller_id ke user
-		},
-		"$addToSet": bson.M{
-			"roles": "seller",
+	// Simpan pengajuan seller. Role "seller" baru diberikan ApproveSeller; pengajuan ulang mencabutnya
+	// sampai admin menyetujui lagi, sehingga toko yang pending tidak bisa memakai endpoint seller
+	update := repository.Fields{
+		"store_info": bson.M{
+			"store_name":   storeName,
+			"full_address": fullAddress,
+			"nik":          nik,
+			"photo_path":   photoPath,
 		},
+		"store_status": "pending",  // 🔹 Toko baru dibuat, status default "pending"
+		"roles":        removeRole(user.Roles, "seller"),
 	}
 
-	// Gunakan koleksi dari MongoDB
-	collection := config.MongoClient.Database("ecommerce").Collection("users")
-
 	// Update dokumen di MongoDB
-	result, err := collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Users.Update(c.Context(), objectID, nil, update)
 	if err != nil {
 		log.Println("Error updating user to become seller:", err)
+		if err == repository.ErrNotFound {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "User not found",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update user",
 		})
 	}
 
-	if result.ModifiedCount == 0 {
-		log.Println("No user updated. Either user not found or already a seller:", userID)
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"message": "User not found or already a seller",
-		})
-	}
-
-	log.Println("User successfully updated to become seller:", userID)
+	log.Println("Seller application submitted:", userID)
 	return c.JSON(fiber.Map{
-		"message":    "User successfully became a seller",
+		"message":    "Seller application submitted, waiting for admin approval",
 		"seller_id":  sellerID.Hex(), // 🔹 Return seller_id agar bisa disimpan di FE
 		"store_status": "pending", // 🔹 Status toko dikembalikan ke FE
 		"photo_path": photoPath,
+		"photo_url":  h.kycPhotoURL(photoPath), // Signed URL, hanya berlaku sebentar
 	})
 }
\ No newline at end of file
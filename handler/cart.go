@@ -1,27 +1,32 @@
 package handler
 
 import (
-	"be_ecommerce/config"
+	"be_ecommerce/middleware"
 	"be_ecommerce/model"
-	"context"
+	"be_ecommerce/repository"
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // AddToCart menambahkan produk ke keranjang pengguna
-func AddToCart(c *fiber.Ctx) error {
+func (h *Handler) AddToCart(c *fiber.Ctx) error {
 	var cartItem model.CartItem
 	if err := c.BodyParser(&cartItem); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid request body"})
 	}
 
+	// User diambil dari token, bukan dari body request
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Unauthorized"})
+	}
+	cartItem.UserID = userID.Hex()
+
 	// Validasi input
-	if cartItem.UserID == "" || cartItem.ProductID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "User ID and Product ID are required"})
+	if cartItem.ProductID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Product ID is required"})
 	}
 
 	// Konversi ProductID ke ObjectID
@@ -32,20 +37,26 @@ func AddToCart(c *fiber.Ctx) error {
 
 	cartItem.Quantity = 1                      // Default jumlah jika tidak diberikan
 	cartItem.ProductID = productObjectID.Hex() // Pastikan format string
-
-	// Ambil koleksi keranjang
-	collection := config.MongoClient.Database("ecommerce").Collection("carts")
+	if cartItem.VariantID != "" {
+		variantObjectID, err := primitive.ObjectIDFromHex(cartItem.VariantID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid Variant ID format"})
+		}
+		cartItem.VariantID = variantObjectID.Hex()
+	}
 
 	// Periksa apakah keranjang sudah ada untuk user
-	var cart model.Cart
-	err = collection.FindOne(context.Background(), bson.M{"user_id": cartItem.UserID}).Decode(&cart)
-	if err == mongo.ErrNoDocuments {
+	cart, err := h.repos.Carts.Get(c.Context(), cartItem.UserID)
+	if err == repository.ErrNotFound {
 		// Jika tidak ada keranjang, buat baru
+		if ok, err := h.checkCartStock(c, productObjectID, cartItem.VariantID, cartItem.Quantity); !ok {
+			return err
+		}
 		cart = model.Cart{
 			UserID:   cartItem.UserID,
 			Products: []model.CartItem{cartItem},
 		}
-		_, err = collection.InsertOne(context.Background(), cart)
+		err = h.repos.Carts.Save(c.Context(), cart)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to create cart"})
 		}
@@ -53,8 +64,9 @@ func AddToCart(c *fiber.Ctx) error {
 		// Jika keranjang sudah ada, tambahkan produk
 		found := false
 		for i, item := range cart.Products {
-			if item.ProductID == cartItem.ProductID {
+			if item.ProductID == cartItem.ProductID && item.VariantID == cartItem.VariantID {
 				cart.Products[i].Quantity += cartItem.Quantity
+				cartItem.Quantity = cart.Products[i].Quantity
 				found = true
 				break
 			}
@@ -63,8 +75,13 @@ func AddToCart(c *fiber.Ctx) error {
 			cart.Products = append(cart.Products, cartItem)
 		}
 
+		// Jumlah di keranjang tidak boleh melebihi stok
+		if ok, err := h.checkCartStock(c, productObjectID, cartItem.VariantID, cartItem.Quantity); !ok {
+			return err
+		}
+
 		// Perbarui keranjang
-		_, err = collection.UpdateOne(context.Background(), bson.M{"user_id": cartItem.UserID}, bson.M{"$set": bson.M{"products": cart.Products}})
+		err = h.repos.Carts.Save(c.Context(), cart)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to update cart"})
 		}
@@ -75,22 +92,62 @@ func AddToCart(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Product added to cart successfully"})
 }
 
-// FetchCart mengambil data keranjang berdasarkan user_id
-func FetchCart(c *fiber.Ctx) error {
-	// Ambil user_id dari query
-	userID := c.Query("user_id")
-	if userID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "user_id is required"})
+// checkCartStock memastikan produk (dan variannya, jika produk dijual per varian) ada, tokonya aktif
+// dan stoknya cukup untuk quantity. Jika tidak, respons error sudah ditulis dan ok bernilai false.
+func (h *Handler) checkCartStock(c *fiber.Ctx, productID primitive.ObjectID, variantID string, quantity int) (ok bool, err error) {
+	product, err := h.repos.Products.FindByID(c.Context(), productID)
+	if err == repository.ErrNotFound {
+		return false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Product not found"})
+	}
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to fetch product details"})
+	}
+	active, err := h.sellerActive(c.Context(), product.SellerID)
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to fetch store details"})
+	}
+	if !active {
+		return false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Product is not available"})
+	}
+
+	missing := repository.OutOfStockItem{
+		ProductID: product.ID,
+		Name:      product.Name,
+		Requested: quantity,
+		Available: product.Stock,
+	}
+	variant, err := selectVariant(product, variantID)
+	if err != nil {
+		return false, writeVariantError(c, err)
+	}
+	if !variant.ID.IsZero() {
+		missing.VariantID = variant.ID
+		missing.SKU = variant.SKU
+		missing.Available = variant.Stock
+	}
+
+	if missing.Available < quantity {
+		return false, c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Some items are out of stock",
+			"code":    "out_of_stock",
+			"items":   []repository.OutOfStockItem{missing},
+		})
 	}
+	return true, nil
+}
 
-	// Ambil koleksi keranjang
-	cartCollection := config.MongoClient.Database("ecommerce").Collection("carts")
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
+// FetchCart mengambil data keranjang milik user yang sedang login
+func (h *Handler) FetchCart(c *fiber.Ctx) error {
+	// Ambil user_id dari token
+	objUserID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Unauthorized"})
+	}
+	userID := objUserID.Hex()
 
 	// Cari keranjang berdasarkan user_id
-	var cart model.Cart
-	err := cartCollection.FindOne(context.Background(), bson.M{"user_id": userID}).Decode(&cart)
-	if err == mongo.ErrNoDocuments {
+	cart, err := h.repos.Carts.Get(c.Context(), userID)
+	if err == repository.ErrNotFound {
 		// Jika keranjang tidak ditemukan, kembalikan keranjang kosong
 		return c.JSON(fiber.Map{
 			"products": []fiber.Map{},
@@ -104,15 +161,14 @@ func FetchCart(c *fiber.Ctx) error {
 	products := make([]fiber.Map, len(cart.Products))
 	for i, item := range cart.Products {
 		// Ambil data produk berdasarkan product_id
-		var product model.Product
 		productID, err := primitive.ObjectIDFromHex(item.ProductID)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid product_id"})
 		}
 
-		err = productCollection.FindOne(context.Background(), bson.M{"_id": productID}).Decode(&product)
+		product, err := h.repos.Products.FindByID(c.Context(), productID)
 		if err != nil {
-			if err == mongo.ErrNoDocuments {
+			if err == repository.ErrNotFound {
 				// Jika produk tidak ditemukan, gunakan data default
 				products[i] = fiber.Map{
 					"product_id":  item.ProductID,
@@ -138,6 +194,24 @@ func FetchCart(c *fiber.Ctx) error {
 			"quantity":    item.Quantity,
 			"total_price": product.Price * item.Quantity,
 		}
+
+		// Harga dan gambar varian menggantikan data produk
+		if item.VariantID != "" {
+			variant, err := selectVariant(product, item.VariantID)
+			if err != nil {
+				products[i]["name"] = product.Name + " (variant unavailable)"
+				products[i]["variant_id"] = item.VariantID
+				continue
+			}
+			products[i]["variant_id"] = variant.ID.Hex()
+			products[i]["sku"] = variant.SKU
+			products[i]["variant"] = product.VariantLabel(variant)
+			products[i]["price"] = variant.Price
+			products[i]["total_price"] = variant.Price * item.Quantity
+			if variant.Image != "" {
+				products[i]["image"] = variant.Image
+			}
+		}
 	}
 
 	// Kembalikan hanya properti "products"
@@ -147,10 +221,10 @@ func FetchCart(c *fiber.Ctx) error {
 }
 
 // UpdateCartItem memperbarui kuantitas produk dalam keranjang
-func UpdateCartItem(c *fiber.Ctx) error {
+func (h *Handler) UpdateCartItem(c *fiber.Ctx) error {
 	var request struct {
-		UserID    string `json:"user_id"`
 		ProductID string `json:"product_id"`
+		VariantID string `json:"variant_id"`
 		Quantity  int    `json:"quantity"`
 	}
 
@@ -163,11 +237,13 @@ func UpdateCartItem(c *fiber.Ctx) error {
 	// Debugging Input Data
 	fmt.Printf("Request Data: %+v\n", request)
 
-	// Validasi Input
-	if request.UserID == "" {
-		fmt.Println("Error: Missing user_id")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "User ID is required"})
+	// User diambil dari token
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Unauthorized"})
 	}
+
+	// Validasi Input
 	if request.ProductID == "" {
 		fmt.Println("Error: Missing product_id")
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Product ID is required"})
@@ -177,12 +253,19 @@ func UpdateCartItem(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Quantity must be greater than 0"})
 	}
 
+	// Jumlah di keranjang tidak boleh melebihi stok
+	productObjectID, err := primitive.ObjectIDFromHex(request.ProductID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid Product ID format"})
+	}
+	if ok, err := h.checkCartStock(c, productObjectID, request.VariantID, request.Quantity); !ok {
+		return err
+	}
+
 	// Proses Update Cart
-	collection := config.MongoClient.Database("ecommerce").Collection("carts")
-	var cart model.Cart
-	err := collection.FindOne(context.Background(), bson.M{"user_id": request.UserID}).Decode(&cart)
-	if err == mongo.ErrNoDocuments {
-		fmt.Println("Error: Cart not found for user_id", request.UserID)
+	cart, err := h.repos.Carts.Get(c.Context(), userID.Hex())
+	if err == repository.ErrNotFound {
+		fmt.Println("Error: Cart not found for user_id", userID.Hex())
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Cart not found"})
 	} else if err != nil {
 		fmt.Printf("Error: Failed to fetch cart: %v\n", err)
@@ -192,7 +275,7 @@ func UpdateCartItem(c *fiber.Ctx) error {
 	// Update Quantity
 	updated := false
 	for i, item := range cart.Products {
-		if item.ProductID == request.ProductID {
+		if item.ProductID == request.ProductID && item.VariantID == request.VariantID {
 			cart.Products[i].Quantity = request.Quantity
 			updated = true
 			fmt.Printf("Updated product %s with new quantity %d\n", request.ProductID, request.Quantity)
@@ -206,7 +289,7 @@ func UpdateCartItem(c *fiber.Ctx) error {
 	}
 
 	// Simpan Perubahan
-	_, err = collection.UpdateOne(context.Background(), bson.M{"user_id": request.UserID}, bson.M{"$set": bson.M{"products": cart.Products}})
+	err = h.repos.Carts.Save(c.Context(), cart)
 	if err != nil {
 		fmt.Printf("Error: Failed to update cart: %v\n", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to update cart"})
@@ -214,10 +297,10 @@ func UpdateCartItem(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"message": "Cart updated successfully"})
 }
-func RemoveFromCart(c *fiber.Ctx) error {
+func (h *Handler) RemoveFromCart(c *fiber.Ctx) error {
 	var request struct {
-		UserID    string `json:"user_id"`
 		ProductID string `json:"product_id"`
+		VariantID string `json:"variant_id"`
 	}
 
 	if err := c.BodyParser(&request); err != nil {
@@ -226,18 +309,22 @@ func RemoveFromCart(c *fiber.Ctx) error {
 		})
 	}
 
-	if request.UserID == "" || request.ProductID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "User ID and Product ID are required",
+	// User diambil dari token
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
 		})
 	}
 
-	collection := config.MongoClient.Database("ecommerce").Collection("carts")
-	filter := bson.M{"user_id": request.UserID}
-	update := bson.M{"$pull": bson.M{"products": bson.M{"product_id": request.ProductID}}}
+	if request.ProductID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Product ID is required",
+		})
+	}
 
-	result, err := collection.UpdateOne(context.Background(), filter, update)
-	if err != nil || result.ModifiedCount == 0 {
+	err = h.repos.Carts.RemoveItem(c.Context(), userID.Hex(), request.ProductID, request.VariantID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to remove product from cart",
 		})
@@ -1,17 +1,15 @@
 package handler
 
 import (
-	"be_ecommerce/config"
 	"be_ecommerce/model"
-	"context"
+	"be_ecommerce/repository"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // AddCategory handles adding a new category
-func AddCategory(c *fiber.Ctx) error {
+func (h *Handler) AddCategory(c *fiber.Ctx) error {
 	var category model.Category
 	if err := c.BodyParser(&category); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -21,9 +19,7 @@ func AddCategory(c *fiber.Ctx) error {
 	}
 
 	// Validasi duplikasi kategori berdasarkan nama
-	collection := config.MongoClient.Database("ecommerce").Collection("categories")
-	existingCategory := model.Category{}
-	err := collection.FindOne(context.Background(), bson.M{"name": category.Name}).Decode(&existingCategory)
+	_, err := h.repos.Categories.FindByName(c.Context(), category.Name)
 	if err == nil {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 			"message": "Category already exists",
@@ -34,7 +30,7 @@ func AddCategory(c *fiber.Ctx) error {
 	category.ID = primitive.NewObjectID()
 
 	// Simpan kategori ke database
-	_, err = collection.InsertOne(context.Background(), category)
+	err = h.repos.Categories.Create(c.Context(), &category)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to save category",
@@ -49,7 +45,7 @@ func AddCategory(c *fiber.Ctx) error {
 }
 
 // AddSubCategory handles adding a new sub-category to an existing category
-func AddSubCategory(c *fiber.Ctx) error {
+func (h *Handler) AddSubCategory(c *fiber.Ctx) error {
 	var request struct {
 		CategoryID primitive.ObjectID `json:"category_id"`
 		Name       string             `json:"name"`
@@ -63,9 +59,7 @@ func AddSubCategory(c *fiber.Ctx) error {
 	}
 
 	// Periksa apakah kategori ada
-	collection := config.MongoClient.Database("ecommerce").Collection("categories")
-	var category model.Category
-	err := collection.FindOne(context.Background(), bson.M{"_id": request.CategoryID}).Decode(&category)
+	category, err := h.repos.Categories.FindByID(c.Context(), request.CategoryID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "Category not found",
@@ -86,12 +80,9 @@ func AddSubCategory(c *fiber.Ctx) error {
 		ID:   primitive.NewObjectID(),
 		Name: request.Name,
 	}
-	category.SubCategories = append(category.SubCategories, subCategory)
 
 	// Perbarui kategori dengan sub-kategori baru
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": request.CategoryID}, bson.M{
-		"$set": bson.M{"sub_categories": category.SubCategories},
-	})
+	err = h.repos.Categories.AddSubCategory(c.Context(), request.CategoryID, subCategory)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to add sub-category",
@@ -106,25 +97,14 @@ func AddSubCategory(c *fiber.Ctx) error {
 }
 
 // GetCategories handles fetching all categories and their sub-categories
-func GetCategories(c *fiber.Ctx) error {
-	collection := config.MongoClient.Database("ecommerce").Collection("categories")
-
-	cursor, err := collection.Find(context.Background(), bson.M{})
+func (h *Handler) GetCategories(c *fiber.Ctx) error {
+	categories, err := h.repos.Categories.List(c.Context())
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to fetch categories",
 			"error":   err.Error(),
 		})
 	}
-	defer cursor.Close(context.Background())
-
-	var categories []model.Category
-	if err := cursor.All(context.Background(), &categories); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to parse categories",
-			"error":   err.Error(),
-		})
-	}
 
 	return c.JSON(fiber.Map{
 		"message": "Categories fetched successfully",
@@ -132,7 +112,7 @@ func GetCategories(c *fiber.Ctx) error {
 	})
 }
 // UpdateCategory handles updating a category by its ID
-func UpdateCategory(c *fiber.Ctx) error {
+func (h *Handler) UpdateCategory(c *fiber.Ctx) error {
 	id := c.Params("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -151,11 +131,12 @@ func UpdateCategory(c *fiber.Ctx) error {
 		})
 	}
 
-	collection := config.MongoClient.Database("ecommerce").Collection("categories")
-	filter := bson.M{"_id": objectID}
-	update := bson.M{"$set": bson.M{"name": payload.Name}}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Categories.Rename(c.Context(), objectID, payload.Name)
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Category not found",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update category",
@@ -168,7 +149,7 @@ func UpdateCategory(c *fiber.Ctx) error {
 }
 
 // UpdateSubCategory handles updating a sub-category by its ID
-func UpdateSubCategory(c *fiber.Ctx) error {
+func (h *Handler) UpdateSubCategory(c *fiber.Ctx) error {
 	id := c.Params("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -188,11 +169,12 @@ func UpdateSubCategory(c *fiber.Ctx) error {
 		})
 	}
 
-	collection := config.MongoClient.Database("ecommerce").Collection("categories")
-	filter := bson.M{"_id": payload.CategoryID, "sub_categories._id": objectID}
-	update := bson.M{"$set": bson.M{"sub_categories.$.name": payload.Name}}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Categories.RenameSubCategory(c.Context(), payload.CategoryID, objectID, payload.Name)
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Sub-category not found",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update sub-category",
@@ -205,7 +187,7 @@ func UpdateSubCategory(c *fiber.Ctx) error {
 }
 
 // DeleteCategory handles deleting a category by its ID
-func DeleteCategory(c *fiber.Ctx) error {
+func (h *Handler) DeleteCategory(c *fiber.Ctx) error {
 	id := c.Params("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -214,8 +196,12 @@ func DeleteCategory(c *fiber.Ctx) error {
 		})
 	}
 
-	collection := config.MongoClient.Database("ecommerce").Collection("categories")
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": objectID})
+	err = h.repos.Categories.Delete(c.Context(), objectID)
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Category not found",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to delete category",
@@ -228,7 +214,7 @@ func DeleteCategory(c *fiber.Ctx) error {
 }
 
 // DeleteSubCategory handles deleting a sub-category by its ID
-func DeleteSubCategory(c *fiber.Ctx) error {
+func (h *Handler) DeleteSubCategory(c *fiber.Ctx) error {
 	id := c.Params("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -247,13 +233,12 @@ func DeleteSubCategory(c *fiber.Ctx) error {
 		})
 	}
 
-	collection := config.MongoClient.Database("ecommerce").Collection("categories")
-	filter := bson.M{"_id": payload.CategoryID}
-	update := bson.M{
-		"$pull": bson.M{"sub_categories": bson.M{"_id": objectID}},
+	err = h.repos.Categories.RemoveSubCategory(c.Context(), payload.CategoryID, objectID)
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Category not found",
+		})
 	}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to delete sub-category",
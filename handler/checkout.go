@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"be_ecommerce/middleware"
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newCheckout memecah hasil priceOrder menjadi satu Order per toko di bawah satu Checkout induk.
+// Setiap order anak memiliki status, ongkir dan total sendiri sehingga seller hanya melihat bagiannya.
+func newCheckout(userID primitive.ObjectID, shipping string, priced pricedOrder) (model.Checkout, []model.Order) {
+	now := time.Now()
+	checkout := model.Checkout{
+		ID:              primitive.NewObjectID(),
+		UserID:          userID,
+		Subtotal:        priced.Subtotal,
+		ShippingCost:    priced.ShippingCost,
+		TotalAmount:     priced.Total,
+		ShippingAddress: shipping,
+		Status:          model.OrderStatusAwaitingPayment,
+		CreatedAt:       now,
+	}
+	checkout.PaymentReference = "checkout-" + checkout.ID.Hex()
+
+	orders := make([]model.Order, 0, len(priced.Sellers))
+	for _, seller := range priced.Sellers {
+		order := model.Order{
+			ID:              primitive.NewObjectID(),
+			UserID:          userID,
+			SellerID:        seller.SellerID,
+			CheckoutID:      checkout.ID,
+			Items:           seller.Items,
+			Subtotal:        seller.Subtotal,
+			TotalAmount:     seller.Total,
+			ShippingCost:    seller.ShippingCost,
+			ShippingAddress: shipping,
+			Status:          model.OrderStatusAwaitingPayment,
+			StatusHistory: []model.StatusChange{{
+				To:        model.OrderStatusAwaitingPayment,
+				At:        now,
+				ActorID:   userID,
+				ActorRole: actorCustomer,
+			}},
+			CreatedAt: now,
+		}
+		orders = append(orders, order)
+		checkout.OrderIDs = append(checkout.OrderIDs, order.ID)
+	}
+	return checkout, orders
+}
+
+// cancelCheckout membatalkan checkout yang gagal dibayar beserta semua order anaknya
+// agar stok kembali tersedia. Error hanya dicatat karena dipanggil saat menangani error lain.
+func (h *Handler) cancelCheckout(ctx context.Context, checkout model.Checkout, orders []model.Order) {
+	for _, order := range orders {
+		err := h.changeOrderStatus(ctx, order, model.OrderStatusCancelled, systemActor, "Payment could not be created", nil)
+		if err != nil {
+			log.Println("Error cancelling order", order.ID.Hex(), "of checkout", checkout.ID.Hex()+":", err)
+		}
+	}
+	if err := h.repos.Checkouts.Update(ctx, checkout.ID, repository.Fields{"status": model.OrderStatusCancelled}); err != nil {
+		log.Println("Error cancelling checkout", checkout.ID.Hex()+":", err)
+	}
+}
+
+// orderIDsHex mengubah ID order anak menjadi hex string untuk respons
+func orderIDsHex(orders []model.Order) []string {
+	ids := make([]string, 0, len(orders))
+	for _, order := range orders {
+		ids = append(ids, order.ID.Hex())
+	}
+	return ids
+}
+
+// **GET /checkouts/:checkout_id** → Detail checkout beserta order per toko milik pembeli
+func (h *Handler) GetCheckoutHandler(c *fiber.Ctx) error {
+	checkoutID, err := primitive.ObjectIDFromHex(c.Params("checkout_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Checkout ID"})
+	}
+
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	checkout, err := h.repos.Checkouts.FindForUser(c.Context(), checkoutID, userID)
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Checkout not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch checkout"})
+	}
+
+	orders, err := h.repos.Orders.ListByCheckout(c.Context(), checkout.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch orders"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Checkout fetched successfully",
+		"data":    fiber.Map{"checkout": checkout, "orders": orders},
+	})
+}
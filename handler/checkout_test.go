@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/services"
+	"context"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCheckoutSplitsOrdersAndDecrementsStock(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	otherSeller := env.store(t, "approved")
+	a := env.product(t, env.sellerID, 50000, 5)
+	b := env.product(t, otherSeller, 20000, 1)
+
+	status, body := env.do(t, fiber.MethodPost, "/checkout", orderBody(2*50000+20000+2*testShippingRate, orderItem(a, 2), orderItem(b, 1)))
+	if status != fiber.StatusCreated {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	if ids, _ := body["order_ids"].([]interface{}); len(ids) != 2 {
+		t.Errorf("order_ids = %v, want one order per store", body["order_ids"])
+	}
+	if got := env.stock(t, a); got != 3 {
+		t.Errorf("stock of a = %d, want 3", got)
+	}
+	if got := env.stock(t, b); got != 0 {
+		t.Errorf("stock of b = %d, want 0", got)
+	}
+
+	// Setiap order anak hanya berisi item tokonya sendiri dengan ongkir dan total sendiri
+	checkoutID, _ := primitive.ObjectIDFromHex(body["checkout_id"].(string))
+	orders, err := env.repos.Orders.ListByCheckout(context.Background(), checkoutID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals := map[primitive.ObjectID]int{}
+	for _, order := range orders {
+		for _, item := range order.Items {
+			if item.SellerID != order.SellerID {
+				t.Errorf("order of %s contains an item of %s", order.SellerID.Hex(), item.SellerID.Hex())
+			}
+		}
+		totals[order.SellerID] = order.TotalAmount
+	}
+	if totals[env.sellerID] != 2*50000+testShippingRate || totals[otherSeller] != 20000+testShippingRate {
+		t.Errorf("order totals = %v, want %d and %d", totals, 2*50000+testShippingRate, 20000+testShippingRate)
+	}
+}
+
+func TestCheckoutRejectsChangedPrice(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+
+	status, body := env.do(t, fiber.MethodPost, "/checkout", orderBody(40000+testShippingRate, orderItem(a, 1)))
+	if status != fiber.StatusConflict || body["code"] != "price_changed" {
+		t.Fatalf("status = %d, body = %v, want 409 price_changed", status, body)
+	}
+	if got := env.stock(t, a); got != 5 {
+		t.Errorf("stock of a = %d, want 5", got)
+	}
+}
+
+func TestCheckoutOutOfStockKeepsStock(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	b := env.product(t, env.sellerID, 20000, 1)
+
+	status, body := env.do(t, fiber.MethodPost, "/checkout", orderBody(2*50000+2*20000+testShippingRate, orderItem(a, 2), orderItem(b, 2)))
+	if status != fiber.StatusConflict || body["code"] != "out_of_stock" {
+		t.Fatalf("status = %d, body = %v, want 409 out_of_stock", status, body)
+	}
+	if got := env.stock(t, a); got != 5 {
+		t.Errorf("stock of a = %d, want 5", got)
+	}
+	orders, err := env.repos.Orders.ListByUser(context.Background(), env.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 0 {
+		t.Errorf("%d orders saved, want none", len(orders))
+	}
+}
+
+func TestPaymentFailureCancelsCheckoutAndRestoresStock(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomeError)
+	a := env.product(t, env.sellerID, 50000, 5)
+
+	status, body := env.do(t, fiber.MethodPost, "/payment", orderBody(2*50000+testShippingRate, orderItem(a, 2)))
+	if status != fiber.StatusInternalServerError {
+		t.Fatalf("status = %d, body = %v, want 500", status, body)
+	}
+	if got := env.stock(t, a); got != 5 {
+		t.Errorf("stock of a = %d, want 5", got)
+	}
+	orders, err := env.repos.Orders.ListByUser(context.Background(), env.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].Status != model.OrderStatusCancelled {
+		t.Errorf("orders = %+v, want one Cancelled order", orders)
+	}
+}
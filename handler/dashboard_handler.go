@@ -1,12 +1,9 @@
 package handler
 
 import (
-	"be_ecommerce/config"
-	"context"
+	"be_ecommerce/middleware"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // DashboardData represents the response structure for the dashboard
@@ -17,56 +14,23 @@ type DashboardData struct {
 }
 
 // GetDashboardData retrieves statistics for the seller dashboard
-func GetDashboardData(c *fiber.Ctx) error {
-	sellerID := c.Query("seller_id")
-	if sellerID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Seller ID is required"})
-	}
-
-	ctx := context.TODO()
-	db := config.MongoClient.Database("ecommerce")
-
-	// Get total sales
-	ordersCollection := db.Collection("orders")
-	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"seller_id": sellerID}}},
-		{{Key: "$group", Value: bson.M{"_id": nil, "totalSales": bson.M{"$sum": "$total"}}}},
-	}
-	cursor, err := ordersCollection.Aggregate(ctx, pipeline)
+func (h *Handler) GetDashboardData(c *fiber.Ctx) error {
+	// Toko seller diambil dari token; orders.seller_id merujuk ke _id toko
+	sellerID, err := middleware.CurrentSellerID(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get total sales"})
-	}
-
-	var result struct{ TotalSales int }
-	if cursor.Next(ctx) {
-		cursor.Decode(&result)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	// Get pending orders count
-	pendingOrdersCount, err := ordersCollection.CountDocuments(ctx, bson.M{"seller_id": sellerID, "status": "Pending"})
+	stats, err := h.repos.Orders.SellerStats(c.Context(), sellerID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get pending orders"})
-	}
-
-	// Get total revenue
-	var totalRevenueResult struct{ TotalRevenue int }
-	revenuePipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"seller_id": sellerID}}},
-		{{Key: "$group", Value: bson.M{"_id": nil, "totalRevenue": bson.M{"$sum": "$total"}}}},
-	}
-	revenueCursor, err := ordersCollection.Aggregate(ctx, revenuePipeline)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get total revenue"})
-	}
-	if revenueCursor.Next(ctx) {
-		revenueCursor.Decode(&totalRevenueResult)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get dashboard data"})
 	}
 
 	// Return response
 	dashboardData := DashboardData{
-		TotalSales:   result.TotalSales,
-		PendingOrders: int(pendingOrdersCount),
-		TotalRevenue: totalRevenueResult.TotalRevenue,
+		TotalSales:   stats.ItemsSold,
+		PendingOrders: stats.PendingOrders,
+		TotalRevenue: stats.Revenue,
 	}
 
 	return c.JSON(dashboardData)
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"be_ecommerce/repository"
+	"be_ecommerce/search"
+	"be_ecommerce/services"
+	"be_ecommerce/storage"
+	"context"
+	"log"
+)
+
+// Handler berisi dependency semua handler HTTP dan job terjadwal. Dibuat sekali di main lewat New,
+// lalu method-nya didaftarkan di router; test bisa membuatnya dengan repository.NewMemory().
+type Handler struct {
+	repos    repository.Repositories
+	payments services.PaymentProvider
+	blobs    storage.Stores
+
+	// searchIndex berisi semua produk; dibangun saat server start lalu diperbarui setiap
+	// produk berubah dan dibangun ulang berkala agar perubahan kategori/nama toko ikut masuk
+	searchIndex *search.Index
+}
+
+// New menyusun Handler dari repository (contoh: repository.NewMongo(db)), payment gateway
+// (contoh: services.NewPaymentProvider()) dan bucket file unggahan (contoh: storage.FromEnv())
+func New(repos repository.Repositories, payments services.PaymentProvider, blobs storage.Stores) *Handler {
+	h := &Handler{repos: repos, payments: payments, blobs: blobs, searchIndex: search.NewIndex()}
+
+	// Mock mengirim hasil simulasi langsung ke sini, tanpa HTTP notification
+	if mock, ok := payments.(*services.MockProvider); ok {
+		mock.SetNotifier(func(payment services.PaymentStatus) {
+			if _, err := h.applyPaymentStatus(context.Background(), payment); err != nil {
+				log.Println("Error applying mock payment status:", err)
+			}
+		})
+	}
+	return h
+}
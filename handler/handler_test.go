@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"be_ecommerce/middleware"
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"be_ecommerce/services"
+	"be_ecommerce/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testShippingRate = 10000
+
+// testEnv adalah Handler di atas repository memori, mock payment gateway dan storage lokal sementara,
+// dengan satu pembeli dan satu toko yang sedang login di semua request
+type testEnv struct {
+	h        *Handler
+	repos    repository.Repositories
+	mock     *services.MockProvider
+	blobs    storage.Stores
+	blobDir  string // Direktori sementara berisi bucket public dan private
+	app      *fiber.App
+	userID   primitive.ObjectID
+	sellerID primitive.ObjectID
+}
+
+func newTestEnv(t *testing.T, outcome string) *testEnv {
+	t.Helper()
+	t.Setenv("SHIPPING_FLAT_RATE", strconv.Itoa(testShippingRate))
+
+	mock, err := services.NewMockProvider(outcome, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := &testEnv{
+		repos:  repository.NewMemory(),
+		mock:   mock,
+		userID: primitive.NewObjectID(),
+	}
+	env.blobDir = t.TempDir()
+	env.blobs = storage.Stores{
+		Public:  storage.NewLocalStore(filepath.Join(env.blobDir, "public"), "/uploads", nil),
+		Private: storage.NewLocalStore(filepath.Join(env.blobDir, "private"), "/"+storage.PrivateFilesURL, []byte("test-key")),
+	}
+	env.h = New(env.repos, mock, env.blobs)
+	env.sellerID = env.store(t, "approved")
+	// Notification simulasi dikirim dari goroutine; test menerapkan status pembayaran sendiri
+	mock.SetNotifier(func(services.PaymentStatus) {})
+
+	env.app = fiber.New()
+	env.app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalUserID, env.userID.Hex())
+		c.Locals(middleware.LocalSellerID, env.sellerID.Hex())
+		return c.Next()
+	})
+	env.app.Post("/checkout", env.h.CheckoutHandler)
+	env.app.Post("/payment", env.h.CreatePaymentHandler)
+	env.app.Post("/orders/:order_id/refunds", env.h.CreateSellerRefundHandler)
+	env.app.Put("/orders/:order_id", env.h.UpdateSellerOrderHandler)
+	env.app.Post("/cart", env.h.AddToCart)
+	return env
+}
+
+// store menyimpan toko berstatus status beserta pemiliknya
+func (e *testEnv) store(t *testing.T, status string) primitive.ObjectID {
+	t.Helper()
+	owner := model.User{ID: primitive.NewObjectID(), Username: "seller", Email: primitive.NewObjectID().Hex() + "@example.com", Roles: []string{"seller"}}
+	if err := e.repos.Users.Create(context.Background(), &owner); err != nil {
+		t.Fatal(err)
+	}
+	store := model.Store{OwnerID: owner.ID, Name: "Store", Status: status}
+	if err := e.repos.Stores.Save(context.Background(), &store); err != nil {
+		t.Fatal(err)
+	}
+	return store.ID
+}
+
+// product menyimpan produk milik sellerID dengan harga dan stok tertentu
+func (e *testEnv) product(t *testing.T, sellerID primitive.ObjectID, price, stock int) primitive.ObjectID {
+	t.Helper()
+	product := model.Product{Name: "Product", Price: price, Stock: stock, SellerID: sellerID}
+	if err := e.repos.Products.Create(context.Background(), &product); err != nil {
+		t.Fatal(err)
+	}
+	return product.ID
+}
+
+func (e *testEnv) stock(t *testing.T, id primitive.ObjectID) int {
+	t.Helper()
+	product, err := e.repos.Products.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product.Stock
+}
+
+// do mengirim body sebagai JSON dan mengembalikan status serta body respons
+func (e *testEnv) do(t *testing.T, method, path string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := e.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+// orderBody membuat body checkout/payment dengan total yang dihitung dari harga produk
+func orderBody(amount int, items ...model.OrderItem) fiber.Map {
+	return fiber.Map{"shipping": "Jl. Merdeka 1", "amount": amount, "items": items}
+}
+
+func orderItem(id primitive.ObjectID, quantity int) model.OrderItem {
+	return model.OrderItem{ProductID: id, Quantity: quantity}
+}
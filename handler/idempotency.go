@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"be_ecommerce/middleware"
+	"be_ecommerce/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	defaultIdempotencyHours   = 24          // Masa retensi jika IDEMPOTENCY_RETENTION_HOURS tidak diisi
+	idempotencyLockTimeout    = time.Minute // Request pertama dianggap mati jika lease-nya tidak diperpanjang selama ini
+)
+
+// idempotencyRetention membaca lama respons disimpan dari IDEMPOTENCY_RETENTION_HOURS
+func idempotencyRetention() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_RETENTION_HOURS"))
+	if err != nil || hours < 1 {
+		hours = defaultIdempotencyHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// Idempotent dipasang sebelum handler yang membuat checkout atau tagihan. Jika request membawa
+// header Idempotency-Key, respons pertama disimpan dan retry dengan key yang sama mendapat respons
+// yang sama tanpa menjalankan handler lagi. Respons 5xx tidak disimpan agar bisa dicoba ulang.
+// Harus dipasang setelah middleware.Protected karena key dibatasi per user.
+func (h *Handler) Idempotent(c *fiber.Ctx) error {
+	key := strings.TrimSpace(c.Get(idempotencyKeyHeader))
+	if key == "" {
+		return c.Next()
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Idempotency-Key is too long"})
+	}
+
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Unauthorized"})
+	}
+
+	hash := sha256.Sum256(c.Body())
+	// Presisi waktu MongoDB hanya milidetik; CreatedAt dipakai untuk mengenali reservasi ini
+	now := time.Now().Truncate(time.Millisecond)
+	record := model.IdempotencyRecord{
+		ID:          strings.Join([]string{userID.Hex(), c.Method(), c.Path(), key}, " "),
+		UserID:      userID,
+		RequestHash: hex.EncodeToString(hash[:]),
+		Status:      model.IdempotencyProcessing,
+		CreatedAt:   now,
+		LockedUntil: now.Add(idempotencyLockTimeout),
+		ExpiresAt:   now.Add(idempotencyRetention()),
+	}
+
+	existing, reserved, err := h.repos.Idempotency.Reserve(c.Context(), &record)
+	if err != nil {
+		log.Println("Error reserving idempotency key:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to process Idempotency-Key"})
+	}
+	if !reserved {
+		if existing.RequestHash != record.RequestHash {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Idempotency-Key was already used with a different request",
+			})
+		}
+		if existing.Status != model.IdempotencyCompleted {
+			c.Set(fiber.HeaderRetryAfter, "1")
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "A request with this Idempotency-Key is still being processed",
+			})
+		}
+
+		// Putar ulang respons asli, termasuk checkout_id dan token pembayaran yang sama
+		c.Set(idempotencyReplayedHeader, "true")
+		if existing.ResponseType != "" {
+			c.Set(fiber.HeaderContentType, existing.ResponseType)
+		}
+		return c.Status(existing.ResponseStatus).Send(existing.ResponseBody)
+	}
+
+	stop := h.holdIdempotencyKey(record)
+	err = c.Next()
+	stop()
+	if err != nil {
+		h.releaseIdempotencyKey(c, record)
+		return err
+	}
+
+	status := c.Response().StatusCode()
+	if status >= fiber.StatusInternalServerError {
+		h.releaseIdempotencyKey(c, record)
+		return nil
+	}
+	contentType := string(c.Response().Header.ContentType())
+	err = h.repos.Idempotency.Complete(c.Context(), record.ID, record.CreatedAt, status, contentType, c.Response().Body())
+	if err != nil {
+		log.Println("Error saving idempotent response:", err)
+	}
+	return nil
+}
+
+// holdIdempotencyKey memperpanjang LockedUntil reservasi secara berkala selama handler berjalan,
+// sehingga request yang lama menunggu payment gateway tidak diambil alih retry dengan key yang sama.
+// stop harus dipanggil setelah handler selesai dan sebelum reservasi di-Complete atau di-Release.
+func (h *Handler) holdIdempotencyKey(record model.IdempotencyRecord) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(idempotencyLockTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				err := h.repos.Idempotency.Extend(context.Background(), record.ID, record.CreatedAt, now.Add(idempotencyLockTimeout))
+				if err != nil {
+					log.Println("Error extending idempotency key:", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// releaseIdempotencyKey menghapus reservasi agar retry dengan key yang sama dijalankan ulang
+func (h *Handler) releaseIdempotencyKey(c *fiber.Ctx, record model.IdempotencyRecord) {
+	if err := h.repos.Idempotency.Release(c.Context(), record.ID, record.CreatedAt); err != nil {
+		log.Println("Error releasing idempotency key:", err)
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// attemptPolicy menentukan kapan sebuah kunci dikunci dan berapa lama
+type attemptPolicy struct {
+	Threshold int           // Jumlah kegagalan sebelum mulai dikunci
+	BaseLock  time.Duration // Lama kunci pertama, dilipatgandakan tiap kegagalan berikutnya
+	MaxLock   time.Duration
+	Window    time.Duration // Kegagalan lebih lama dari ini tidak dihitung lagi
+}
+
+var (
+	accountAttemptPolicy = attemptPolicy{Threshold: 5, BaseLock: time.Minute, MaxLock: time.Hour, Window: time.Hour}
+	ipAttemptPolicy      = attemptPolicy{Threshold: 20, BaseLock: time.Minute, MaxLock: time.Hour, Window: time.Hour}
+)
+
+const maxOTPAttempts = 5 // OTP dibatalkan setelah 5 kali salah
+
+// lockedFor mengembalikan sisa waktu kunci terlama dari kunci-kunci yang diberikan
+func (h *Handler) lockedFor(ctx context.Context, keys ...string) time.Duration {
+	until, err := h.repos.LoginAttempts.LockedUntil(ctx, keys...)
+	if err != nil {
+		log.Println("Error checking login attempts:", err)
+		return 0
+	}
+	if remaining := time.Until(until); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// recordFailure menambah jumlah kegagalan untuk kunci dan menerapkan kunci progresif.
+// Hitungan dinaikkan secara atomik di repository sehingga percobaan paralel tidak membaca angka yang sama.
+// Mengembalikan true jika kunci sedang terkunci setelah kegagalan ini.
+func (h *Handler) recordFailure(ctx context.Context, key string, policy attemptPolicy) bool {
+	now := time.Now()
+
+	// Kegagalan sebelum jendela tidak dihitung lagi; hitungan dimulai dari awal
+	attempt, err := h.repos.LoginAttempts.RecordFailure(ctx, key, now, now.Add(-policy.Window), now.Add(policy.Window+policy.MaxLock))
+	if err != nil {
+		log.Println("Error recording login failure:", err)
+		return false
+	}
+	if attempt.Failures < policy.Threshold {
+		return attempt.LockedUntil.After(now)
+	}
+
+	// 1 menit, 2 menit, 4 menit, ... hingga MaxLock; Lock tidak memperpendek kunci yang lebih lama
+	exponent := float64(attempt.Failures - policy.Threshold)
+	lock := time.Duration(float64(policy.BaseLock) * math.Pow(2, exponent))
+	if lock > policy.MaxLock || lock <= 0 {
+		lock = policy.MaxLock
+	}
+	if err := h.repos.LoginAttempts.Lock(ctx, key, now.Add(lock)); err != nil {
+		log.Println("Error locking login attempts:", err)
+	}
+	return true
+}
+
+// resetAttempts menghapus hitungan kegagalan setelah berhasil
+func (h *Handler) resetAttempts(ctx context.Context, keys ...string) {
+	if err := h.repos.LoginAttempts.Reset(ctx, keys...); err != nil {
+		log.Println("Error resetting login attempts:", err)
+	}
+}
+
+// tooManyAttempts membuat respons 429 dengan header Retry-After
+func tooManyAttempts(c *fiber.Ctx, wait time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, fmt.Sprintf("%d", int(wait.Seconds())+1))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"message":             "Too many failed attempts, please try again later",
+		"retry_after_seconds": int(wait.Seconds()) + 1,
+	})
+}
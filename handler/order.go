@@ -1,137 +1,107 @@
 package handler
 
 import (
-	"be_ecommerce/config"
+	"be_ecommerce/middleware"
 	"be_ecommerce/model"
-	"context"
-	"time"
+	"be_ecommerce/repository"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // CheckoutHandler menangani proses checkout dan menyimpan order ke database
-func CheckoutHandler(c *fiber.Ctx) error {
+func (h *Handler) CheckoutHandler(c *fiber.Ctx) error {
+	// Amount adalah total yang ditampilkan ke pembeli; harga, ongkir dan total tetap dihitung server
 	var input struct {
-		UserID       string            `json:"user_id"`
-		Shipping     string            `json:"shipping"`
-		Amount       int               `json:"amount"`
-		Items        []model.OrderItem `json:"items"`
-		ShippingCost int               `json:"shipping_cost"`
+		Shipping string            `json:"shipping"`
+		Amount   int               `json:"amount"`
+		Items    []model.OrderItem `json:"items"`
 	}
 
 	if err := c.BodyParser(&input); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(input.UserID)
+	// User diambil dari token, bukan dari body request
+	userID, err := middleware.CurrentUserID(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid User ID"})
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	order := model.Order{
-		ID:              primitive.NewObjectID(),
-		UserID:          userID,
-		Items:           input.Items,
-		TotalAmount:     input.Amount,
-		ShippingCost:    input.ShippingCost,
-		ShippingAddress: input.Shipping,
-		Status:          "Pending",
-		CreatedAt:       time.Now(),
+	// Hitung ulang harga dari data produk terbaru
+	priced, err := h.priceOrder(c.Context(), input.Items)
+	if err != nil {
+		return writePricingError(c, err)
+	}
+	if input.Amount != priced.Total {
+		return priceChanged(c, input.Amount, priced)
 	}
 
-	collection := config.MongoClient.Database("ecommerce").Collection("orders")
-	_, err = collection.InsertOne(context.TODO(), order)
+	// Satu order per toko di bawah satu checkout
+	checkout, orders := newCheckout(userID, input.Shipping, priced)
+
+	// Stok dikurangi dan semua order disimpan dalam satu transaksi
+	err = h.placeCheckout(c.Context(), &checkout, orders, "")
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to place order"})
+		return writeStockError(c, err, fiber.Map{"error": "Failed to place order"})
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message":  "Order placed successfully",
-		"order_id": order.ID.Hex(),
+		"message":      "Order placed successfully",
+		"checkout_id":  checkout.ID.Hex(),
+		"order_ids":    orderIDsHex(orders),
+		"total_amount": checkout.TotalAmount,
 	})
 }
 
-// GetOrdersHandler mengambil daftar order berdasarkan user_id
-func GetOrdersHandler(c *fiber.Ctx) error {
-	userID := c.Query("user_id")
-	if userID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "User ID is required"})
-	}
-
-	objID, err := primitive.ObjectIDFromHex(userID)
+// GetOrdersHandler mengambil daftar order milik user yang sedang login
+func (h *Handler) GetOrdersHandler(c *fiber.Ctx) error {
+	objID, err := middleware.CurrentUserID(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid User ID"})
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	var orders []model.Order
-	collection := config.MongoClient.Database("ecommerce").Collection("orders")
-
-	cursor, err := collection.Find(context.TODO(), bson.M{"user_id": objID})
+	orders, err := h.repos.Orders.ListByUser(c.Context(), objID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch orders"})
 	}
-	defer cursor.Close(context.TODO())
-
-	for cursor.Next(context.TODO()) {
-		var order model.Order
-		if err := cursor.Decode(&order); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error decoding order data"})
-		}
-		orders = append(orders, order)
-	}
 
 	return c.JSON(fiber.Map{"message": "Orders fetched successfully", "data": orders})
 }
 
-func GetOrdersBySellerHandler(c *fiber.Ctx) error {
-	sellerID := c.Query("seller_id")
-	if sellerID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Seller ID is required"})
-	}
-
-	objID, err := primitive.ObjectIDFromHex(sellerID)
+func (h *Handler) GetOrdersBySellerHandler(c *fiber.Ctx) error {
+	// Seller diambil dari token
+	objID, err := middleware.CurrentSellerID(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Seller ID"})
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	var orders []model.Order
-	collection := config.MongoClient.Database("ecommerce").Collection("orders")
-
 	// Menemukan semua pesanan untuk seller
-	cursor, err := collection.Find(context.TODO(), bson.M{"seller_id": objID})
+	orders, err := h.repos.Orders.ListBySeller(c.Context(), objID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch orders"})
 	}
-	defer cursor.Close(context.TODO())
-
-	// Decode data ke dalam array orders
-	for cursor.Next(context.TODO()) {
-		var order model.Order
-		if err := cursor.Decode(&order); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error decoding order data"})
-		}
-		orders = append(orders, order)
-	}
 
 	// Mengembalikan data order
 	return c.JSON(fiber.Map{"message": "Orders fetched successfully", "data": orders})
 }
 
 // **GET /orders/:order_id** → Ambil detail pesanan berdasarkan ID untuk seller
-func GetSellerOrderDetailsHandler(c *fiber.Ctx) error {
+func (h *Handler) GetSellerOrderDetailsHandler(c *fiber.Ctx) error {
 	orderID := c.Params("order_id")
 	objID, err := primitive.ObjectIDFromHex(orderID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Order ID"})
 	}
 
-	var order model.Order
-	collection := config.MongoClient.Database("ecommerce").Collection("orders")
+	sellerID, err := middleware.CurrentSellerID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
 
-	// Cari order berdasarkan orderID
-	err = collection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&order)
+	// Cari order berdasarkan orderID, hanya jika milik seller yang login
+	order, err := h.repos.Orders.FindForSeller(c.Context(), objID, sellerID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
 	}
@@ -141,7 +111,7 @@ func GetSellerOrderDetailsHandler(c *fiber.Ctx) error {
 }
 
 // **PUT /orders/:order_id** → Update status pesanan oleh seller
-func UpdateSellerOrderHandler(c *fiber.Ctx) error {
+func (h *Handler) UpdateSellerOrderHandler(c *fiber.Ctx) error {
 	// Ambil orderID dari params
 	orderID := c.Params("order_id")
 	objID, err := primitive.ObjectIDFromHex(orderID)
@@ -149,20 +119,12 @@ func UpdateSellerOrderHandler(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Order ID"})
 	}
 
-	// Struct untuk data yang ingin diperbarui
+	// Struct untuk data yang ingin diperbarui.
+	// Item, total dan payment token tidak bisa diubah seller karena dibuat server.
 	var updateData struct {
 		Status          string `json:"status"`
-		ShippingAddress string `json:"shipping_address"`
-		Items           []struct {
-			ProductID string `json:"product_id"`
-			Name      string `json:"name"`
-			Quantity  int    `json:"quantity"`
-			Price     int    `json:"price"`
-			SellerID  string `json:"seller_id"`
-		} `json:"items"`
-		TotalAmount    int    `json:"total_amount"`
-		ShippingCost   int    `json:"shipping_cost"`
-		PaymentToken   string `json:"payment_token"`
+		Note            string `json:"note"`
+		ShippingAddress string `json:"shipping_address"` // Kosong berarti alamat tidak diubah
 	}
 
 	// Parsing data request body
@@ -170,38 +132,43 @@ func UpdateSellerOrderHandler(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	// Cek apakah status valid (opsional, tergantung kebutuhan)
-	validStatuses := []string{"Pending", "Confirmed", "Shipped", "Delivered"}
-	statusValid := false
-	for _, validStatus := range validStatuses {
-		if updateData.Status == validStatus {
-			statusValid = true
-			break
-		}
+	sellerID, err := middleware.CurrentSellerID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
-	if !statusValid {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid status"})
+
+	// Order hanya bisa diubah oleh seller pemiliknya
+	order, err := h.repos.Orders.FindForSeller(c.Context(), objID, sellerID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
 	}
 
-	// Collection orders
-	collection := config.MongoClient.Database("ecommerce").Collection("orders")
+	// Data yang akan diupdate selain status; alamat hanya bisa diubah sebelum barang dikirim
+	updateFields := repository.Fields{}
+	if address := strings.TrimSpace(updateData.ShippingAddress); address != "" {
+		if !unshippedStatuses[order.Status] {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Shipping address can only be changed before the order is shipped"})
+		}
+		updateFields["shipping_address"] = address
+	}
 
-	// Data yang akan diupdate
-	updateFields := bson.M{
-		"$set": bson.M{
-			"status":           updateData.Status,
-			"shipping_address": updateData.ShippingAddress,
-			"items":            updateData.Items,
-			"total_amount":     updateData.TotalAmount,
-			"shipping_cost":    updateData.ShippingCost,
-			"payment_token":    updateData.PaymentToken,
-		},
+	// Tanpa perubahan status, cukup perbarui data lainnya
+	if updateData.Status == "" || updateData.Status == order.Status {
+		if len(updateFields) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Nothing to update"})
+		}
+		err = h.repos.Orders.UpdateForSeller(c.Context(), objID, sellerID, updateFields)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update order"})
+		}
+		return c.JSON(fiber.Map{"message": "Order updated successfully"})
 	}
 
-	// Melakukan update data berdasarkan orderID
-	_, err = collection.UpdateOne(context.TODO(), bson.M{"_id": objID}, updateFields)
+	// Status hanya boleh berpindah sesuai lifecycle; stok dikembalikan jika order dibatalkan
+	actor := statusActor{ID: sellerID, Role: actorSeller}
+	err = h.setOrderStatus(c.Context(), order, updateData.Status, actor, updateData.Note, updateFields)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update order"})
+		return writeTransitionError(c, err, "Failed to update order")
 	}
 
 	// Mengembalikan pesan sukses
@@ -209,7 +176,7 @@ func UpdateSellerOrderHandler(c *fiber.Ctx) error {
 }
 
 // PUT /orders/status/:order_id → Update status order
-func UpdateOrderStatusHandler(c *fiber.Ctx) error {
+func (h *Handler) UpdateOrderStatusHandler(c *fiber.Ctx) error {
 	orderID := c.Params("order_id")
 	objID, err := primitive.ObjectIDFromHex(orderID)
 	if err != nil {
@@ -218,6 +185,7 @@ func UpdateOrderStatusHandler(c *fiber.Ctx) error {
   
 	var statusUpdate struct {
 	  Status string `json:"status"`
+	  Note   string `json:"note"`
 	}
   
 	if err := c.BodyParser(&statusUpdate); err != nil {
@@ -228,35 +196,140 @@ func UpdateOrderStatusHandler(c *fiber.Ctx) error {
 	  return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status is required"})
 	}
   
-	collection := config.MongoClient.Database("ecommerce").Collection("orders")
-	_, err = collection.UpdateOne(
-	  context.TODO(),
-	  bson.M{"_id": objID},
-	  bson.M{"$set": bson.M{"status": statusUpdate.Status}},
-	)
-  
+	sellerID, err := middleware.CurrentSellerID(c)
+	if err != nil {
+	  return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	order, err := h.repos.Orders.FindForSeller(c.Context(), objID, sellerID)
 	if err != nil {
-	  return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update order status"})
+	  return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+
+	// Status hanya boleh berpindah sesuai lifecycle; stok dikembalikan jika order dibatalkan atau di-refund
+	actor := statusActor{ID: sellerID, Role: actorSeller}
+	err = h.setOrderStatus(c.Context(), order, statusUpdate.Status, actor, statusUpdate.Note, nil)
+	if err != nil {
+	  return writeTransitionError(c, err, "Failed to update order status")
 	}
   
 	return c.JSON(fiber.Map{"message": "Order status updated successfully"})
   }
   
-// **DELETE /orders/:order_id** → Hapus pesanan oleh seller
-func DeleteSellerOrderHandler(c *fiber.Ctx) error {
+// PUT /orders/:order_id/status → Pembeli membatalkan order yang belum dibayar atau menyelesaikan order yang sudah diterima
+func (h *Handler) UpdateCustomerOrderStatusHandler(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("order_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Order ID"})
+	}
+
+	var statusUpdate struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := c.BodyParser(&statusUpdate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if statusUpdate.Status == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status is required"})
+	}
+
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	// Order hanya bisa diubah oleh pembelinya sendiri
+	order, err := h.repos.Orders.FindByID(c.Context(), objID)
+	if err != nil || order.UserID != userID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+
+	actor := statusActor{ID: userID, Role: actorCustomer}
+	err = h.setOrderStatus(c.Context(), order, statusUpdate.Status, actor, statusUpdate.Note, nil)
+	if err != nil {
+		return writeTransitionError(c, err, "Failed to update order status")
+	}
+
+	return c.JSON(fiber.Map{"message": "Order status updated successfully"})
+}
+
+// PUT /admin/orders/:order_id/status → Admin mengubah status order apa pun, termasuk refund
+func (h *Handler) AdminUpdateOrderStatusHandler(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("order_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Order ID"})
+	}
+
+	var statusUpdate struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := c.BodyParser(&statusUpdate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if statusUpdate.Status == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status is required"})
+	}
+
+	adminID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	order, err := h.repos.Orders.FindByID(c.Context(), objID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+
+	actor := statusActor{ID: adminID, Role: actorAdmin}
+	err = h.setOrderStatus(c.Context(), order, statusUpdate.Status, actor, statusUpdate.Note, nil)
+	if err != nil {
+		return writeTransitionError(c, err, "Failed to update order status")
+	}
+
+	return c.JSON(fiber.Map{"message": "Order status updated successfully"})
+}
+
+// Status akhir order yang boleh diarsipkan seller
+var archivableStatuses = map[string]bool{
+	model.OrderStatusCompleted: true,
+	model.OrderStatusCancelled: true,
+	model.OrderStatusRefunded:  true,
+}
+
+// **DELETE /orders/:order_id** → Arsipkan pesanan oleh seller (soft delete)
+func (h *Handler) DeleteSellerOrderHandler(c *fiber.Ctx) error {
 	orderID := c.Params("order_id")
 	objID, err := primitive.ObjectIDFromHex(orderID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Order ID"})
 	}
 
-	collection := config.MongoClient.Database("ecommerce").Collection("orders")
-	// Menghapus order berdasarkan orderID
-	_, err = collection.DeleteOne(context.TODO(), bson.M{"_id": objID})
+	sellerID, err := middleware.CurrentSellerID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	order, err := h.repos.Orders.FindForSeller(c.Context(), objID, sellerID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+
+	// Order adalah catatan keuangan, jadi hanya diarsipkan dan hanya setelah selesai
+	if !archivableStatuses[order.Status] {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only completed, cancelled or refunded orders can be deleted"})
+	}
+
+	// Menyembunyikan order dari daftar seller, hanya jika milik seller yang login
+	err = h.repos.Orders.ArchiveForSeller(c.Context(), objID, sellerID)
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete order"})
 	}
 
 	// Mengembalikan pesan sukses
-	return c.JSON(fiber.Map{"message": "Order deleted successfully"})
+	return c.JSON(fiber.Map{"message": "Order archived successfully"})
 }
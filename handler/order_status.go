@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Role pelaku perubahan status order
+const (
+	actorCustomer = "customer"
+	actorSeller   = "seller"
+	actorAdmin    = "admin"
+	actorSystem   = "system" // Pembayaran, webhook dan job terjadwal
+)
+
+// statusActor adalah pihak yang mengubah status order; ID kosong untuk actorSystem
+type statusActor struct {
+	ID   primitive.ObjectID
+	Role string
+}
+
+var systemActor = statusActor{Role: actorSystem}
+
+// transitionTable berisi lifecycle: status asal → status tujuan → role yang boleh melakukannya
+type transitionTable map[string]map[string][]string
+
+// orderTransitions: AwaitingPayment → Paid → Processing → Shipped → Delivered → Completed, dengan Cancelled/Refunded.
+var orderTransitions = transitionTable{
+	model.OrderStatusAwaitingPayment: {
+		model.OrderStatusPaid:      {actorSystem, actorAdmin},
+		model.OrderStatusCancelled: {actorCustomer, actorSeller, actorAdmin, actorSystem},
+	},
+	model.OrderStatusPaid: {
+		model.OrderStatusProcessing: {actorSeller, actorAdmin},
+		model.OrderStatusCancelled:  {actorCustomer, actorSeller, actorAdmin},
+		model.OrderStatusRefunded:   {actorAdmin, actorSystem},
+	},
+	model.OrderStatusProcessing: {
+		model.OrderStatusShipped:   {actorSeller, actorAdmin},
+		model.OrderStatusCancelled: {actorCustomer, actorSeller, actorAdmin},
+		model.OrderStatusRefunded:  {actorAdmin, actorSystem},
+	},
+	model.OrderStatusShipped: {
+		model.OrderStatusDelivered: {actorSeller, actorAdmin, actorSystem},
+		model.OrderStatusRefunded:  {actorAdmin, actorSystem},
+	},
+	model.OrderStatusDelivered: {
+		model.OrderStatusCompleted: {actorCustomer, actorAdmin, actorSystem},
+		model.OrderStatusRefunded:  {actorAdmin, actorSystem},
+	},
+	model.OrderStatusCompleted: {
+		model.OrderStatusRefunded: {actorAdmin, actorSystem},
+	},
+}
+
+// lifecycleStatus memetakan status lama ke status lifecycle yang setara
+func lifecycleStatus(status string) string {
+	switch status {
+	case model.OrderStatusLegacyPending:
+		return model.OrderStatusAwaitingPayment
+	case model.OrderStatusLegacyConfirmed:
+		return model.OrderStatusPaid
+	}
+	return status
+}
+
+// transitionError dikembalikan jika role tidak boleh mengubah status from menjadi to
+type transitionError struct {
+	from, to string
+	allowed  []string
+}
+
+func (e *transitionError) Error() string {
+	return fmt.Sprintf("illegal status transition from %q to %q", e.from, e.to)
+}
+
+// allowed mengembalikan status tujuan yang boleh dipilih role dari status from
+func (t transitionTable) allowed(from, role string) []string {
+	allowed := []string{}
+	for to, roles := range t[from] {
+		if contains(roles, role) {
+			allowed = append(allowed, to)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// check memastikan perubahan status from → to diizinkan untuk role
+func (t transitionTable) check(from, to, role string) error {
+	if !contains(t[from][to], role) {
+		return &transitionError{from: from, to: to, allowed: t.allowed(from, role)}
+	}
+	return nil
+}
+
+// checkTransition memastikan perubahan status order from → to diizinkan untuk role
+func checkTransition(from, to, role string) error {
+	return orderTransitions.check(lifecycleStatus(from), to, role)
+}
+
+// writeTransitionError memetakan error setOrderStatus, changeOrderStatus dan refund ke respons HTTP
+func writeTransitionError(c *fiber.Ctx, err error, message string) error {
+	var terr *transitionError
+	var rerr *refundError
+	var ferr *refundFailedError
+	switch {
+	case errors.Is(err, errReasonRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A note with the reason is required"})
+	case errors.As(err, &rerr):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": rerr.message})
+	case errors.Is(err, errNoPaymentReference):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Order has no online payment to refund"})
+	case errors.As(err, &ferr):
+		// Perubahan order sudah tersimpan; refund yang gagal bisa dicoba ulang oleh admin
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":   "Refund failed at the payment provider, an admin can retry it",
+			"details": ferr.err.Error(),
+		})
+	case errors.As(err, &terr):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "Illegal status transition",
+			"from":    terr.from,
+			"to":      terr.to,
+			"allowed": terr.allowed,
+		})
+	case err == repository.ErrNotFound:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Order was modified, please retry"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": message})
+	}
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/services"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSellerOrderUpdateKeepsServerFields(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.paidOrder(t, 50000+testShippingRate, orderItem(a, 1))
+	path := "/orders/" + order.ID.Hex()
+
+	status, body := env.do(t, fiber.MethodPut, path, fiber.Map{"status": model.OrderStatusProcessing, "payment_token": "attacker"})
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	updated := env.order(t, order.ID)
+	if updated.PaymentToken != order.PaymentToken || updated.ShippingAddress != order.ShippingAddress {
+		t.Errorf("payment_token = %q, shipping_address = %q, want both unchanged", updated.PaymentToken, updated.ShippingAddress)
+	}
+
+	if status, _ := env.do(t, fiber.MethodPut, path, fiber.Map{"shipping_address": "Jl. Sudirman 2"}); status != fiber.StatusOK {
+		t.Fatalf("address update status = %d", status)
+	}
+	if updated = env.order(t, order.ID); updated.ShippingAddress != "Jl. Sudirman 2" {
+		t.Errorf("shipping_address = %q, want the new address", updated.ShippingAddress)
+	}
+
+	if status, _ := env.do(t, fiber.MethodPut, path, fiber.Map{"status": model.OrderStatusShipped}); status != fiber.StatusOK {
+		t.Fatalf("ship status = %d", status)
+	}
+	if status, _ := env.do(t, fiber.MethodPut, path, fiber.Map{"shipping_address": "Jl. Thamrin 3"}); status != fiber.StatusConflict {
+		t.Errorf("address update after shipping: status = %d, want 409", status)
+	}
+}
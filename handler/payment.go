@@ -1,28 +1,23 @@
 package handler
 
 import (
-	"be_ecommerce/config"
+	"be_ecommerce/middleware"
 	"be_ecommerce/model"
+	"be_ecommerce/repository"
 	"be_ecommerce/services"
-	"context"
-	"fmt"
+	"log"
 	"net/http"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/veritrans/go-midtrans"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // CreatePaymentHandler menangani proses pembayaran menggunakan Midtrans
-func CreatePaymentHandler(c *fiber.Ctx) error {
+func (h *Handler) CreatePaymentHandler(c *fiber.Ctx) error {
+	// Amount adalah total yang ditampilkan ke pembeli; harga, ongkir dan total tetap dihitung server
 	var input struct {
-		UserID       string            `json:"user_id"`
-		Shipping     string            `json:"shipping"`
-		Amount       int               `json:"amount"`
-		ShippingCost int               `json:"shipping_cost"`
-		Items        []model.OrderItem `json:"items"`
+		Shipping string            `json:"shipping"`
+		Amount   int               `json:"amount"`
+		Items    []model.OrderItem `json:"items"`
 	}
 
 	// 🔥 1. Parse Request Body
@@ -30,119 +25,100 @@ func CreatePaymentHandler(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": "Invalid request body"})
 	}
 
-	// 🔥 2. Validasi User ID
-	if input.UserID == "" {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": "User ID is required"})
-	}
-	objUserID, err := primitive.ObjectIDFromHex(input.UserID)
+	// 🔥 2. Ambil User ID dari token
+	objUserID, err := middleware.CurrentUserID(c)
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": "Invalid User ID"})
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"message": "Unauthorized"})
 	}
 
-	// 🔥 3. Ambil Seller ID dari Produk
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-	for i, item := range input.Items {
-		var product model.Product
-		err := productCollection.FindOne(context.TODO(), bson.M{"_id": item.ProductID}).Decode(&product)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid product ID"})
-		}
-		// ✅ Tambahkan seller_id dari produk ke item
-		input.Items[i].SellerID = product.SellerID
+	// 🔥 3. Hitung harga, diskon, ongkir dan total dari data produk di database
+	priced, err := h.priceOrder(c.Context(), input.Items)
+	if err != nil {
+		return writePricingError(c, err)
+	}
+	if input.Amount != priced.Total {
+		return priceChanged(c, input.Amount, priced)
 	}
 
-	// 🔥 4. Hitung Total Amount dan Siapkan Data Midtrans
-	var totalAmount int64 = 0
-	var midtransItems []midtrans.ItemDetail
-
-	for _, item := range input.Items {
-		totalAmount += int64(item.Price * item.Quantity)
-
-		// ✅ Tambahkan ke Midtrans Items
-		midtransItems = append(midtransItems, midtrans.ItemDetail{
-			ID:    item.ProductID.Hex(),
-			Name:  item.Name,
-			Qty:   int32(item.Quantity),
-			Price: int64(item.Price),
-		})
+	// 🔥 4. Siapkan Item Tagihan
+	var chargeItems []services.ChargeItem
+	for _, item := range priced.Items {
+		charge := services.ChargeItem{
+			ID:       item.ProductID.Hex(),
+			Name:     item.Name,
+			Quantity: item.Quantity,
+			Price:    item.Price,
+		}
+		// Varian ditagih per SKU agar terlihat jelas di dashboard payment gateway
+		if item.SKU != "" {
+			charge.ID = item.SKU
+			charge.Name = item.Name + " (" + item.Variant + ")"
+		}
+		chargeItems = append(chargeItems, charge)
 	}
 
-	// ✅ Tambahkan Shipping Cost sebagai item terpisah di Midtrans
-	if input.ShippingCost > 0 {
-		midtransItems = append(midtransItems, midtrans.ItemDetail{
-			ID:    "SHIPPING",
-			Name:  "Shipping Cost",
-			Qty:   1,
-			Price: int64(input.ShippingCost),
+	// ✅ Tambahkan Shipping Cost sebagai item terpisah
+	if priced.ShippingCost > 0 {
+		chargeItems = append(chargeItems, services.ChargeItem{
+			ID:       "SHIPPING",
+			Name:     "Shipping Cost",
+			Quantity: 1,
+			Price:    priced.ShippingCost,
 		})
-		totalAmount += int64(input.ShippingCost)
 	}
+	totalAmount := priced.Total
 
 	// 🔥 5. Pastikan Total Amount Tidak 0
 	if totalAmount < 1 {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"message": "Total transaction amount must be greater than 0"})
 	}
 
-	// 🔥 6. Simpan Order ke Database
-	order := model.Order{
-		ID:              primitive.NewObjectID(),
-		UserID:          objUserID,
-		SellerID:        input.Items[0].SellerID, // Ambil Seller ID dari item pertama
-		Items:           input.Items,
-		TotalAmount:     int(totalAmount),
-		ShippingCost:    input.ShippingCost,
-		ShippingAddress: input.Shipping,
-		Status:          "Pending",
-		CreatedAt:       time.Now(),
-	}
+	// 🔥 6. Pecah menjadi satu Order per toko di bawah satu Checkout
+	checkout, orders := newCheckout(objUserID, input.Shipping, priced)
 
-	orderCollection := config.MongoClient.Database("ecommerce").Collection("orders")
-	_, err = orderCollection.InsertOne(context.Background(), order)
+	// 🔥 7. Kurangi Stok, Simpan Checkout + Order dan Hapus Cart dalam satu transaksi
+	err = h.placeCheckout(c.Context(), &checkout, orders, objUserID.Hex())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to place order"})
+		return writeStockError(c, err, fiber.Map{"message": "Failed to place order"})
 	}
 
-	// 🔥 7. Hapus Cart Setelah Pembayaran
-	cartCollection := config.MongoClient.Database("ecommerce").Collection("carts")
-	_, err = cartCollection.DeleteOne(context.Background(), bson.M{"user_id": objUserID})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to clear cart after order placement"})
-	}
-
-	// 🔥 8. Konfigurasi Midtrans
-	orderID := "order-" + time.Now().Format("20060102150405")
-	midtransClient := services.MidtransClient()
-	snapGateway := midtrans.SnapGateway{Client: *midtransClient}
-
-	snapReq := &midtrans.SnapReq{
-		TransactionDetails: midtrans.TransactionDetails{
-			OrderID:  orderID,
-			GrossAmt: totalAmount, // ✅ Sekarang sudah sesuai dengan item Midtrans
-		},
-		Items: &midtransItems,
+	// 🔥 8. Siapkan Tagihan; semua order toko dibayar dalam satu transaksi
+	chargeReq := services.ChargeRequest{
+		Reference: checkout.PaymentReference,
+		Amount:    totalAmount, // ✅ Sesuai dengan jumlah item tagihan
+		Items:     chargeItems,
+		ExpiresIn: paymentDeadline(), // Checkout yang lewat batas ini dibatalkan PaymentExpiryJob
 	}
 
-	// 🔥 9. Kirim Permintaan ke Midtrans
-	snapResp, err := snapGateway.GetToken(snapReq)
+	// 🔥 9. Kirim Permintaan ke Payment Gateway
+	charge, err := h.payments.CreateCharge(c.Context(), chargeReq)
 	if err != nil {
-		fmt.Printf("Midtrans Error: %+v\n", err)
+		log.Println("Error creating payment with", h.payments.Name()+":", err)
+		// Checkout tidak bisa dibayar, batalkan agar stok kembali tersedia.
+		// Pesan dari gateway hanya dicatat di log, tidak dikirim ke client.
+		h.cancelCheckout(c.Context(), checkout, orders)
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to create payment",
-			"details": err.Error(),
+			"message": "Failed to create payment, please try again later",
 		})
 	}
 
-	// 🔥 10. Perbarui Order dengan Payment Token
-	_, err = orderCollection.UpdateOne(context.Background(), bson.M{"_id": order.ID}, bson.M{
-		"$set": bson.M{"payment_token": snapResp.Token},
-	})
+	// 🔥 10. Perbarui Checkout dan semua Order dengan Payment Token
+	err = h.repos.Checkouts.Update(c.Context(), checkout.ID, repository.Fields{"payment_token": charge.Token})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to update order with payment token"})
 	}
+	for _, order := range orders {
+		err = h.repos.Orders.Update(c.Context(), order.ID, repository.Fields{"payment_token": charge.Token})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to update order with payment token"})
+		}
+	}
 
 	// ✅ 11. Kirim Response ke FE
 	return c.JSON(fiber.Map{
-		"token":        snapResp.Token,
-		"redirect_url": snapResp.RedirectURL,
+		"token":        charge.Token,
+		"redirect_url": charge.RedirectURL,
+		"checkout_id":  checkout.ID.Hex(),
+		"order_ids":    orderIDsHex(orders),
 	})
 }
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/scheduler"
+	"be_ecommerce/services"
+	"be_ecommerce/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	defaultPaymentDeadline       = 24 * time.Hour   // Jika PAYMENT_DEADLINE tidak diisi
+	defaultPaymentExpiryInterval = 5 * time.Minute  // Jika PAYMENT_EXPIRY_INTERVAL tidak diisi
+	paymentExpiryGrace           = 10 * time.Minute // Waktu tambahan untuk notification gateway yang terlambat
+	paymentExpiryBatch           = 100
+)
+
+// envDuration membaca durasi Go (contoh: "30m", "24h") dari env, atau fallback jika kosong/tidak valid
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", name, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// paymentDeadline adalah batas waktu pembeli membayar sejak checkout dibuat
+func paymentDeadline() time.Duration {
+	return envDuration("PAYMENT_DEADLINE", defaultPaymentDeadline)
+}
+
+// PaymentExpiryJob membatalkan checkout yang tidak dibayar sampai batas waktu.
+// PAYMENT_EXPIRY_INTERVAL=0 mematikan job ini.
+func (h *Handler) PaymentExpiryJob() scheduler.Job {
+	return scheduler.Job{
+		Name:     "expire-unpaid-checkouts",
+		Interval: envDuration("PAYMENT_EXPIRY_INTERVAL", defaultPaymentExpiryInterval),
+		Run:      h.ExpireUnpaidCheckouts,
+	}
+}
+
+// ExpireUnpaidCheckouts memproses checkout AwaitingPayment yang melewati batas waktu, terlama dulu.
+// Status di gateway diperiksa dulu sehingga pembayaran yang notification-nya terlewat tetap tercatat.
+// Order lama tanpa checkout tidak disentuh karena reference Midtrans-nya tidak tersimpan.
+func (h *Handler) ExpireUnpaidCheckouts(ctx context.Context) error {
+	cutoff := time.Now().Add(-paymentDeadline() - paymentExpiryGrace)
+	checkouts, err := h.repos.Checkouts.ListAwaitingPayment(ctx, cutoff, paymentExpiryBatch)
+	if err != nil {
+		return err
+	}
+
+	expired := 0
+	for _, checkout := range checkouts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result, err := h.expireCheckout(ctx, checkout)
+		if err != nil {
+			// Dicoba lagi pada putaran berikutnya
+			log.Println("Error expiring checkout", checkout.ID.Hex()+":", err)
+			continue
+		}
+		if result == model.OrderStatusCancelled {
+			expired++
+			h.notifyPaymentExpired(ctx, checkout)
+		}
+	}
+	if expired > 0 {
+		log.Printf("Expired %d unpaid checkouts", expired)
+	}
+	return nil
+}
+
+// expireCheckout membatalkan checkout jika pembayarannya memang tidak masuk, lalu mengembalikan
+// status hasil applyPaymentStatus. Order yang dibatalkan otomatis mengembalikan stoknya.
+func (h *Handler) expireCheckout(ctx context.Context, checkout model.Checkout) (string, error) {
+	payment, err := h.payments.GetStatus(ctx, checkout.PaymentReference)
+	switch {
+	case errors.Is(err, services.ErrPaymentNotFound):
+		// Pembeli belum memilih metode pembayaran; token Snap sudah kedaluwarsa di gateway
+	case err != nil:
+		return "", err
+	case payment.Status == services.PaymentPending:
+		// Tutup transaksi di gateway dulu agar tidak bisa dibayar setelah order dibatalkan
+		if err := h.payments.Expire(ctx, checkout.PaymentReference); err != nil {
+			return "", err
+		}
+	case payment.Status == services.PaymentChallenge:
+		// Menunggu review fraud, keputusan akan datang lewat notification
+		return "ignored", nil
+	default:
+		// Notification terlewat: terapkan status sebenarnya dari gateway
+		return h.applyPaymentStatus(ctx, payment)
+	}
+
+	return h.applyPaymentStatus(ctx, services.PaymentStatus{
+		Reference:   checkout.PaymentReference,
+		Status:      services.PaymentExpired,
+		RawStatus:   services.PaymentExpired,
+		GrossAmount: checkout.TotalAmount,
+	})
+}
+
+// notifyPaymentExpired memberi tahu pembeli lewat email; kegagalan hanya dicatat
+func (h *Handler) notifyPaymentExpired(ctx context.Context, checkout model.Checkout) {
+	user, err := h.repos.Users.FindByID(ctx, checkout.UserID)
+	if err != nil {
+		log.Println("Error fetching user for expired checkout", checkout.ID.Hex()+":", err)
+		return
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nWe did not receive the payment of Rp%d for checkout %s before the deadline, "+
+		"so your order has been cancelled automatically.\n\nYou can place a new order at any time.",
+		user.Username, checkout.TotalAmount, checkout.ID.Hex())
+	if err := utils.SendEmail(user.Email, "Your order has been cancelled", body); err != nil {
+		log.Println("Error sending payment expiry email to", utils.MaskEmail(user.Email)+":", err)
+	}
+}
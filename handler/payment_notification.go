@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"be_ecommerce/services"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var errPaymentAmountMismatch = errors.New("gross amount does not match checkout total")
+
+// paymentOrderStatus memetakan status pembayaran ke status order.
+// String kosong berarti status tersebut tidak mengubah order, contoh: pending atau fraud challenge.
+func paymentOrderStatus(status string) string {
+	switch status {
+	case services.PaymentPaid:
+		return model.OrderStatusPaid
+	case services.PaymentFailed, services.PaymentExpired:
+		return model.OrderStatusCancelled
+	case services.PaymentRefunded:
+		return model.OrderStatusRefunded
+	}
+	return ""
+}
+
+// applyPaymentStatus menerapkan status pembayaran dari gateway ke checkout dan semua order anaknya.
+// Aman dipanggil berulang untuk status yang sama: order yang sudah berada di status tujuan
+// dilewati, dan transisi yang tidak lagi berlaku (misalnya order sudah dikirim) hanya dicatat.
+func (h *Handler) applyPaymentStatus(ctx context.Context, payment services.PaymentStatus) (string, error) {
+	checkout, err := h.repos.Checkouts.FindByPaymentReference(ctx, payment.Reference)
+	if err != nil {
+		return "", err
+	}
+	if payment.GrossAmount != checkout.TotalAmount {
+		return "", errPaymentAmountMismatch
+	}
+
+	now := time.Now()
+	checkoutFields := repository.Fields{"payment_status": payment.RawStatus}
+
+	target := paymentOrderStatus(payment.Status)
+	if target == "" {
+		return "ignored", h.repos.Checkouts.Update(ctx, checkout.ID, checkoutFields)
+	}
+
+	orders, err := h.repos.Orders.ListByCheckout(ctx, checkout.ID)
+	if err != nil {
+		return "", err
+	}
+
+	var orderFields repository.Fields
+	if target == model.OrderStatusPaid {
+		orderFields = repository.Fields{"payment_date": now}
+		if checkout.PaymentDate.IsZero() {
+			checkoutFields["payment_date"] = now
+		}
+	}
+
+	note := h.payments.Name() + ": " + payment.RawStatus
+	skipped := 0
+	for _, order := range orders {
+		if order.Status == target {
+			continue
+		}
+		err := h.changeOrderStatus(ctx, order, target, systemActor, note, orderFields)
+		var terr *transitionError
+		if errors.As(err, &terr) || err == repository.ErrNotFound {
+			log.Println("Skipping payment status for order", order.ID.Hex()+":", err)
+			skipped++
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Status checkout hanya mengikuti jika pembayaran berlaku untuk order anaknya
+	if skipped > 0 && skipped == len(orders) {
+		return "ignored", h.repos.Checkouts.Update(ctx, checkout.ID, checkoutFields)
+	}
+	checkoutFields["status"] = target
+	return target, h.repos.Checkouts.Update(ctx, checkout.ID, checkoutFields)
+}
+
+// POST /payments/:provider/notification → Menerima HTTP notification dari payment gateway yang aktif.
+// Setiap notification disimpan mentah di payment_notifications untuk audit.
+func (h *Handler) PaymentNotificationHandler(c *fiber.Ctx) error {
+	if c.Params("provider") != h.payments.Name() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Unknown payment provider"})
+	}
+
+	record := model.PaymentNotification{
+		Provider:   h.payments.Name(),
+		Raw:        string(c.Body()),
+		ReceivedAt: time.Now(),
+	}
+	defer func() {
+		if err := h.repos.Payments.Create(context.Background(), &record); err != nil {
+			log.Println("Error saving payment notification:", err)
+		}
+	}()
+
+	// Notification yang tidak ditandatangani gateway ditolak
+	payment, err := h.payments.ParseNotification(c.Body())
+	record.PaymentReference = payment.Reference
+	record.TransactionID = payment.TransactionID
+	record.TransactionStatus = payment.RawStatus
+	record.FraudStatus = payment.FraudStatus
+	switch {
+	case errors.Is(err, services.ErrInvalidSignature):
+		record.Result = "invalid_signature"
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Invalid signature"})
+	case errors.Is(err, services.ErrPaymentNotFound):
+		record.Result = "unknown_order"
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Order not found"})
+	case err != nil:
+		record.Result = "invalid_body"
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid request body"})
+	}
+	record.SignatureValid = true
+
+	result, err := h.applyPaymentStatus(c.Context(), payment)
+	switch {
+	case err == repository.ErrNotFound:
+		record.Result = "unknown_order"
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Order not found"})
+	case errors.Is(err, errPaymentAmountMismatch):
+		record.Result = "amount_mismatch"
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Gross amount does not match order total"})
+	case err != nil:
+		record.Result = "error"
+		log.Println("Error processing payment notification:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to process notification"})
+	}
+
+	record.Result = result
+	return c.JSON(fiber.Map{"message": "Notification processed", "result": result})
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/services"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testServerKey = "SB-Mid-server-test"
+
+// recordedNotifications menyimpan notification yang dicatat handler agar bisa diperiksa test
+type recordedNotifications struct {
+	mu      sync.Mutex
+	records []model.PaymentNotification
+}
+
+func (r *recordedNotifications) Create(ctx context.Context, notification *model.PaymentNotification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, *notification)
+	return nil
+}
+
+func (r *recordedNotifications) last() model.PaymentNotification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) == 0 {
+		return model.PaymentNotification{}
+	}
+	return r.records[len(r.records)-1]
+}
+
+// webhook adalah endpoint notification Midtrans di atas repository testEnv, dengan signature asli
+type webhook struct {
+	app     *fiber.App
+	records *recordedNotifications
+}
+
+func (e *testEnv) midtransWebhook() *webhook {
+	w := &webhook{app: fiber.New(), records: &recordedNotifications{}}
+	repos := e.repos
+	repos.Payments = w.records
+	h := New(repos, services.NewMidtrans(testServerKey, "SB-Mid-client-test", false), e.blobs)
+	w.app.Post("/payments/:provider/notification", h.PaymentNotificationHandler)
+	return w
+}
+
+// notify mengirim notification Midtrans; signature kosong berarti ditandatangani dengan server key test
+func (w *webhook) notify(t *testing.T, reference, transactionStatus, fraudStatus string, amount int, signature string) (int, map[string]interface{}) {
+	t.Helper()
+	gross := strconv.Itoa(amount) + ".00"
+	if signature == "" {
+		signature = services.MidtransSignature(testServerKey, reference, "200", gross)
+	}
+	raw, err := json.Marshal(services.MidtransNotification{
+		TransactionStatus: transactionStatus,
+		TransactionID:     primitive.NewObjectID().Hex(),
+		FraudStatus:       fraudStatus,
+		OrderID:           reference,
+		StatusCode:        "200",
+		GrossAmount:       gross,
+		SignatureKey:      signature,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(fiber.MethodPost, "/payments/midtrans/notification", bytes.NewReader(raw))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := w.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+// pendingCheckout membuat checkout yang menunggu pembayaran lewat POST /checkout
+func (e *testEnv) pendingCheckout(t *testing.T, amount int, items ...model.OrderItem) model.Checkout {
+	t.Helper()
+	status, body := e.do(t, fiber.MethodPost, "/checkout", orderBody(amount, items...))
+	if status != fiber.StatusCreated {
+		t.Fatalf("checkout status = %d, body = %v", status, body)
+	}
+	checkoutID, _ := primitive.ObjectIDFromHex(body["checkout_id"].(string))
+	checkout, err := e.repos.Checkouts.FindByID(context.Background(), checkoutID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return checkout
+}
+
+func (e *testEnv) checkoutOrders(t *testing.T, checkout model.Checkout) []model.Order {
+	t.Helper()
+	orders, err := e.repos.Orders.ListByCheckout(context.Background(), checkout.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return orders
+}
+
+func TestPaymentNotificationRejectsInvalidSignature(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	total := 50000 + testShippingRate
+	checkout := env.pendingCheckout(t, total, orderItem(a, 1))
+	w := env.midtransWebhook()
+
+	forged := services.MidtransSignature("another-server-key", checkout.PaymentReference, "200", strconv.Itoa(total)+".00")
+	status, body := w.notify(t, checkout.PaymentReference, "settlement", "", total, forged)
+	if status != fiber.StatusForbidden {
+		t.Fatalf("status = %d, body = %v, want 403", status, body)
+	}
+	if record := w.records.last(); record.Result != "invalid_signature" || record.SignatureValid {
+		t.Errorf("record = %+v, want an invalid_signature record", record)
+	}
+	for _, order := range env.checkoutOrders(t, checkout) {
+		if order.Status != model.OrderStatusAwaitingPayment {
+			t.Errorf("order status = %s, want %s", order.Status, model.OrderStatusAwaitingPayment)
+		}
+	}
+}
+
+func TestPaymentNotificationRejectsAmountMismatch(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	total := 50000 + testShippingRate
+	checkout := env.pendingCheckout(t, total, orderItem(a, 1))
+	w := env.midtransWebhook()
+
+	// Signature valid, tetapi gross_amount tidak sama dengan total checkout
+	status, body := w.notify(t, checkout.PaymentReference, "settlement", "", 1000, "")
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("status = %d, body = %v, want 400", status, body)
+	}
+	if record := w.records.last(); record.Result != "amount_mismatch" {
+		t.Errorf("result = %q, want amount_mismatch", record.Result)
+	}
+	for _, order := range env.checkoutOrders(t, checkout) {
+		if order.Status != model.OrderStatusAwaitingPayment {
+			t.Errorf("order status = %s, want %s", order.Status, model.OrderStatusAwaitingPayment)
+		}
+	}
+}
+
+func TestPaymentNotificationUnknownOrder(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	w := env.midtransWebhook()
+
+	status, body := w.notify(t, "checkout-"+primitive.NewObjectID().Hex(), "settlement", "", 1000, "")
+	if status != fiber.StatusNotFound {
+		t.Fatalf("status = %d, body = %v, want 404", status, body)
+	}
+}
+
+func TestPaymentNotificationRedeliveryIsIdempotent(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	total := 2*50000 + testShippingRate
+	checkout := env.pendingCheckout(t, total, orderItem(a, 2))
+	w := env.midtransWebhook()
+
+	for i := 0; i < 3; i++ {
+		status, body := w.notify(t, checkout.PaymentReference, "settlement", "", total, "")
+		if status != fiber.StatusOK || body["result"] != model.OrderStatusPaid {
+			t.Fatalf("delivery %d: status = %d, body = %v, want 200 %s", i+1, status, body, model.OrderStatusPaid)
+		}
+	}
+
+	orders := env.checkoutOrders(t, checkout)
+	if len(orders) != 1 || orders[0].Status != model.OrderStatusPaid {
+		t.Fatalf("orders = %+v, want one Paid order", orders)
+	}
+	paid := 0
+	for _, change := range orders[0].StatusHistory {
+		if change.To == model.OrderStatusPaid {
+			paid++
+		}
+	}
+	if paid != 1 {
+		t.Errorf("%d transitions to Paid in history, want 1", paid)
+	}
+	if got := env.stock(t, a); got != 3 {
+		t.Errorf("stock = %d, want 3", got)
+	}
+}
+
+func TestPaymentNotificationOutOfOrderStatuses(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, late   string // transaction_status yang diterima lebih dulu, lalu yang terlambat
+		lateFraud     string
+		wantResult    string
+		wantStatus    string
+		wantStockLeft int
+	}{
+		{"pending after settlement", "settlement", "pending", "", "ignored", model.OrderStatusPaid, 4},
+		{"challenge after settlement", "settlement", "capture", "challenge", "ignored", model.OrderStatusPaid, 4},
+		{"expire after settlement", "settlement", "expire", "", "ignored", model.OrderStatusPaid, 4},
+		{"settlement after expire", "expire", "settlement", "", "ignored", model.OrderStatusCancelled, 5},
+		{"settlement after pending", "pending", "settlement", "", model.OrderStatusPaid, model.OrderStatusPaid, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, services.MockOutcomePending)
+			a := env.product(t, env.sellerID, 50000, 5)
+			total := 50000 + testShippingRate
+			checkout := env.pendingCheckout(t, total, orderItem(a, 1))
+			w := env.midtransWebhook()
+
+			if status, body := w.notify(t, checkout.PaymentReference, tt.first, "", total, ""); status != fiber.StatusOK {
+				t.Fatalf("first notification: status = %d, body = %v", status, body)
+			}
+			status, body := w.notify(t, checkout.PaymentReference, tt.late, tt.lateFraud, total, "")
+			if status != fiber.StatusOK || body["result"] != tt.wantResult {
+				t.Fatalf("late notification: status = %d, body = %v, want 200 %s", status, body, tt.wantResult)
+			}
+
+			orders := env.checkoutOrders(t, checkout)
+			if len(orders) != 1 || orders[0].Status != tt.wantStatus {
+				t.Errorf("orders = %+v, want one %s order", orders, tt.wantStatus)
+			}
+			if got := env.stock(t, a); got != tt.wantStockLeft {
+				t.Errorf("stock = %d, want %d", got, tt.wantStockLeft)
+			}
+			checkout, err := env.repos.Checkouts.FindByID(context.Background(), checkout.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if checkout.Status != tt.wantStatus {
+				t.Errorf("checkout status = %s, want %s", checkout.Status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"be_ecommerce/services"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPaymentErrorDoesNotLeakGatewayMessage(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomeError)
+	a := env.product(t, env.sellerID, 50000, 5)
+
+	status, body := env.do(t, fiber.MethodPost, "/payment", orderBody(50000+testShippingRate, orderItem(a, 1)))
+	if status != fiber.StatusInternalServerError {
+		t.Fatalf("status = %d, body = %v, want 500", status, body)
+	}
+	for key, value := range body {
+		if text, _ := value.(string); strings.Contains(text, "mock:") {
+			t.Errorf("%s = %q exposes the gateway error", key, text)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const defaultShippingRate = 10000 // Ongkos kirim flat per toko jika SHIPPING_FLAT_RATE tidak diisi
+
+var (
+	errEmptyOrder      = errors.New("order has no items")
+	errInvalidQuantity = errors.New("invalid item quantity")
+	errUnknownProduct  = errors.New("product not found")
+	errVariantRequired = errors.New("variant is required")
+	errUnknownVariant  = errors.New("variant not found")
+	errUnavailable     = errors.New("product is not available")
+)
+
+// pricedOrder adalah hasil perhitungan harga di server; harga dari client tidak pernah dipakai
+type pricedOrder struct {
+	Items        []model.OrderItem
+	Sellers      []sellerPortion // Satu per toko sesuai urutan item pertama kali muncul
+	Subtotal     int
+	ShippingCost int
+	Total        int
+}
+
+// sellerPortion adalah bagian pricedOrder milik satu toko dan menjadi satu Order anak
+type sellerPortion struct {
+	SellerID     primitive.ObjectID
+	Items        []model.OrderItem
+	Subtotal     int
+	ShippingCost int
+	Total        int
+}
+
+// pricingError menyimpan product_id yang menyebabkan perhitungan gagal
+type pricingError struct {
+	err       error
+	productID primitive.ObjectID
+}
+
+func (e *pricingError) Error() string { return e.err.Error() + ": " + e.productID.Hex() }
+func (e *pricingError) Unwrap() error { return e.err }
+
+// shippingRate membaca ongkos kirim flat per toko dari SHIPPING_FLAT_RATE
+func shippingRate() int {
+	if rate, err := strconv.Atoi(os.Getenv("SHIPPING_FLAT_RATE")); err == nil && rate >= 0 {
+		return rate
+	}
+	return defaultShippingRate
+}
+
+// discountedPrice menerapkan diskon persen ke harga satuan produk atau varian
+func discountedPrice(price, discount int) int {
+	if discount < 0 {
+		discount = 0
+	} else if discount > 100 {
+		discount = 100
+	}
+	return price - price*discount/100
+}
+
+// priceOrder memuat setiap produk dari database lalu menghitung harga satuan setelah diskon,
+// subtotal, ongkos kirim (flat per toko) dan total, lalu mengelompokkannya per toko.
+// Hanya product_id, variant_id dan quantity yang diambil dari client.
+func (h *Handler) priceOrder(ctx context.Context, items []model.OrderItem) (pricedOrder, error) {
+	var order pricedOrder
+	if len(items) == 0 {
+		return order, errEmptyOrder
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		if item.Quantity < 1 {
+			return order, &pricingError{errInvalidQuantity, item.ProductID}
+		}
+		ids = append(ids, item.ProductID)
+	}
+
+	products, err := h.repos.Products.FindByIDs(ctx, ids)
+	if err != nil {
+		return order, err
+	}
+	byID := make(map[primitive.ObjectID]model.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	sellerIndex := map[primitive.ObjectID]int{}
+	activeSellers := map[primitive.ObjectID]bool{}
+	for _, item := range items {
+		product, ok := byID[item.ProductID]
+		if !ok {
+			return order, &pricingError{errUnknownProduct, item.ProductID}
+		}
+		// Produk dari toko nonaktif atau pemilik yang di-suspend tidak bisa dibeli, sama seperti di katalog
+		if _, checked := activeSellers[product.SellerID]; !checked {
+			active, err := h.sellerActive(ctx, product.SellerID)
+			if err != nil {
+				return order, err
+			}
+			activeSellers[product.SellerID] = active
+		}
+		if !activeSellers[product.SellerID] {
+			return order, &pricingError{errUnavailable, item.ProductID}
+		}
+
+		variant, err := productVariant(product, item.VariantID)
+		if err != nil {
+			return order, &pricingError{err, item.ProductID}
+		}
+		priced := model.OrderItem{
+			ProductID: product.ID,
+			Name:      product.Name,
+			Quantity:  item.Quantity,
+			Discount:  product.Discount,
+			SellerID:  product.SellerID,
+		}
+		if !variant.ID.IsZero() {
+			priced.VariantID = variant.ID
+			priced.SKU = variant.SKU
+			priced.Variant = product.VariantLabel(variant)
+			priced.Discount = variant.Discount
+			priced.Price = discountedPrice(variant.Price, variant.Discount)
+		} else {
+			priced.Price = discountedPrice(product.Price, product.Discount)
+		}
+		order.Items = append(order.Items, priced)
+		order.Subtotal += priced.Price * item.Quantity
+
+		i, ok := sellerIndex[product.SellerID]
+		if !ok {
+			i = len(order.Sellers)
+			sellerIndex[product.SellerID] = i
+			order.Sellers = append(order.Sellers, sellerPortion{SellerID: product.SellerID})
+		}
+		order.Sellers[i].Items = append(order.Sellers[i].Items, priced)
+		order.Sellers[i].Subtotal += priced.Price * item.Quantity
+	}
+
+	// Ongkos kirim dihitung per toko karena setiap toko mengirim paketnya sendiri
+	rate := shippingRate()
+	for i := range order.Sellers {
+		order.Sellers[i].ShippingCost = rate
+		order.Sellers[i].Total = order.Sellers[i].Subtotal + rate
+	}
+	order.ShippingCost = rate * len(order.Sellers)
+	order.Total = order.Subtotal + order.ShippingCost
+	return order, nil
+}
+
+// sellerActive memeriksa apakah produk toko sellerID boleh dibeli: toko ada, sudah disetujui dan pemiliknya tidak di-suspend
+func (h *Handler) sellerActive(ctx context.Context, sellerID primitive.ObjectID) (bool, error) {
+	_, _, err := h.findActiveStore(ctx, sellerID, false)
+	switch err {
+	case nil:
+		return true, nil
+	case errStoreInactive, repository.ErrNotFound:
+		return false, nil
+	}
+	return false, err
+}
+
+// writePricingError memetakan error priceOrder ke respons HTTP
+func writePricingError(c *fiber.Ctx, err error) error {
+	var perr *pricingError
+	switch {
+	case errors.Is(err, errEmptyOrder):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Order must contain at least one item"})
+	case errors.As(err, &perr) && errors.Is(err, errInvalidQuantity):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message":    "Quantity must be greater than 0",
+			"product_id": perr.productID.Hex(),
+		})
+	case errors.As(err, &perr) && errors.Is(err, errUnknownProduct):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message":    "Invalid product ID",
+			"product_id": perr.productID.Hex(),
+		})
+	case errors.As(err, &perr) && errors.Is(err, errVariantRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message":    "Please choose a variant of this product",
+			"product_id": perr.productID.Hex(),
+		})
+	case errors.As(err, &perr) && errors.Is(err, errUnknownVariant):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message":    "Invalid variant ID",
+			"product_id": perr.productID.Hex(),
+		})
+	case errors.As(err, &perr) && errors.Is(err, errUnavailable):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message":    "Product is not available",
+			"product_id": perr.productID.Hex(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to calculate order total"})
+	}
+}
+
+// priceChanged mengirim 409 jika total yang diharapkan client berbeda dengan hasil hitung server,
+// beserta rincian harga terbaru agar client bisa menampilkan ulang ke pembeli
+func priceChanged(c *fiber.Ctx, expected int, order pricedOrder) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"message":        "Prices have changed, please review your order",
+		"code":           "price_changed",
+		"expected_total": expected,
+		"items":          order.Items,
+		"subtotal":       order.Subtotal,
+		"shipping_cost":  order.ShippingCost,
+		"total":          order.Total,
+	})
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"be_ecommerce/services"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestInactiveStoreProductsCannotBeBought(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	pending := env.product(t, env.store(t, "pending"), 20000, 5)
+
+	suspendedStore := env.store(t, "approved")
+	suspended := env.product(t, suspendedStore, 20000, 5)
+	store, err := env.repos.Stores.FindByID(context.Background(), suspendedStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suspension := model.Suspension{Reason: "Fraud", SuspendedAt: time.Now()}
+	if err := env.repos.Users.Update(context.Background(), store.OwnerID, nil, repository.Fields{"suspension": &suspension}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, id := range map[string]primitive.ObjectID{"pending store": pending, "suspended owner": suspended} {
+		status, body := env.do(t, fiber.MethodPost, "/checkout", orderBody(20000+testShippingRate, orderItem(id, 1)))
+		if status != fiber.StatusBadRequest || body["product_id"] != id.Hex() {
+			t.Errorf("%s checkout: status = %d, body = %v, want 400 for the product", name, status, body)
+		}
+		if status, _ := env.do(t, fiber.MethodPost, "/cart", fiber.Map{"product_id": id}); status != fiber.StatusNotFound {
+			t.Errorf("%s add to cart: status = %d, want 404", name, status)
+		}
+	}
+	if got := env.stock(t, suspended); got != 5 {
+		t.Errorf("stock = %d, want 5", got)
+	}
+}
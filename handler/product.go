@@ -1,21 +1,20 @@
 package handler
 
 import (
-	"be_ecommerce/config"
+	"be_ecommerce/imaging"
+	"be_ecommerce/middleware"
 	"be_ecommerce/model"
-	"be_ecommerce/utils"
+	"be_ecommerce/repository"
 	"context"
 	"fmt"
-	"log"
+	"mime/multipart"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func CreateProduct(c *fiber.Ctx) error {
+func (h *Handler) CreateProduct(c *fiber.Ctx) error {
 	// Parse multipart form
 	form, err := c.MultipartForm()
 	if err != nil {
@@ -33,8 +32,19 @@ func CreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	if len(form.Value["price"]) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Product price is required",
+		})
+	}
+	if len(form.Value["seller_id"]) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Seller ID is required",
+		})
+	}
+
 	// Konversi price dari int64 ke int
-	price64, err := strconv.ParseInt(form.Value["price"][0], 10, 64)
+	price64, err := strconv.ParseInt(formValue(form, "price"), 10, 64)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid price format",
@@ -44,13 +54,13 @@ func CreateProduct(c *fiber.Ctx) error {
 	price := int(price64) // Konversi ke int
 
 	// Konversi discount dari int64 ke int
-	discount64, err := strconv.ParseInt(form.Value["discount"][0], 10, 64)
+	discount64, err := strconv.ParseInt(formValue(form, "discount"), 10, 64)
 	if err != nil {
 		discount64 = 0 // Default discount jika tidak valid
 	}
 	discount := int(discount64) // Konversi ke int
 
-	sellerID, err := primitive.ObjectIDFromHex(form.Value["seller_id"][0])
+	sellerID, err := primitive.ObjectIDFromHex(formValue(form, "seller_id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid Seller ID format",
@@ -58,7 +68,14 @@ func CreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
-	categoryID, err := primitive.ObjectIDFromHex(form.Value["category_id"][0])
+	// seller_id adalah _id toko, pastikan tokonya ada
+	if _, err := h.repos.Stores.FindByID(c.Context(), sellerID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Store not found for the given Seller ID",
+		})
+	}
+
+	categoryID, err := primitive.ObjectIDFromHex(formValue(form, "category_id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid Category ID format",
@@ -66,7 +83,7 @@ func CreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
-	subCategoryID, err := primitive.ObjectIDFromHex(form.Value["sub_category_id"][0])
+	subCategoryID, err := primitive.ObjectIDFromHex(formValue(form, "sub_category_id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid Sub-Category ID format",
@@ -81,22 +98,10 @@ func CreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
-	// Handle file upload
-	var imagePath string
-	fileHeaders := form.File["image"]
-	if len(fileHeaders) > 0 {
-		file := fileHeaders[0]
-		imagePath = fmt.Sprintf("./uploads/%s", file.Filename)
-
-		// Save image file
-		if err := c.SaveFile(file, imagePath); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": "Failed to save image",
-				"error":   err.Error(),
-			})
-		}
-	} else {
-		imagePath = "" // Default jika tidak ada gambar
+	// Handle file upload: "image" menjadi sampul, "images" sisa galeri
+	images, err := h.formGallery(c.Context(), form)
+	if err != nil {
+		return writeImageError(c, err)
 	}
 
 	// Prepare product data
@@ -109,27 +114,31 @@ func CreateProduct(c *fiber.Ctx) error {
 		CategoryID:    categoryID,
 		SubCategoryID: subCategoryID,
 		Description:   description[0],
-		Image:         imagePath,
+		Images:        images,
+	}
+	if len(images) > 0 {
+		product.Image = images[0].Renditions[imaging.Medium]
 	}
 
 	// Save product to database
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-	result, err := productCollection.InsertOne(context.Background(), product)
+	err = h.repos.Products.Create(c.Context(), &product)
 	if err != nil {
+		h.cleanupProductImages(c.Context(), images)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to save product",
 			"error":   err.Error(),
 		})
 	}
+	h.reindexProduct(c.Context(), product.ID)
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message":    "Product created successfully",
-		"product_id": result.InsertedID,
+		"product_id": product.ID,
 	})
 }
 
 
-func DeleteProductByID(c *fiber.Ctx) error {
+func (h *Handler) DeleteProductByID(c *fiber.Ctx) error {
 	// Ambil ID produk dari parameter URL
 	productID := c.Params("id")
 
@@ -142,24 +151,26 @@ func DeleteProductByID(c *fiber.Ctx) error {
 		})
 	}
 
-	// Ambil koleksi produk dari database
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
+	// Galeri produk dibersihkan setelah produk dihapus
+	product, _ := h.repos.Products.FindByID(c.Context(), objectID)
 
 	// Cari dan hapus produk berdasarkan ID
-	result, err := productCollection.DeleteOne(context.Background(), bson.M{"_id": objectID})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to delete product",
-			"error":   err.Error(),
-		})
-	}
+	err = h.repos.Products.Delete(c.Context(), objectID, nil)
 
 	// Periksa apakah produk ditemukan dan dihapus
-	if result.DeletedCount == 0 {
+	if err == repository.ErrNotFound {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "Product not found",
 		})
 	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to delete product",
+			"error":   err.Error(),
+		})
+	}
+	h.unindexProduct(objectID)
+	h.cleanupProductImages(c.Context(), product.Images)
 
 	// Berikan respons berhasil
 	return c.JSON(fiber.Map{
@@ -167,161 +178,15 @@ func DeleteProductByID(c *fiber.Ctx) error {
 		"status":  "success",
 	})
 }
-func CreateSellerProduct(c *fiber.Ctx) error {
-	// Ambil seller_id dari token (middleware JWT harus sudah diterapkan sebelumnya)
-	sellerID, err := utils.GetUserIDFromToken(c)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Unauthorized", "error": err.Error()})
-	}
-
-	// Parse multipart form
-	form, err := c.MultipartForm()
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid form data", "error": err.Error()})
-	}
-
-	// Ambil dan validasi field
-	name := form.Value["name"]
-	if len(name) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Product name is required"})
-	}
-
-	price, err := strconv.Atoi(form.Value["price"][0])
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid price format", "error": err.Error()})
-	}
-
-	discount, _ := strconv.Atoi(form.Value["discount"][0])
-
-	categoryID, _ := primitive.ObjectIDFromHex(form.Value["category_id"][0])
-	subCategoryID, _ := primitive.ObjectIDFromHex(form.Value["sub_category_id"][0])
-
-	description := form.Value["description"][0]
-
-	// Handle file upload
-	var imagePath string
-	fileHeaders := form.File["image"]
-	if len(fileHeaders) > 0 {
-		file := fileHeaders[0]
-		imagePath = fmt.Sprintf("./uploads/%s", file.Filename)
-
-		// Simpan file gambar
-		if err := c.SaveFile(file, imagePath); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to save image", "error": err.Error()})
-		}
-	} else {
-		imagePath = ""
-	}
-
-	// Simpan produk ke database
-	product := model.Product{
-		ID:            primitive.NewObjectID(),
-		Name:          name[0],
-		Price:         price,
-		Discount:      discount,
-		SellerID:      sellerID,
-		CategoryID:    categoryID,
-		SubCategoryID: subCategoryID,
-		Description:   description,
-		Image:         imagePath,
-	}
-
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-	result, err := productCollection.InsertOne(context.Background(), product)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to save product", "error": err.Error()})
-	}
-
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Product created successfully", "product_id": result.InsertedID})
-}
-
-// **2. Update Product for Seller**
-func UpdateSellerProductByID(c *fiber.Ctx) error {
-	// Ambil seller_id dari token
-	sellerID, err := utils.GetUserIDFromToken(c)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Unauthorized", "error": err.Error()})
-	}
-
-	// Ambil ID produk dari parameter URL
-	productID := c.Params("id")
-	objectID, err := primitive.ObjectIDFromHex(productID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid product ID format", "error": err.Error()})
-	}
-
-	// Parse form data
-	form, err := c.MultipartForm()
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid form data", "error": err.Error()})
-	}
-
-	name := form.Value["name"][0]
-	price, _ := strconv.Atoi(form.Value["price"][0])
-	discount, _ := strconv.Atoi(form.Value["discount"][0])
-	categoryID, _ := primitive.ObjectIDFromHex(form.Value["category_id"][0])
-	subCategoryID, _ := primitive.ObjectIDFromHex(form.Value["sub_category_id"][0])
-	description := form.Value["description"][0]
-
-	// Handle file upload
-	var imagePath string
-	fileHeaders := form.File["image"]
-	if len(fileHeaders) > 0 {
-		file := fileHeaders[0]
-		imagePath = fmt.Sprintf("./uploads/%s", file.Filename)
-		c.SaveFile(file, imagePath)
-	}
-
-	updateData := bson.M{
-		"name":            name,
-		"price":           price,
-		"discount":        discount,
-		"category_id":     categoryID,
-		"sub_category_id": subCategoryID,
-		"description":     description,
-	}
-
-	if imagePath != "" {
-		updateData["image"] = imagePath
-	}
-
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-
-	// Pastikan produk dimiliki oleh seller yang sedang login
-	result, err := productCollection.UpdateOne(context.Background(), bson.M{"_id": objectID, "seller_id": sellerID}, bson.M{"$set": updateData})
-	if err != nil || result.MatchedCount == 0 {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Failed to update product or product not found"})
+// formValue mengambil nilai pertama field form; string kosong jika field tidak dikirim
+func formValue(form *multipart.Form, key string) string {
+	if values := form.Value[key]; len(values) > 0 {
+		return values[0]
 	}
-
-	return c.JSON(fiber.Map{"message": "Product updated successfully"})
+	return ""
 }
 
-// **3. Delete Product for Seller**
-func DeleteSellerProductByID(c *fiber.Ctx) error {
-	// Ambil seller_id dari token
-	sellerID, err := utils.GetUserIDFromToken(c)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Unauthorized", "error": err.Error()})
-	}
-
-	// Ambil ID produk dari parameter URL
-	productID := c.Params("id")
-	objectID, err := primitive.ObjectIDFromHex(productID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid product ID format", "error": err.Error()})
-	}
-
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-
-	// Pastikan produk dimiliki oleh seller yang sedang login
-	result, err := productCollection.DeleteOne(context.Background(), bson.M{"_id": objectID, "seller_id": sellerID})
-	if err != nil || result.DeletedCount == 0 {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Failed to delete product or product not found"})
-	}
-
-	return c.JSON(fiber.Map{"message": "Product deleted successfully"})
-}
-func GetProductDetail(c *fiber.Ctx) error {
+func (h *Handler) GetProductDetail(c *fiber.Ctx) error {
 	productID := c.Params("id")
 
 	// Konversi productID ke ObjectID
@@ -333,36 +198,28 @@ func GetProductDetail(c *fiber.Ctx) error {
 	}
 
 	// Ambil produk dari database
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-	var product model.Product
-	err = productCollection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&product)
+	product, err := h.repos.Products.FindByID(c.Context(), objectID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "Product not found",
 		})
 	}
 
-	// Ambil detail toko berdasarkan SellerID
-	userCollection := config.MongoClient.Database("ecommerce").Collection("users")
-	var seller model.User
-	err = userCollection.FindOne(context.Background(), bson.M{"_id": product.SellerID}).Decode(&seller)
+	// Ambil detail toko berdasarkan SellerID; produk dari toko nonaktif atau pemilik yang di-suspend disembunyikan
+	store, owner, err := h.findActiveStore(context.Background(), product.SellerID, false)
 	if err != nil {
+		if err == errStoreInactive {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Store is not active",
+			})
+		}
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "Seller not found",
 		})
 	}
 
-	// Pastikan toko aktif (store_status == "approved")
-	if seller.StoreStatus != nil && *seller.StoreStatus != "approved" {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "Store is not active",
-		})
-	}
-
 	// Ambil detail kategori dan sub-kategori
-	categoryCollection := config.MongoClient.Database("ecommerce").Collection("categories")
-	var category model.Category
-	err = categoryCollection.FindOne(context.Background(), bson.M{"_id": product.CategoryID}).Decode(&category)
+	category, err := h.repos.Categories.FindByID(c.Context(), product.CategoryID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "Category not found",
@@ -394,123 +251,72 @@ func GetProductDetail(c *fiber.Ctx) error {
 			"sub_category": subCategoryName,
 			"description":  product.Description,
 			"image":        product.Image,
+			"images":       product.Images, // Galeri berurutan beserta rendition thumbnail/medium/large
+			"stock":        product.Stock,
+			"options":      product.Options,
+			"variants":     product.Variants,
 		},
 		"store": fiber.Map{
-			"store_name":   seller.StoreInfo.StoreName,
-			"full_address": seller.StoreInfo.FullAddress,
-			"seller_email": seller.Email,
-			"store_status": seller.StoreStatus,
-			"seller_id":    seller.ID,
+			"store_name":   store.Name,
+			"full_address": store.FullAddress,
+			"seller_email": owner.Email,
+			"store_status": store.Status,
+			"seller_id":    store.ID,
 		},
 	}
 
 	return c.JSON(response)
 }
 
-// GetAllProducts fetches all products
-func GetAllProducts(c *fiber.Ctx) error {
-	// Ambil koleksi produk dari MongoDB
-	collection := config.MongoClient.Database("ecommerce").Collection("products")
-
-	// Pipeline agregasi
-	pipeline := mongo.Pipeline{
-		// Lookup kategori berdasarkan category_id
-		{{Key: "$lookup", Value: bson.D{
-			{Key: "from", Value: "categories"},          // Koleksi yang di-lookup
-			{Key: "localField", Value: "category_id"},   // Field di koleksi "products"
-			{Key: "foreignField", Value: "_id"},         // Field di koleksi "categories"
-			{Key: "as", Value: "category"},              // Alias hasil lookup
-		}}},
-		// Lookup sub-kategori berdasarkan sub_category_id
-		{{Key: "$lookup", Value: bson.D{
-			{Key: "from", Value: "categories"},          // Koleksi yang di-lookup
-			{Key: "localField", Value: "sub_category_id"},
-			{Key: "foreignField", Value: "_id"},
-			{Key: "as", Value: "sub_category"},
-		}}},
-		// Hilangkan array kategori dan sub-kategori (ubah menjadi objek tunggal)
-		{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$category"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
-		{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$sub_category"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
-	}
-
-	// Jalankan agregasi
-	cursor, err := collection.Aggregate(c.Context(), pipeline)
+// GetAllProducts mencari produk dengan filter, urutan dan pagination cursor dari query string.
+// Contoh: /products?q=sepatu&category_id=...&min_price=50000&in_stock=true&sort=price_asc&limit=20
+func (h *Handler) GetAllProducts(c *fiber.Ctx) error {
+	query, err := parseProductQuery(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Failed to fetch products with categories",
-		})
-	}
-	defer cursor.Close(c.Context())
-
-	// Decode hasil agregasi ke slice
-	var products []bson.M
-	if err := cursor.All(c.Context(), &products); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"status":  "error",
-			"message": "Failed to parse products",
+			"message": err.Error(),
 		})
 	}
 
-	// Kembalikan daftar produk dengan kategori dan sub-kategori
-	return c.JSON(fiber.Map{
-		"status":  "success",
-		"message": "Products fetched successfully",
-		"data":    products,
-	})
-}
-
-
-func GetProductsUnderPrice(c *fiber.Ctx) error {
-	priceLimit := 100000.0
-
-	collection := config.MongoClient.Database("ecommerce").Collection("products")
-	cursor, err := collection.Find(c.Context(), bson.M{"price": bson.M{"$lte": priceLimit}})
+	// Ambil produk beserta kategori dan sub-kategorinya
+	page, err := h.repos.Products.Search(c.Context(), query)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
-			"message": "Failed to fetch products under price limit",
+			"message": "Failed to fetch products with categories",
 		})
 	}
-	defer cursor.Close(c.Context())
 
-	var products []model.Product
-	if err := cursor.All(c.Context(), &products); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Failed to parse products",
-		})
+	var nextCursor string
+	if page.Next != nil {
+		nextCursor = encodeProductCursor(query.Sort, *page.Next)
 	}
 
+	// Kembalikan daftar produk dengan kategori dan sub-kategori
 	return c.JSON(fiber.Map{
 		"status":  "success",
-		"message": "Products under Rp.100.000 fetched successfully",
-		"data":    products,
+		"message": "Products fetched successfully",
+		"data":    page.Products,
+		"pagination": fiber.Map{
+			"total":       page.Total,
+			"limit":       query.Limit,
+			"next_cursor": nextCursor, // Kosong jika sudah halaman terakhir
+		},
 	})
 }
-func GetBestSellers(c *fiber.Ctx) error {
-	collection := config.MongoClient.Database("ecommerce").Collection("products")
 
+func (h *Handler) GetBestSellers(c *fiber.Ctx) error {
 	// Filter best sellers: Rating > 4.0 and Reviews > 1000
-	filter := bson.M{
-		"rating":  bson.M{"$gt": 4.0},
-		"reviews": bson.M{"$gt": 1000},
-	}
+	minRating, minReviews := 4.0, 1000
+	filter := repository.ProductFilter{MinRating: &minRating, MinReviews: &minReviews}
 
-	cursor, err := collection.Find(context.Background(), filter)
+	products, err := h.repos.Products.Find(c.Context(), filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to fetch best sellers",
 		})
 	}
-	defer cursor.Close(context.Background())
-
-	var products []model.Product
-	if err := cursor.All(context.Background(), &products); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to parse best sellers",
-		})
-	}
 
 	return c.JSON(fiber.Map{
 		"message": "Best sellers fetched successfully",
@@ -518,45 +324,23 @@ func GetBestSellers(c *fiber.Ctx) error {
 	})
 }
 
-func GetProductsByUserID(c *fiber.Ctx) error {
-	// Ambil user_id dari parameter query atau autentikasi JWT
-	userIDParam := c.Query("user_id")
-	if userIDParam == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "User ID is required",
-		})
-	}
-
-	// Konversi user_id ke ObjectID
-	userID, err := primitive.ObjectIDFromHex(userIDParam)
+func (h *Handler) GetProductsByUserID(c *fiber.Ctx) error {
+	// Ambil toko (seller_id) dari token seller yang sedang login
+	storeID, err := middleware.CurrentSellerID(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid User ID format",
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Forbidden: Seller store not found",
 		})
 	}
 
-	// Ambil koleksi produk
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-
-	// Filter produk berdasarkan user_id
-	filter := bson.M{"seller_id": userID}
-	cursor, err := productCollection.Find(c.Context(), filter)
+	// Filter produk berdasarkan toko
+	products, err := h.repos.Products.Find(c.Context(), repository.ProductFilter{SellerID: &storeID})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to fetch products",
 			"error":   err.Error(),
 		})
 	}
-	defer cursor.Close(c.Context())
-
-	// Decode produk
-	var products []model.Product
-	if err := cursor.All(c.Context(), &products); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to parse products",
-			"error":   err.Error(),
-		})
-	}
 
 	// Kembalikan data produk
 	return c.JSON(fiber.Map{
@@ -565,52 +349,26 @@ func GetProductsByUserID(c *fiber.Ctx) error {
 		"data":    products,
 	})
 }
-func CreateProductForSeller(c *fiber.Ctx) error {
-	// Ambil token dari header Authorization
-	token := c.Get("Authorization")
-	if token == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Unauthorized: Missing token",
-		})
-	}
-
-	// Validasi token dan ambil klaim
-	claims, err := utils.ValidateJWT(token[7:]) // Hapus prefix "Bearer "
+func (h *Handler) CreateProductForSeller(c *fiber.Ctx) error {
+	// Ambil user_id dari token yang sudah diverifikasi middleware
+	objectID, err := middleware.CurrentUserID(c)
 	if err != nil {
-		log.Println("Error validating token:", err)
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Unauthorized: Invalid token",
-		})
-	}
-
-	// Ambil user_id dari klaim
-	userID, ok := claims["user_id"].(string)
-	if !ok || userID == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"message": "Unauthorized: Invalid user ID",
 		})
 	}
 
-	// Konversi user_id ke ObjectID
-	objectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid User ID format",
-		})
-	}
-
-	// Periksa apakah user adalah seller
-	userCollection := config.MongoClient.Database("ecommerce").Collection("users")
-	var seller model.User
-	err = userCollection.FindOne(context.Background(), bson.M{"_id": objectID, "roles": "seller"}).Decode(&seller)
-	if err != nil {
+	// Periksa apakah user adalah seller yang memiliki toko
+	seller, err := h.repos.Users.FindByID(c.Context(), objectID)
+	if err != nil || !contains(seller.Roles, "seller") || seller.SellerID == nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"message": "Forbidden: User is not a seller",
 		})
 	}
 
 	// Periksa apakah toko aktif
-	if seller.StoreStatus == nil || *seller.StoreStatus != "approved" {
+	store, err := h.repos.Stores.FindByID(c.Context(), *seller.SellerID)
+	if err != nil || !store.IsActive() {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"message": "Forbidden: Store is not active or approved",
 		})
@@ -626,18 +384,16 @@ func CreateProductForSeller(c *fiber.Ctx) error {
 	}
 
 	// Ambil data dari form
-	name := form.Value["name"][0]
-	price, _ := strconv.Atoi(form.Value["price"][0])
-	discount, _ := strconv.Atoi(form.Value["discount"][0])
-	stock, _ := strconv.Atoi(form.Value["stock"][0])
-	categoryID, _ := primitive.ObjectIDFromHex(form.Value["category_id"][0])
-	subCategoryID, _ := primitive.ObjectIDFromHex(form.Value["sub_category_id"][0])
-	description := form.Value["description"][0]
+	name := formValue(form, "name")
+	price, _ := strconv.Atoi(formValue(form, "price"))
+	discount, _ := strconv.Atoi(formValue(form, "discount"))
+	stock, _ := strconv.Atoi(formValue(form, "stock"))
+	categoryID, _ := primitive.ObjectIDFromHex(formValue(form, "category_id"))
+	subCategoryID, _ := primitive.ObjectIDFromHex(formValue(form, "sub_category_id"))
+	description := formValue(form, "description")
 
 	// Validasi kategori dan subkategori
-	categoryCollection := config.MongoClient.Database("ecommerce").Collection("categories")
-	var category model.Category
-	err = categoryCollection.FindOne(context.Background(), bson.M{"_id": categoryID}).Decode(&category)
+	category, err := h.repos.Categories.FindByID(c.Context(), categoryID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid Category ID",
@@ -657,22 +413,14 @@ func CreateProductForSeller(c *fiber.Ctx) error {
 		})
 	}
 
-	// Handle file upload (jika ada)
-	var imagePath string
-	fileHeaders := form.File["image"]
-	if len(fileHeaders) > 0 {
-		file := fileHeaders[0]
-		imagePath = fmt.Sprintf("uploads/%s", file.Filename)
-
-		// Simpan file ke folder `uploads/`
-		if err := c.SaveFile(file, imagePath); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": "Failed to save image",
-				"error":   err.Error(),
-			})
-		}
-	} else {
-		imagePath = "uploads/default.png" // Gambar default jika tidak ada gambar diunggah
+	// Handle file upload (jika ada): "image" menjadi sampul, "images" sisa galeri
+	images, err := h.formGallery(c.Context(), form)
+	if err != nil {
+		return writeImageError(c, err)
+	}
+	imagePath := "uploads/default.png" // Gambar default jika tidak ada gambar diunggah
+	if len(images) > 0 {
+		imagePath = images[0].Renditions[imaging.Medium]
 	}
 
 	// Simpan produk ke database
@@ -682,24 +430,26 @@ func CreateProductForSeller(c *fiber.Ctx) error {
 		Price:         price,
 		Discount:      discount,
 		Stock:         stock,
-		SellerID:      objectID,
+		SellerID:      store.ID,
 		CategoryID:    categoryID,
 		SubCategoryID: subCategoryID,
 		Description:   description,
 		Image:         imagePath,
+		Images:        images,
 	}
 
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-	result, err := productCollection.InsertOne(context.Background(), product)
+	err = h.repos.Products.Create(c.Context(), &product)
 	if err != nil {
+		h.cleanupProductImages(c.Context(), images)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error saving product to database",
 		})
 	}
+	h.reindexProduct(c.Context(), product.ID)
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message":    "Product created successfully",
-		"product_id": result.InsertedID,
+		"product_id": product.ID,
 		"image_url":  fmt.Sprintf("%s/%s", "http://localhost:3000", imagePath),
 	})
 }
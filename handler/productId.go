@@ -1,97 +1,15 @@
 package handler
 
 import (
-	"be_ecommerce/config"
 	"be_ecommerce/model"
-	"context"
-	"fmt"
-	"net/http"
+	"be_ecommerce/repository"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// GetProductByID retrieves a product by its ID
-func GetProductByID(c *fiber.Ctx) error {
-	// Ambil ID dari URL parameter
-	productID := c.Params("id")
-
-	// Konversi ID ke ObjectID MongoDB
-	objectID, err := primitive.ObjectIDFromHex(productID)
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Invalid product ID format",
-		})
-	}
-
-	// Ambil koleksi produk
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-	categoryCollection := config.MongoClient.Database("ecommerce").Collection("categories")
-	sellerCollection := config.MongoClient.Database("ecommerce").Collection("sellers")
-
-	// Cari produk berdasarkan ID
-	var product model.Product
-	err = productCollection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&product)
-	if err != nil {
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Product not found",
-		})
-	}
-
-	// Ambil kategori
-	var category model.Category
-	if err := categoryCollection.FindOne(context.Background(), bson.M{"_id": product.CategoryID}).Decode(&category); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Failed to fetch category",
-		})
-	}
-
-	// Cari sub-kategori
-	var subCategoryName string
-	for _, sub := range category.SubCategories {
-		if sub.ID == product.SubCategoryID {
-			subCategoryName = sub.Name
-			break
-		}
-	}
-
-	// Ambil data toko
-	var store model.StoreInfo
-	if err := sellerCollection.FindOne(context.Background(), bson.M{"_id": product.SellerID}).Decode(&store); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Failed to fetch store",
-		})
-	}
-
-	// Kembalikan data produk dengan detail tambahan
-	return c.JSON(fiber.Map{
-		"status": "success",
-		"product": fiber.Map{
-			"id":           product.ID.Hex(),
-			"name":         product.Name,
-			"price":        product.Price,
-			"discount":     product.Discount,
-			"image":        product.Image,
-			"description":  product.Description,
-			"category":     category.Name,
-			"sub_category": subCategoryName,
-			"seller_id": 	product.SellerID,
-		},
-		"store": fiber.Map{
-			"store_name":   store.StoreName,
-			"nik":          store.NIK,
-			"photoselfie":  store.PhotoSelfie,
-			"full_address": store.FullAddress,
-		},
-	})
-}
-func UpdateProductByID(c *fiber.Ctx) error {
+func (h *Handler) UpdateProductByID(c *fiber.Ctx) error {
 	// Ambil ID dari URL parameter
 	productID := c.Params("id")
 
@@ -121,7 +39,7 @@ func UpdateProductByID(c *fiber.Ctx) error {
 		})
 	}
 
-	price, err := strconv.ParseInt(form.Value["price"][0], 10, 64)
+	price, err := strconv.ParseInt(formValue(form, "price"), 10, 64)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid price format",
@@ -129,12 +47,12 @@ func UpdateProductByID(c *fiber.Ctx) error {
 		})
 	}
 
-	discount, err := strconv.ParseInt(form.Value["discount"][0], 10, 64)
+	discount, err := strconv.ParseInt(formValue(form, "discount"), 10, 64)
 	if err != nil {
 		discount = 0 // Default discount jika tidak valid
 	}
 
-	categoryID, err := primitive.ObjectIDFromHex(form.Value["category_id"][0])
+	categoryID, err := primitive.ObjectIDFromHex(formValue(form, "category_id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid Category ID format",
@@ -142,7 +60,7 @@ func UpdateProductByID(c *fiber.Ctx) error {
 		})
 	}
 
-	subCategoryID, err := primitive.ObjectIDFromHex(form.Value["sub_category_id"][0])
+	subCategoryID, err := primitive.ObjectIDFromHex(formValue(form, "sub_category_id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid Sub-Category ID format",
@@ -157,54 +75,62 @@ func UpdateProductByID(c *fiber.Ctx) error {
 		})
 	}
 
-	// Handle file upload
-	var imagePath string
-	fileHeaders := form.File["image"]
-	if len(fileHeaders) > 0 {
-		file := fileHeaders[0]
-		imagePath = fmt.Sprintf("./uploads/%s", file.Filename)
-
-		// Save image file
-		if err := c.SaveFile(file, imagePath); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": "Failed to save image",
-				"error":   err.Error(),
-			})
-		}
-	} else {
-		// Jika tidak ada gambar baru, gunakan gambar lama
-		productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-		var existingProduct model.Product
-		err := productCollection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&existingProduct)
-		if err != nil {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"message": "Product not found",
-				"error":   err.Error(),
-			})
-		}
-		imagePath = existingProduct.Image
+	// Produk lama dibutuhkan untuk gambar default dan varian
+	existingProduct, err := h.repos.Products.FindByID(c.Context(), objectID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Product not found",
+			"error":   err.Error(),
+		})
 	}
 
 	// Update data produk
-	updateData := bson.M{
+	updateData := repository.Fields{
 		"name":            name[0],
 		"price":           price,
 		"discount":        discount,
 		"category_id":     categoryID,
 		"sub_category_id": subCategoryID,
 		"description":     description[0],
-		"image":           imagePath,
+	}
+
+	// Handle file upload: gambar baru menggantikan sampul galeri, jika tidak ada gambar lama dipertahankan
+	var added, removed []model.ProductImage
+	if fileHeaders := form.File["image"]; len(fileHeaders) > 0 {
+		images, old, err := h.replaceCover(c.Context(), existingProduct, fileHeaders[0])
+		if err != nil {
+			return writeImageError(c, err)
+		}
+		for key, value := range galleryFields(existingProduct, images) {
+			updateData[key] = value
+		}
+		added, removed = newImages(existingProduct.Images, images), old
+	}
+
+	// Harga dan diskon produk bervarian adalah ringkasan varian, diatur lewat /products/:id/variants
+	if existingProduct.HasVariants() {
+		delete(updateData, "price")
+		delete(updateData, "discount")
 	}
 
 	// Update produk di database
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-	_, err = productCollection.UpdateOne(context.Background(), bson.M{"_id": objectID}, bson.M{"$set": updateData})
+	err = h.repos.Products.Update(c.Context(), objectID, nil, updateData)
+	if err != nil {
+		h.cleanupProductImages(c.Context(), added)
+	}
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Product not found",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update product",
 			"error":   err.Error(),
 		})
 	}
+	h.reindexProduct(c.Context(), objectID)
+	h.cleanupProductImages(c.Context(), removed)
 
 	return c.JSON(fiber.Map{
 		"message": "Product updated successfully",
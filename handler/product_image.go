@@ -0,0 +1,368 @@
+package handler
+
+import (
+	"be_ecommerce/imaging"
+	"be_ecommerce/middleware"
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/url"
+	"path"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	productImagePrefix = "products/" // Prefix key gambar produk di bucket publik
+	MaxProductImages   = 10          // Juga batas jumlah file per request upload produk di router
+)
+
+// errTooManyImages dikembalikan jika galeri produk melebihi MaxProductImages
+var errTooManyImages = fmt.Errorf("A product can have at most %d images", MaxProductImages)
+
+// storeProductImage memvalidasi file unggahan, membuat rendition lalu menyimpan semuanya di bucket
+// publik dengan nama hash isi file. Gambar yang sama selalu menghasilkan key yang sama.
+func (h *Handler) storeProductImage(ctx context.Context, header *multipart.FileHeader) (model.ProductImage, error) {
+	data, err := readUpload(header, imaging.MaxFileSize)
+	if err != nil {
+		return model.ProductImage{}, err
+	}
+
+	processed, err := imaging.Process(data)
+	if err != nil {
+		return model.ProductImage{}, err
+	}
+	image := model.ProductImage{
+		Hash:       processed.Hash,
+		Renditions: map[string]string{},
+		Width:      processed.Width,
+		Height:     processed.Height,
+	}
+
+	image.Original, err = h.putPublic(ctx, productImagePrefix+processed.Hash+processed.Extension, processed.Original, processed.ContentType)
+	if err != nil {
+		return image, err
+	}
+	for _, name := range imaging.RenditionNames() {
+		location, err := h.putPublic(ctx, productImagePrefix+processed.Hash+"-"+name+".jpg", processed.Renditions[name], "image/jpeg")
+		if err != nil {
+			return image, err
+		}
+		image.Renditions[name] = location
+	}
+	return image, nil
+}
+
+// productImageKey mengembalikan key blob dari URL file gambar produk. Nama file (hash) unik di
+// bawah productImagePrefix, sehingga key tetap bisa diturunkan dari URL backend mana pun.
+func productImageKey(location string) string {
+	if u, err := url.Parse(location); err == nil {
+		location = u.Path
+	}
+	return productImagePrefix + path.Base(location)
+}
+
+// storeProductImages menyimpan beberapa file sekaligus; jika salah satu gagal, file yang sudah
+// tersimpan dibersihkan lagi
+func (h *Handler) storeProductImages(ctx context.Context, headers []*multipart.FileHeader) ([]model.ProductImage, error) {
+	images := make([]model.ProductImage, 0, len(headers))
+	for _, header := range headers {
+		image, err := h.storeProductImage(ctx, header)
+		if err != nil {
+			if image.Hash != "" {
+				images = append(images, image)
+			}
+			h.cleanupProductImages(ctx, images)
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// formGallery menyimpan galeri dari form pembuatan produk: file "image" (form lama) menjadi sampul,
+// diikuti file "images" sesuai urutan unggahan
+func (h *Handler) formGallery(ctx context.Context, form *multipart.Form) ([]model.ProductImage, error) {
+	headers := append(append([]*multipart.FileHeader{}, form.File["image"]...), form.File["images"]...)
+	if len(headers) > MaxProductImages {
+		return nil, errTooManyImages
+	}
+	uploaded, err := h.storeProductImages(ctx, headers)
+	if err != nil {
+		return nil, err
+	}
+	return newImages(nil, uploaded), nil
+}
+
+// replaceCover mengganti gambar pertama galeri dengan file "image" dari form update produk.
+// Sampul lama yang masih dipakai varian tetap di galeri. Mengembalikan galeri baru dan gambar
+// yang perlu dibersihkan setelah update berhasil.
+func (h *Handler) replaceCover(ctx context.Context, product model.Product, header *multipart.FileHeader) (images, removed []model.ProductImage, err error) {
+	uploaded, err := h.storeProductImages(ctx, []*multipart.FileHeader{header})
+	if err != nil {
+		return nil, nil, err
+	}
+	cover := uploaded[0]
+
+	images = []model.ProductImage{cover}
+	for i, image := range product.Images {
+		if image.Hash == cover.Hash {
+			continue
+		}
+		if i == 0 && !usedByVariant(product, image) {
+			removed = append(removed, image)
+			continue
+		}
+		images = append(images, image)
+	}
+	if len(images) > MaxProductImages {
+		h.cleanupProductImages(ctx, newImages(product.Images, images))
+		return nil, nil, errTooManyImages
+	}
+	return images, removed, nil
+}
+
+// usedByVariant melaporkan apakah salah satu varian produk memakai file dari image
+func usedByVariant(product model.Product, image model.ProductImage) bool {
+	for _, variant := range product.Variants {
+		for _, path := range image.Paths() {
+			if variant.Image == path {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cleanupProductImages menghapus file gambar yang tidak lagi dipakai produk mana pun.
+// Kegagalan hanya dicatat; file yatim tidak mengganggu data produk.
+func (h *Handler) cleanupProductImages(ctx context.Context, images []model.ProductImage) {
+	for _, image := range images {
+		inUse, err := h.repos.Products.ImageInUse(ctx, image.Hash)
+		if err != nil {
+			log.Println("Error checking product image", image.Hash+":", err)
+			continue
+		}
+		if inUse {
+			continue
+		}
+		for _, location := range image.Paths() {
+			if location == "" {
+				continue
+			}
+			if err := h.blobs.Public.Delete(ctx, productImageKey(location)); err != nil {
+				log.Println("Error removing product image", location+":", err)
+			}
+		}
+	}
+}
+
+// galleryFields menyusun perubahan galeri untuk repos.Products.Update. Image diisi rendition medium
+// gambar pertama agar client lama tetap menampilkan sampul; produk lama tanpa galeri mempertahankan Image-nya.
+func galleryFields(product model.Product, images []model.ProductImage) repository.Fields {
+	fields := repository.Fields{"images": images}
+	if len(images) > 0 {
+		fields["image"] = images[0].Renditions[imaging.Medium]
+	} else if len(product.Images) > 0 {
+		fields["image"] = ""
+	}
+	return fields
+}
+
+// writeImageError memetakan error validasi gambar ke 400 dan error lainnya ke 500
+func writeImageError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, imaging.ErrTooLarge), errors.Is(err, imaging.ErrUnsupportedFormat),
+		errors.Is(err, imaging.ErrInvalidDimensions), errors.Is(err, imaging.ErrCorrupt):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid image: " + err.Error()})
+	case errors.Is(err, errTooManyImages):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to save image",
+			"error":   err.Error(),
+		})
+	}
+}
+
+// findOwnedProduct mengambil produk dari parameter :id; sellerID nil berarti admin (produk toko mana pun).
+// Jika gagal, respons error sudah ditulis dan ok bernilai false.
+func (h *Handler) findOwnedProduct(c *fiber.Ctx, sellerID *primitive.ObjectID) (product model.Product, ok bool, err error) {
+	productID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return product, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid product ID format"})
+	}
+	product, err = h.repos.Products.FindByID(c.Context(), productID)
+	if err != nil || (sellerID != nil && product.SellerID != *sellerID) {
+		return product, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Product not found"})
+	}
+	return product, true, nil
+}
+
+// saveGallery menyimpan galeri baru lalu membersihkan file gambar yang dibuang
+func (h *Handler) saveGallery(c *fiber.Ctx, product model.Product, sellerID *primitive.ObjectID, images []model.ProductImage, removed []model.ProductImage) error {
+	err := h.repos.Products.Update(c.Context(), product.ID, sellerID, galleryFields(product, images))
+	if err != nil {
+		// Gambar baru yang gagal disimpan ke produk tidak boleh tertinggal di disk
+		h.cleanupProductImages(c.Context(), newImages(product.Images, images))
+		if err == repository.ErrNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Product not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update product images",
+			"error":   err.Error(),
+		})
+	}
+	h.cleanupProductImages(c.Context(), removed)
+
+	return c.JSON(fiber.Map{"message": "Product images updated successfully", "images": images})
+}
+
+// newImages mengembalikan gambar di after yang belum ada di before, tanpa hash ganda
+func newImages(before, after []model.ProductImage) []model.ProductImage {
+	existing := map[string]bool{}
+	for _, image := range before {
+		existing[image.Hash] = true
+	}
+	added := []model.ProductImage{}
+	for _, image := range after {
+		if !existing[image.Hash] {
+			existing[image.Hash] = true
+			added = append(added, image)
+		}
+	}
+	return added
+}
+
+// addImages menambahkan file "images" ke akhir galeri; gambar yang sudah ada di galeri dilewati
+func (h *Handler) addImages(c *fiber.Ctx, sellerID *primitive.ObjectID) error {
+	product, ok, err := h.findOwnedProduct(c, sellerID)
+	if !ok {
+		return err
+	}
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["images"]) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "At least one file in images is required"})
+	}
+	if len(product.Images)+len(form.File["images"]) > MaxProductImages {
+		return writeImageError(c, errTooManyImages)
+	}
+
+	uploaded, err := h.storeProductImages(c.Context(), form.File["images"])
+	if err != nil {
+		return writeImageError(c, err)
+	}
+	images := append([]model.ProductImage{}, product.Images...)
+	images = append(images, newImages(product.Images, uploaded)...)
+	return h.saveGallery(c, product, sellerID, images, nil)
+}
+
+// reorderImages mengubah urutan galeri; gambar pertama menjadi sampul produk
+func (h *Handler) reorderImages(c *fiber.Ctx, sellerID *primitive.ObjectID) error {
+	product, ok, err := h.findOwnedProduct(c, sellerID)
+	if !ok {
+		return err
+	}
+	var req struct {
+		Hashes []string `json:"hashes"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid request body"})
+	}
+
+	byHash := map[string]model.ProductImage{}
+	for _, image := range product.Images {
+		byHash[image.Hash] = image
+	}
+	images := make([]model.ProductImage, 0, len(req.Hashes))
+	for _, hash := range req.Hashes {
+		image, ok := byHash[hash]
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "hashes must list every image of the product exactly once"})
+		}
+		delete(byHash, hash)
+		images = append(images, image)
+	}
+	if len(byHash) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "hashes must list every image of the product exactly once"})
+	}
+	return h.saveGallery(c, product, sellerID, images, nil)
+}
+
+// deleteImage menghapus satu gambar dari galeri; gambar yang masih dipakai varian tidak bisa dihapus
+func (h *Handler) deleteImage(c *fiber.Ctx, sellerID *primitive.ObjectID) error {
+	product, ok, err := h.findOwnedProduct(c, sellerID)
+	if !ok {
+		return err
+	}
+	hash := c.Params("hash")
+
+	var images, removed []model.ProductImage
+	for _, image := range product.Images {
+		if image.Hash == hash {
+			removed = append(removed, image)
+		} else {
+			images = append(images, image)
+		}
+	}
+	if len(removed) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Image not found"})
+	}
+	if usedByVariant(product, removed[0]) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "Image is used by a product variant"})
+	}
+	if images == nil {
+		images = []model.ProductImage{}
+	}
+	return h.saveGallery(c, product, sellerID, images, removed)
+}
+
+// POST /products/:id/images → Admin menambah gambar galeri (multipart: images)
+func (h *Handler) AddProductImages(c *fiber.Ctx) error { return h.addImages(c, nil) }
+
+// PUT /products/:id/images/order → Admin mengubah urutan galeri ({"hashes": [...]})
+func (h *Handler) ReorderProductImages(c *fiber.Ctx) error { return h.reorderImages(c, nil) }
+
+// DELETE /products/:id/images/:hash → Admin menghapus gambar galeri
+func (h *Handler) DeleteProductImage(c *fiber.Ctx) error { return h.deleteImage(c, nil) }
+
+// currentSellerOrForbidden mengambil toko seller yang login; jika gagal, respons 403 sudah ditulis
+func currentSellerOrForbidden(c *fiber.Ctx) (*primitive.ObjectID, error) {
+	sellerID, err := middleware.CurrentSellerID(c)
+	if err != nil {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Forbidden: Seller store not found"})
+	}
+	return &sellerID, nil
+}
+
+// POST /seller/products/:id/images → Seller menambah gambar galeri produknya
+func (h *Handler) AddSellerProductImages(c *fiber.Ctx) error {
+	sellerID, err := currentSellerOrForbidden(c)
+	if sellerID == nil {
+		return err
+	}
+	return h.addImages(c, sellerID)
+}
+
+// PUT /seller/products/:id/images/order → Seller mengubah urutan galeri produknya
+func (h *Handler) ReorderSellerProductImages(c *fiber.Ctx) error {
+	sellerID, err := currentSellerOrForbidden(c)
+	if sellerID == nil {
+		return err
+	}
+	return h.reorderImages(c, sellerID)
+}
+
+// DELETE /seller/products/:id/images/:hash → Seller menghapus gambar galeri produknya
+func (h *Handler) DeleteSellerProductImage(c *fiber.Ctx) error {
+	sellerID, err := currentSellerOrForbidden(c)
+	if sellerID == nil {
+		return err
+	}
+	return h.deleteImage(c, sellerID)
+}
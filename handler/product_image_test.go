@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"be_ecommerce/imaging"
+	"be_ecommerce/model"
+	"be_ecommerce/services"
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testPNG membuat PNG valid; lebar berbeda menghasilkan isi (dan hash) berbeda
+func testPNG(t *testing.T, width int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, imaging.MinDimension))
+	img.Set(0, 0, color.NRGBA{B: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newGalleryEnv(t *testing.T) *testEnv {
+	t.Helper()
+	env := newTestEnv(t, services.MockOutcomePending)
+	env.app.Post("/products/:id/images", env.h.AddProductImages)
+	env.app.Put("/products/:id/images/order", env.h.ReorderProductImages)
+	env.app.Delete("/products/:id/images/:hash", env.h.DeleteProductImage)
+	return env
+}
+
+// uploadImages mengirim file sebagai field "images" ke POST /products/:id/images
+func (e *testEnv) uploadImages(t *testing.T, product primitive.ObjectID, files ...[]byte) (int, map[string]interface{}) {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, file := range files {
+		part, err := w.CreateFormFile("images", "photo.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(file)
+	}
+	w.Close()
+
+	req := httptest.NewRequest(fiber.MethodPost, "/products/"+product.Hex()+"/images", &body)
+	req.Header.Set(fiber.HeaderContentType, w.FormDataContentType())
+	resp, err := e.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func (e *testEnv) productImages(t *testing.T, id primitive.ObjectID) model.Product {
+	t.Helper()
+	product, err := e.repos.Products.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product
+}
+
+// imageFiles mengembalikan file gambar produk yang tersimpan di bucket publik
+func (e *testEnv) imageFiles(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(e.blobDir, "public", productImagePrefix, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestAddProductImagesStoresRenditionsOnce(t *testing.T) {
+	env := newGalleryEnv(t)
+	a := env.product(t, env.sellerID, 50000, 5)
+	first, second := testPNG(t, 300), testPNG(t, 400)
+
+	// File yang sama dua kali dalam satu upload hanya disimpan sekali
+	if status, body := env.uploadImages(t, a, first, second, first); status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	product := env.productImages(t, a)
+	if len(product.Images) != 2 {
+		t.Fatalf("images = %+v, want 2", product.Images)
+	}
+	cover := product.Images[0]
+	if len(cover.Renditions) != len(imaging.RenditionNames()) || cover.Width != 300 {
+		t.Errorf("cover = %+v, want every rendition and width 300", cover)
+	}
+	if product.Image != cover.Renditions[imaging.Medium] {
+		t.Errorf("image = %q, want the medium rendition of the cover", product.Image)
+	}
+	want := 2 * (1 + len(imaging.RenditionNames())) // Asli + rendition per gambar
+	if files := env.imageFiles(t); len(files) != want {
+		t.Errorf("%d files stored, want %d", len(files), want)
+	}
+
+	// Gambar yang sudah ada di galeri dilewati saat diunggah ulang
+	if status, body := env.uploadImages(t, a, second); status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	if images := env.productImages(t, a).Images; len(images) != 2 {
+		t.Errorf("images after re-upload = %d, want 2", len(images))
+	}
+}
+
+func TestAddProductImagesRejectsInvalidFiles(t *testing.T) {
+	env := newGalleryEnv(t)
+	a := env.product(t, env.sellerID, 50000, 5)
+
+	status, body := env.uploadImages(t, a, testPNG(t, 300), []byte("not an image"))
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("status = %d, body = %v, want 400", status, body)
+	}
+	if files := env.imageFiles(t); len(files) != 0 {
+		t.Errorf("files left in storage: %v", files)
+	}
+	if images := env.productImages(t, a).Images; len(images) != 0 {
+		t.Errorf("images = %+v, want none", images)
+	}
+
+	files := make([][]byte, MaxProductImages+1)
+	for i := range files {
+		files[i] = testPNG(t, 200+i)
+	}
+	if status, _ := env.uploadImages(t, a, files...); status != fiber.StatusBadRequest {
+		t.Errorf("%d images: status = %d, want 400", len(files), status)
+	}
+}
+
+func TestDeleteProductImageKeepsFilesSharedWithOtherProducts(t *testing.T) {
+	env := newGalleryEnv(t)
+	a := env.product(t, env.sellerID, 50000, 5)
+	b := env.product(t, env.sellerID, 50000, 5)
+	shared := testPNG(t, 300)
+	env.uploadImages(t, a, shared)
+	env.uploadImages(t, b, shared)
+	hash := env.productImages(t, a).Images[0].Hash
+
+	if status, body := env.do(t, fiber.MethodDelete, "/products/"+a.Hex()+"/images/"+hash, nil); status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	if product := env.productImages(t, a); len(product.Images) != 0 || product.Image != "" {
+		t.Errorf("images = %+v, image = %q, want an empty gallery", product.Images, product.Image)
+	}
+	if files := env.imageFiles(t); len(files) == 0 {
+		t.Fatal("files removed while another product still uses them")
+	}
+
+	if status, body := env.do(t, fiber.MethodDelete, "/products/"+b.Hex()+"/images/"+hash, nil); status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	if files := env.imageFiles(t); len(files) != 0 {
+		t.Errorf("files left after the last product dropped the image: %v", files)
+	}
+}
+
+func TestDeleteProductImageUsedByVariant(t *testing.T) {
+	env := newGalleryEnv(t)
+	a := env.product(t, env.sellerID, 50000, 5)
+	env.uploadImages(t, a, testPNG(t, 300))
+	image := env.productImages(t, a).Images[0]
+
+	variants := []model.ProductVariant{{ID: primitive.NewObjectID(), SKU: "TS-M", Options: map[string]string{"Size": "M"}, Price: 1000, Image: image.Renditions[imaging.Large]}}
+	options := []model.ProductOption{{Name: "Size", Values: []string{"M"}}}
+	if err := env.repos.Products.SetVariants(context.Background(), a, nil, options, variants); err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := env.do(t, fiber.MethodDelete, "/products/"+a.Hex()+"/images/"+image.Hash, nil)
+	if status != fiber.StatusConflict {
+		t.Fatalf("status = %d, body = %v, want 409", status, body)
+	}
+	if files := env.imageFiles(t); len(files) == 0 {
+		t.Error("files of an image used by a variant were removed")
+	}
+}
+
+func TestReorderProductImages(t *testing.T) {
+	env := newGalleryEnv(t)
+	a := env.product(t, env.sellerID, 50000, 5)
+	env.uploadImages(t, a, testPNG(t, 300), testPNG(t, 400))
+	images := env.productImages(t, a).Images
+	path := "/products/" + a.Hex() + "/images/order"
+
+	for _, hashes := range [][]string{
+		{images[1].Hash},
+		{images[1].Hash, images[1].Hash},
+		{images[1].Hash, images[0].Hash, "unknown"},
+	} {
+		if status, _ := env.do(t, fiber.MethodPut, path, fiber.Map{"hashes": hashes}); status != fiber.StatusBadRequest {
+			t.Errorf("hashes %v: status = %d, want 400", hashes, status)
+		}
+	}
+
+	status, body := env.do(t, fiber.MethodPut, path, fiber.Map{"hashes": []string{images[1].Hash, images[0].Hash}})
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	product := env.productImages(t, a)
+	if product.Images[0].Hash != images[1].Hash || product.Image != images[1].Renditions[imaging.Medium] {
+		t.Errorf("cover = %s (%s), want %s", product.Images[0].Hash, product.Image, images[1].Hash)
+	}
+}
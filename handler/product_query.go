@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"be_ecommerce/repository"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultProductPageSize = 20
+	maxProductPageSize     = 100
+	maxProductQueryLength  = 100
+)
+
+var productSorts = map[string]bool{
+	repository.ProductSortNewest:      true,
+	repository.ProductSortPriceAsc:    true,
+	repository.ProductSortPriceDesc:   true,
+	repository.ProductSortRating:      true,
+	repository.ProductSortBestSelling: true,
+}
+
+// productCursor adalah isi cursor yang dikirim ke client; sort ikut disimpan
+// agar cursor dari urutan lain ditolak
+type productCursor struct {
+	Sort  string  `json:"s"`
+	Value float64 `json:"v,omitempty"`
+	ID    string  `json:"id"`
+}
+
+// encodeProductCursor membuat cursor opaque (base64 URL-safe) untuk halaman berikutnya
+func encodeProductCursor(sort string, cursor repository.ProductCursor) string {
+	raw, _ := json.Marshal(productCursor{Sort: sort, Value: cursor.Value, ID: cursor.ID.Hex()})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeProductCursor(sort, value string) (*repository.ProductCursor, error) {
+	invalid := errors.New("Invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor productCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.Sort != sort {
+		return nil, invalid
+	}
+	id, err := primitive.ObjectIDFromHex(cursor.ID)
+	if err != nil {
+		return nil, invalid
+	}
+	return &repository.ProductCursor{Value: cursor.Value, ID: id}, nil
+}
+
+// parseProductQuery membaca filter katalog dari query string GET /products
+func parseProductQuery(c *fiber.Ctx) (repository.ProductQuery, error) {
+	query := repository.ProductQuery{
+		Text:         strings.TrimSpace(c.Query("q")),
+		Sort:         c.Query("sort", repository.ProductSortNewest),
+		DiscountOnly: c.QueryBool("discount"),
+		InStock:      c.QueryBool("in_stock"),
+		Limit:        c.QueryInt("limit", defaultProductPageSize),
+	}
+	if len(query.Text) > maxProductQueryLength {
+		return query, errors.New("Search query is too long")
+	}
+	if !productSorts[query.Sort] {
+		return query, errors.New("Invalid sort, use newest, price_asc, price_desc, rating or best_selling")
+	}
+	if query.Limit < 1 || query.Limit > maxProductPageSize {
+		return query, errors.New("Limit must be between 1 and " + strconv.Itoa(maxProductPageSize))
+	}
+
+	var err error
+	if query.CategoryID, err = optionalObjectID(c, "category_id"); err != nil {
+		return query, err
+	}
+	if query.SubCategoryID, err = optionalObjectID(c, "sub_category_id"); err != nil {
+		return query, err
+	}
+	if query.SellerID, err = optionalObjectID(c, "seller_id"); err != nil {
+		return query, err
+	}
+	if query.MinPrice, err = optionalInt(c, "min_price"); err != nil {
+		return query, err
+	}
+	if query.MaxPrice, err = optionalInt(c, "max_price"); err != nil {
+		return query, err
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return query, errors.New("min_price must not be greater than max_price")
+	}
+	if value := c.Query("min_rating"); value != "" {
+		rating, err := strconv.ParseFloat(value, 64)
+		if err != nil || rating < 0 || rating > 5 {
+			return query, errors.New("min_rating must be between 0 and 5")
+		}
+		query.MinRating = &rating
+	}
+	if value := c.Query("cursor"); value != "" {
+		if query.After, err = decodeProductCursor(query.Sort, value); err != nil {
+			return query, err
+		}
+	}
+	return query, nil
+}
+
+func optionalObjectID(c *fiber.Ctx, key string) (*primitive.ObjectID, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return nil, errors.New("Invalid " + key)
+	}
+	return &id, nil
+}
+
+func optionalInt(c *fiber.Ctx, key string) (*int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return nil, errors.New(key + " must be a non-negative number")
+	}
+	return &n, nil
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"be_ecommerce/repository"
+	"be_ecommerce/services"
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestProductCursorRoundTrip(t *testing.T) {
+	want := repository.ProductCursor{Value: 4.5, ID: primitive.NewObjectID()}
+	encoded := encodeProductCursor(repository.ProductSortRating, want)
+
+	got, err := decodeProductCursor(repository.ProductSortRating, encoded)
+	if err != nil || *got != want {
+		t.Fatalf("decode = %+v, %v, want %+v", got, err, want)
+	}
+	for _, tt := range []struct{ name, sort, value string }{
+		{"other sort", repository.ProductSortPriceAsc, encoded},
+		{"not base64", repository.ProductSortRating, "%%%"},
+		{"not json", repository.ProductSortRating, "bm90LWpzb24"},
+	} {
+		if _, err := decodeProductCursor(tt.sort, tt.value); err == nil {
+			t.Errorf("%s: cursor accepted", tt.name)
+		}
+	}
+}
+
+// listProducts mengikuti next_cursor GET /products sampai halaman terakhir
+func (e *testEnv) listProducts(t *testing.T, sort, limit string) []string {
+	t.Helper()
+	var ids []string
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		query := url.Values{"sort": {sort}, "limit": {limit}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		status, body := e.do(t, fiber.MethodGet, "/products?"+query.Encode(), nil)
+		if status != fiber.StatusOK {
+			t.Fatalf("status = %d, body = %v", status, body)
+		}
+		data, _ := body["data"].([]interface{})
+		for _, item := range data {
+			ids = append(ids, item.(map[string]interface{})["_id"].(string))
+		}
+		cursor, _ = body["pagination"].(map[string]interface{})["next_cursor"].(string)
+		if cursor == "" {
+			return ids
+		}
+	}
+	t.Fatal("pagination did not end")
+	return nil
+}
+
+func TestProductCursorPagesThroughTies(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	env.app.Get("/products", env.h.GetAllProducts)
+
+	// Sebagian besar produk belum punya rating atau penjualan, jadi nilai sort-nya sama
+	rated := env.product(t, env.sellerID, 10000, 1)
+	if err := env.repos.Products.Update(context.Background(), rated, nil, repository.Fields{"rating": 4.5, "sold": 3}); err != nil {
+		t.Fatal(err)
+	}
+	all := map[string]bool{rated.Hex(): true}
+	for i := 0; i < 4; i++ {
+		all[env.product(t, env.sellerID, 10000, 1).Hex()] = true
+	}
+
+	for _, tt := range []struct {
+		sort       string
+		ratedFirst bool
+	}{
+		{repository.ProductSortRating, true},
+		{repository.ProductSortBestSelling, true},
+		{repository.ProductSortPriceAsc, false},
+		{repository.ProductSortNewest, false},
+	} {
+		ids := env.listProducts(t, tt.sort, "2")
+		seen := map[string]bool{}
+		for _, id := range ids {
+			if seen[id] {
+				t.Errorf("%s: product %s returned twice", tt.sort, id)
+			}
+			seen[id] = true
+		}
+		if len(seen) != len(all) {
+			t.Errorf("%s: got %d products, want %d", tt.sort, len(seen), len(all))
+		}
+		if tt.ratedFirst && ids[0] != rated.Hex() {
+			t.Errorf("%s: first product = %s, want the rated one", tt.sort, ids[0])
+		}
+	}
+}
+
+func TestProductCursorFromOtherSortIsRejected(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	env.app.Get("/products", env.h.GetAllProducts)
+	cursor := encodeProductCursor(repository.ProductSortPriceAsc, repository.ProductCursor{Value: 10000, ID: primitive.NewObjectID()})
+
+	status, body := env.do(t, fiber.MethodGet, "/products?sort=rating&cursor="+cursor, nil)
+	if status != fiber.StatusBadRequest || body["message"] != "Invalid cursor" {
+		t.Fatalf("status = %d, body = %v, want 400 Invalid cursor", status, body)
+	}
+}
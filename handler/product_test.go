@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"be_ecommerce/services"
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCreateProductRequiresFormFields(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	env.app.Post("/products", env.h.CreateProduct)
+
+	// seller_id "x" tidak valid, sehingga request tetap ditolak setelah semua field terbaca
+	values := map[string]string{"name": "Kaos", "price": "1000", "discount": "10", "seller_id": "x", "category_id": "x", "sub_category_id": "x"}
+	for _, missing := range []string{"price", "discount", "seller_id", "category_id", "sub_category_id"} {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		for field := range values {
+			if field != missing {
+				w.WriteField(field, values[field])
+			}
+		}
+		w.Close()
+
+		req := httptest.NewRequest(fiber.MethodPost, "/products", &body)
+		req.Header.Set(fiber.HeaderContentType, w.FormDataContentType())
+		resp, err := env.app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("without %s: status = %d, want 400", missing, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package handler
+
+import (
+	"be_ecommerce/middleware"
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"be_ecommerce/services"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errReasonRequired     = errors.New("reason is required")
+	errNoPaymentReference = errors.New("order has no payment to refund")
+)
+
+// pendingRefundTimeout adalah batas refund pending dianggap terhenti sehingga admin boleh mengirim ulang
+const pendingRefundTimeout = 10 * time.Minute
+
+// refundError adalah permintaan refund yang tidak valid; pesannya dikirim apa adanya ke client
+type refundError struct {
+	message string
+}
+
+func (e *refundError) Error() string { return e.message }
+
+// refundFailedError berarti refund sudah dicatat tetapi ditolak payment gateway
+type refundFailedError struct {
+	err error
+}
+
+func (e *refundFailedError) Error() string { return "refund failed: " + e.err.Error() }
+func (e *refundFailedError) Unwrap() error { return e.err }
+
+// Status order yang pembayarannya sudah diterima sehingga bisa di-refund
+var refundableStatuses = map[string]bool{
+	model.OrderStatusPaid:            true,
+	model.OrderStatusProcessing:      true,
+	model.OrderStatusShipped:         true,
+	model.OrderStatusDelivered:       true,
+	model.OrderStatusCompleted:       true,
+	model.OrderStatusLegacyConfirmed: true,
+}
+
+// lineKey mengidentifikasi barang dalam order: produk beserta variannya (nol untuk produk tanpa varian)
+type lineKey struct {
+	product, variant primitive.ObjectID
+}
+
+// refundedQuantities menjumlahkan barang yang sudah di-refund per produk dan varian
+func refundedQuantities(order model.Order) map[lineKey]int {
+	refunded := map[lineKey]int{}
+	for _, refund := range order.Refunds {
+		for _, item := range refund.Items {
+			refunded[lineKey{item.ProductID, item.VariantID}] += item.Quantity
+		}
+	}
+	return refunded
+}
+
+// refundedShipping menjumlahkan ongkir yang sudah di-refund
+func refundedShipping(order model.Order) int {
+	total := 0
+	for _, refund := range order.Refunds {
+		total += refund.Shipping
+	}
+	return total
+}
+
+// remainingStockItems mengembalikan item order yang belum di-refund. Stok barang yang sudah di-refund
+// diputuskan saat refund itu dibuat (Restocked), sehingga item dengan restock=false tidak pernah kembali ke stok.
+func remainingStockItems(order model.Order) []repository.StockItem {
+	refunded := refundedQuantities(order)
+
+	var items []repository.StockItem
+	for _, item := range order.Items {
+		key := lineKey{item.ProductID, item.VariantID}
+		done := refunded[key]
+		if done > item.Quantity {
+			done = item.Quantity
+		}
+		refunded[key] -= done
+		if quantity := item.Quantity - done; quantity > 0 {
+			items = append(items, repository.StockItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: quantity})
+		}
+	}
+	return items
+}
+
+// setOrderStatus adalah changeOrderStatus untuk perubahan status dari user. Pembatalan oleh seller
+// wajib menyertakan alasan, dan pembatalan order yang sudah dibayar langsung me-refund sisa dananya.
+func (h *Handler) setOrderStatus(ctx context.Context, order model.Order, to string, actor statusActor, note string, fields repository.Fields) error {
+	paid := refundableStatuses[order.Status]
+	if to == model.OrderStatusCancelled {
+		if actor.Role == actorSeller && strings.TrimSpace(note) == "" {
+			return errReasonRequired
+		}
+		withReason := repository.Fields{"cancel_reason": note}
+		for key, value := range fields {
+			withReason[key] = value
+		}
+		fields = withReason
+	}
+
+	// Order lama tanpa checkout dibayar di luar payment gateway, sehingga tidak bisa di-refund otomatis
+	if to != model.OrderStatusCancelled || !paid || order.CheckoutID.IsZero() || order.RefundedAmount >= order.TotalAmount {
+		return h.changeOrderStatus(ctx, order, to, actor, note, fields)
+	}
+	if err := checkTransition(order.Status, to, actor.Role); err != nil {
+		return err
+	}
+
+	// Sisa barang dikembalikan ke stok dan sisa dananya di-refund bersama pembatalan
+	refund := model.Refund{
+		Shipping:  order.ShippingCost - refundedShipping(order),
+		Reason:    note,
+		ActorID:   actor.ID,
+		ActorRole: actor.Role,
+	}
+	refunded := refundedQuantities(order)
+	for _, item := range order.Items {
+		key := lineKey{item.ProductID, item.VariantID}
+		done := refunded[key]
+		if done > item.Quantity {
+			done = item.Quantity
+		}
+		refunded[key] -= done
+		if quantity := item.Quantity - done; quantity > 0 {
+			refund.Items = append(refund.Items, model.RefundItem{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Quantity:  quantity,
+				Amount:    item.Price * quantity,
+				Restocked: true,
+			})
+		}
+	}
+	refund.Amount = order.TotalAmount - order.RefundedAmount
+
+	var restock []repository.StockItem
+	for _, item := range refund.Items {
+		restock = append(restock, repository.StockItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+	return h.issueRefund(ctx, order, refund, restock, func(ctx context.Context, refunded model.Order) error {
+		return h.transitionOrder(ctx, refunded, to, actor, note, fields)
+	})
+}
+
+// issueRefund menyimpan refund berstatus pending di order, mengembalikan stok restock dan menjalankan
+// then (perubahan status order yang menyertai refund, boleh nil) dalam satu transaksi, lalu meneruskan
+// refund ke payment gateway. then menerima order yang sudah berisi refund tersebut. Jika proses berhenti
+// sebelum gateway menjawab, refund tetap pending dan bisa dikirim ulang lewat RetryRefundHandler.
+func (h *Handler) issueRefund(ctx context.Context, order model.Order, refund model.Refund, restock []repository.StockItem, then func(ctx context.Context, refunded model.Order) error) error {
+	if order.CheckoutID.IsZero() {
+		return errNoPaymentReference
+	}
+	checkout, err := h.repos.Checkouts.FindByID(ctx, order.CheckoutID)
+	if err != nil {
+		return err
+	}
+
+	refund.ID = primitive.NewObjectID()
+	refund.Status = model.RefundPending
+	refund.CreatedAt = time.Now()
+	refunded := order
+	refunded.Refunds = append(append([]model.Refund{}, order.Refunds...), refund)
+	refunded.RefundedAmount += refund.Amount
+
+	err = h.repos.Tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.repos.Orders.AddRefund(ctx, order.ID, order.RefundedAmount, refund); err != nil {
+			return err
+		}
+		if len(restock) > 0 {
+			if err := h.repos.Products.RestoreStock(ctx, restock); err != nil {
+				return err
+			}
+		}
+		if then != nil {
+			return then(ctx, refunded)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return h.sendRefund(ctx, order.ID, checkout.PaymentReference, refund)
+}
+
+// sendRefund meminta payment gateway mengembalikan dana lalu mencatat hasilnya.
+// ID refund dipakai sebagai refund key sehingga percobaan ulang tidak me-refund dua kali.
+func (h *Handler) sendRefund(ctx context.Context, orderID primitive.ObjectID, reference string, refund model.Refund) error {
+	refundErr := h.payments.Refund(ctx, services.RefundRequest{
+		Reference: reference,
+		Key:       refund.ID.Hex(),
+		Amount:    refund.Amount,
+		Reason:    refund.Reason,
+	})
+
+	status, message := model.RefundSucceeded, ""
+	if refundErr != nil {
+		status, message = model.RefundFailed, refundErr.Error()
+	}
+	if err := h.repos.Orders.UpdateRefund(ctx, orderID, refund.ID, status, message); err != nil {
+		return err
+	}
+	if refundErr != nil {
+		return &refundFailedError{refundErr}
+	}
+	return nil
+}
+
+// refundRequest adalah body permintaan refund sebagian
+type refundRequest struct {
+	Items    []refundItemRequest `json:"items"`
+	Shipping bool                `json:"shipping"` // Ikut kembalikan sisa ongkir
+	Reason   string              `json:"reason"`
+	// Restock menentukan apakah barang dikembalikan ke stok; default hanya jika order belum dikirim
+	Restock *bool `json:"restock"`
+}
+
+type refundItemRequest struct {
+	ProductID primitive.ObjectID `json:"product_id"`
+	VariantID primitive.ObjectID `json:"variant_id"` // Wajib jika produk dibeli per varian
+	Quantity  int                `json:"quantity"`
+}
+
+// refundOrderItems me-refund sebagian barang dari order. Barang yang belum dikirim dikembalikan ke stok.
+// Jika seluruh dana sudah dikembalikan, order berpindah ke status Refunded.
+func (h *Handler) refundOrderItems(ctx context.Context, order model.Order, req refundRequest, actor statusActor) (model.Order, error) {
+	if strings.TrimSpace(req.Reason) == "" {
+		return order, errReasonRequired
+	}
+	if !refundableStatuses[order.Status] {
+		return order, &refundError{"Only paid orders can be refunded"}
+	}
+
+	restock := unshippedStatuses[order.Status]
+	if req.Restock != nil {
+		restock = *req.Restock
+	}
+	refund := model.Refund{Reason: req.Reason, ActorID: actor.ID, ActorRole: actor.Role}
+	var stock []repository.StockItem
+
+	refunded := refundedQuantities(order)
+	for _, requested := range req.Items {
+		if requested.Quantity < 1 {
+			return order, &refundError{"Quantity must be greater than 0"}
+		}
+		key := lineKey{requested.ProductID, requested.VariantID}
+		ordered, price := 0, 0
+		for _, item := range order.Items {
+			if (lineKey{item.ProductID, item.VariantID}) == key {
+				ordered += item.Quantity
+				price = item.Price
+			}
+		}
+		if requested.Quantity > ordered-refunded[key] {
+			return order, &refundError{"Refund quantity exceeds the remaining quantity of product " + requested.ProductID.Hex()}
+		}
+		refunded[key] += requested.Quantity
+
+		refund.Items = append(refund.Items, model.RefundItem{
+			ProductID: requested.ProductID,
+			VariantID: requested.VariantID,
+			Quantity:  requested.Quantity,
+			Amount:    price * requested.Quantity,
+			Restocked: restock,
+		})
+		refund.Amount += price * requested.Quantity
+		if restock {
+			stock = append(stock, repository.StockItem{ProductID: requested.ProductID, VariantID: requested.VariantID, Quantity: requested.Quantity})
+		}
+	}
+	if req.Shipping {
+		refund.Shipping = order.ShippingCost - refundedShipping(order)
+		refund.Amount += refund.Shipping
+	}
+	if refund.Amount <= 0 {
+		return order, &refundError{"Nothing to refund"}
+	}
+	if order.RefundedAmount+refund.Amount > order.TotalAmount {
+		return order, &refundError{"Refund exceeds the order total"}
+	}
+
+	// Refund yang melunasi seluruh order memindahkan order ke Refunded di transaksi yang sama
+	var then func(ctx context.Context, refunded model.Order) error
+	if order.RefundedAmount+refund.Amount >= order.TotalAmount {
+		then = func(ctx context.Context, refunded model.Order) error {
+			return h.transitionOrder(ctx, refunded, model.OrderStatusRefunded, systemActor, "Fully refunded", nil)
+		}
+	}
+	if err := h.issueRefund(ctx, order, refund, stock, then); err != nil {
+		return order, err
+	}
+	return h.repos.Orders.FindByID(ctx, order.ID)
+}
+
+// POST /orders/:order_id/refunds → Seller me-refund sebagian barang dari order miliknya
+func (h *Handler) CreateSellerRefundHandler(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("order_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Order ID"})
+	}
+
+	var req refundRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	sellerID, err := middleware.CurrentSellerID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	order, err := h.repos.Orders.FindForSeller(c.Context(), objID, sellerID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+
+	order, err = h.refundOrderItems(c.Context(), order, req, statusActor{ID: sellerID, Role: actorSeller})
+	if err != nil {
+		return writeTransitionError(c, err, "Failed to refund order")
+	}
+
+	return c.JSON(fiber.Map{"message": "Refund issued successfully", "data": order})
+}
+
+// POST /admin/orders/:order_id/refunds → Admin me-refund sebagian barang dari order mana pun
+func (h *Handler) AdminCreateRefundHandler(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("order_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Order ID"})
+	}
+
+	var req refundRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	adminID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	order, err := h.repos.Orders.FindByID(c.Context(), objID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+
+	order, err = h.refundOrderItems(c.Context(), order, req, statusActor{ID: adminID, Role: actorAdmin})
+	if err != nil {
+		return writeTransitionError(c, err, "Failed to refund order")
+	}
+
+	return c.JSON(fiber.Map{"message": "Refund issued successfully", "data": order})
+}
+
+// POST /admin/orders/:order_id/refunds/:refund_id/retry → Kirim ulang refund yang gagal di payment gateway
+func (h *Handler) RetryRefundHandler(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("order_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Order ID"})
+	}
+	refundID, err := primitive.ObjectIDFromHex(c.Params("refund_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Refund ID"})
+	}
+
+	order, err := h.repos.Orders.FindByID(c.Context(), objID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+
+	var refund *model.Refund
+	for i := range order.Refunds {
+		if order.Refunds[i].ID == refundID {
+			refund = &order.Refunds[i]
+		}
+	}
+	if refund == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Refund not found"})
+	}
+	// Refund pending yang sudah lama berarti prosesnya berhenti sebelum gateway menjawab
+	stuck := refund.Status == model.RefundPending && time.Since(refund.CreatedAt) > pendingRefundTimeout
+	if refund.Status != model.RefundFailed && !stuck {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only failed or stuck pending refunds can be retried"})
+	}
+
+	checkout, err := h.repos.Checkouts.FindByID(c.Context(), order.CheckoutID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch checkout"})
+	}
+
+	if err := h.sendRefund(c.Context(), order.ID, checkout.PaymentReference, *refund); err != nil {
+		return writeTransitionError(c, err, "Failed to retry refund")
+	}
+	return c.JSON(fiber.Map{"message": "Refund issued successfully"})
+}
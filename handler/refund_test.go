@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/services"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// paidOrder membuat checkout lewat POST /payment lalu menerapkan notification "paid" dari mock
+func (e *testEnv) paidOrder(t *testing.T, amount int, items ...model.OrderItem) model.Order {
+	t.Helper()
+	status, body := e.do(t, fiber.MethodPost, "/payment", orderBody(amount, items...))
+	if status != fiber.StatusOK {
+		t.Fatalf("payment status = %d, body = %v", status, body)
+	}
+	checkoutID, _ := primitive.ObjectIDFromHex(body["checkout_id"].(string))
+	checkout, err := e.repos.Checkouts.FindByID(context.Background(), checkoutID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment, err := e.mock.ParseNotification([]byte(fmt.Sprintf(`{"order_id": %q, "status": "paid"}`, checkout.PaymentReference)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.h.applyPaymentStatus(context.Background(), payment); err != nil {
+		t.Fatal(err)
+	}
+
+	orders, err := e.repos.Orders.ListByCheckout(context.Background(), checkoutID)
+	if err != nil || len(orders) != 1 || orders[0].Status != model.OrderStatusPaid {
+		t.Fatalf("orders = %+v, err = %v, want one Paid order", orders, err)
+	}
+	return orders[0]
+}
+
+func (e *testEnv) order(t *testing.T, id primitive.ObjectID) model.Order {
+	t.Helper()
+	order, err := e.repos.Orders.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestSellerRefundRestocksUnshippedItems(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.paidOrder(t, 3*50000+testShippingRate, orderItem(a, 3))
+
+	status, body := env.do(t, fiber.MethodPost, "/orders/"+order.ID.Hex()+"/refunds", fiber.Map{
+		"reason": "Out of stock in warehouse",
+		"items":  []fiber.Map{{"product_id": a, "quantity": 1}},
+	})
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	if got := env.stock(t, a); got != 3 {
+		t.Errorf("stock = %d, want 3", got)
+	}
+	order = env.order(t, order.ID)
+	if order.RefundedAmount != 50000 || order.Status != model.OrderStatusPaid {
+		t.Errorf("refunded = %d, status = %s, want 50000 and Paid", order.RefundedAmount, order.Status)
+	}
+	if len(order.Refunds) != 1 || order.Refunds[0].Status != model.RefundSucceeded {
+		t.Errorf("refunds = %+v, want one succeeded refund", order.Refunds)
+	}
+}
+
+func TestRefundOfShippedOrderDoesNotRestock(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.paidOrder(t, 2*50000+testShippingRate, orderItem(a, 2))
+
+	seller := statusActor{ID: env.sellerID, Role: actorSeller}
+	for _, to := range []string{model.OrderStatusProcessing, model.OrderStatusShipped} {
+		if err := env.h.changeOrderStatus(context.Background(), env.order(t, order.ID), to, seller, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, body := env.do(t, fiber.MethodPost, "/orders/"+order.ID.Hex()+"/refunds", fiber.Map{
+		"reason": "Damaged in transit",
+		"items":  []fiber.Map{{"product_id": a, "quantity": 1}},
+	})
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	if got := env.stock(t, a); got != 3 {
+		t.Errorf("stock = %d, want 3 (shipped items stay with the buyer)", got)
+	}
+}
+
+func TestFullRefundWithoutRestockKeepsStock(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.paidOrder(t, 2*50000+testShippingRate, orderItem(a, 2))
+
+	status, body := env.do(t, fiber.MethodPost, "/orders/"+order.ID.Hex()+"/refunds", fiber.Map{
+		"reason":   "Goodwill refund",
+		"items":    []fiber.Map{{"product_id": a, "quantity": 2}},
+		"shipping": true,
+		"restock":  false,
+	})
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	order = env.order(t, order.ID)
+	if order.Status != model.OrderStatusRefunded || order.RefundedAmount != order.TotalAmount {
+		t.Errorf("status = %s, refunded = %d of %d, want fully Refunded", order.Status, order.RefundedAmount, order.TotalAmount)
+	}
+	// Perpindahan ke Refunded tidak boleh mengembalikan item yang di-refund dengan restock=false
+	if got := env.stock(t, a); got != 3 {
+		t.Errorf("stock = %d, want 3", got)
+	}
+}
+
+func TestRefundExceedingOrderedQuantityIsRejected(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.paidOrder(t, 50000+testShippingRate, orderItem(a, 1))
+
+	status, body := env.do(t, fiber.MethodPost, "/orders/"+order.ID.Hex()+"/refunds", fiber.Map{
+		"reason": "Duplicate",
+		"items":  []fiber.Map{{"product_id": a, "quantity": 2}},
+	})
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("status = %d, body = %v, want 400", status, body)
+	}
+	if got := env.stock(t, a); got != 4 {
+		t.Errorf("stock = %d, want 4", got)
+	}
+	if order = env.order(t, order.ID); order.RefundedAmount != 0 {
+		t.Errorf("refunded = %d, want 0", order.RefundedAmount)
+	}
+}
+
+// failingRefunds adalah payment gateway yang menolak semua refund
+type failingRefunds struct {
+	services.PaymentProvider
+}
+
+func (failingRefunds) Refund(ctx context.Context, req services.RefundRequest) error {
+	return errors.New("gateway unavailable")
+}
+
+func TestCancelPaidOrderRefundsRemainingAmount(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.paidOrder(t, 2*50000+testShippingRate, orderItem(a, 2))
+
+	customer := statusActor{ID: env.userID, Role: actorCustomer}
+	if err := env.h.setOrderStatus(context.Background(), order, model.OrderStatusCancelled, customer, "Changed my mind", nil); err != nil {
+		t.Fatal(err)
+	}
+	order = env.order(t, order.ID)
+	if order.Status != model.OrderStatusCancelled || order.RefundedAmount != order.TotalAmount {
+		t.Errorf("status = %s, refunded = %d of %d, want Cancelled and fully refunded", order.Status, order.RefundedAmount, order.TotalAmount)
+	}
+	if len(order.Refunds) != 1 || order.Refunds[0].Status != model.RefundSucceeded {
+		t.Errorf("refunds = %+v, want one succeeded refund", order.Refunds)
+	}
+	if got := env.stock(t, a); got != 5 {
+		t.Errorf("stock = %d, want 5", got)
+	}
+}
+
+func TestCancelWithFailedRefundKeepsRecordForRetry(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.paidOrder(t, 2*50000+testShippingRate, orderItem(a, 2))
+
+	env.h.payments = failingRefunds{env.mock}
+	seller := statusActor{ID: env.sellerID, Role: actorSeller}
+	err := env.h.setOrderStatus(context.Background(), order, model.OrderStatusCancelled, seller, "Out of stock", nil)
+	var ferr *refundFailedError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("err = %v, want refundFailedError", err)
+	}
+
+	// Pembatalan dan catatan refund tersimpan bersama, sehingga admin bisa mengirim ulang refund-nya
+	order = env.order(t, order.ID)
+	if order.Status != model.OrderStatusCancelled || len(order.Refunds) != 1 || order.Refunds[0].Status != model.RefundFailed {
+		t.Fatalf("status = %s, refunds = %+v, want Cancelled with one failed refund", order.Status, order.Refunds)
+	}
+	if got := env.stock(t, a); got != 5 {
+		t.Errorf("stock = %d, want 5", got)
+	}
+
+	env.h.payments = env.mock
+	checkout, err := env.repos.Checkouts.FindByID(context.Background(), order.CheckoutID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.h.sendRefund(context.Background(), order.ID, checkout.PaymentReference, order.Refunds[0]); err != nil {
+		t.Fatal(err)
+	}
+	if order = env.order(t, order.ID); order.Refunds[0].Status != model.RefundSucceeded {
+		t.Errorf("refund status after retry = %s, want succeeded", order.Refunds[0].Status)
+	}
+}
+
+func TestFullItemRefundMovesOrderToRefundedWithTheRefund(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.paidOrder(t, 50000+testShippingRate, orderItem(a, 1))
+
+	env.h.payments = failingRefunds{env.mock}
+	status, body := env.do(t, fiber.MethodPost, "/orders/"+order.ID.Hex()+"/refunds", fiber.Map{
+		"reason":   "Wrong item",
+		"items":    []fiber.Map{{"product_id": a, "quantity": 1}},
+		"shipping": true,
+	})
+	if status != fiber.StatusBadGateway {
+		t.Fatalf("status = %d, body = %v, want 502", status, body)
+	}
+	order = env.order(t, order.ID)
+	if order.Status != model.OrderStatusRefunded || len(order.Refunds) != 1 || order.Refunds[0].Status != model.RefundFailed {
+		t.Errorf("status = %s, refunds = %+v, want Refunded with one failed refund", order.Status, order.Refunds)
+	}
+	if got := env.stock(t, a); got != 5 {
+		t.Errorf("stock = %d, want 5", got)
+	}
+}
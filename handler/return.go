@@ -0,0 +1,546 @@
+package handler
+
+import (
+	"be_ecommerce/imaging"
+	"be_ecommerce/middleware"
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultReturnWindowDays = 7          // Batas hari pengajuan retur setelah Delivered jika RETURN_WINDOW_DAYS tidak diisi
+	MaxReturnPhotos         = 5          // Juga batas jumlah file per request POST /returns di router
+	returnPhotoPrefix       = "returns/" // Prefix key foto bukti retur di bucket publik
+)
+
+// returnTransitions: Requested → Approved → Shipped → Received → Refunded.
+// Retur yang ditolak seller bisa dieskalasi pembeli ke admin, yang memutuskan Approved atau Closed.
+var returnTransitions = transitionTable{
+	model.ReturnRequested: {
+		model.ReturnApproved:  {actorSeller},
+		model.ReturnRejected:  {actorSeller},
+		model.ReturnCancelled: {actorCustomer},
+	},
+	model.ReturnApproved: {
+		model.ReturnShipped:   {actorCustomer},
+		model.ReturnCancelled: {actorCustomer},
+	},
+	model.ReturnRejected: {
+		model.ReturnEscalated: {actorCustomer},
+	},
+	model.ReturnEscalated: {
+		model.ReturnApproved: {actorAdmin},
+		model.ReturnClosed:   {actorAdmin},
+	},
+	model.ReturnShipped: {
+		model.ReturnReceived: {actorSeller, actorAdmin},
+	},
+	model.ReturnReceived: {
+		model.ReturnRefunded: {actorSystem},
+	},
+}
+
+// Status retur yang sudah selesai dan tidak lagi menahan kuantitas barang
+var closedReturnStatuses = map[string]bool{
+	model.ReturnRefunded:  true, // Kuantitasnya sudah tercatat di refund order
+	model.ReturnCancelled: true,
+	model.ReturnClosed:    true,
+}
+
+// returnWindow membaca batas waktu pengajuan retur dari RETURN_WINDOW_DAYS
+func returnWindow() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("RETURN_WINDOW_DAYS"))
+	if err != nil || days < 0 {
+		days = defaultReturnWindowDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// deliveredAt mengambil waktu order berstatus Delivered dari timeline; order lama memakai CreatedAt
+func deliveredAt(order model.Order) time.Time {
+	for i := len(order.StatusHistory) - 1; i >= 0; i-- {
+		if order.StatusHistory[i].To == model.OrderStatusDelivered {
+			return order.StatusHistory[i].At
+		}
+	}
+	return order.CreatedAt
+}
+
+// changeReturnStatus memindahkan retur ke status to jika diizinkan untuk actor dan status-nya belum berubah
+func (h *Handler) changeReturnStatus(ctx context.Context, ret model.ReturnRequest, to string, actor statusActor, note string, fields repository.Fields) error {
+	if err := returnTransitions.check(ret.Status, to, actor.Role); err != nil {
+		return err
+	}
+	change := model.StatusChange{
+		From:      ret.Status,
+		To:        to,
+		At:        time.Now(),
+		ActorID:   actor.ID,
+		ActorRole: actor.Role,
+		Note:      note,
+	}
+	return h.repos.Returns.Transition(ctx, ret.ID, change, fields)
+}
+
+// findReturn mengambil retur dari parameter :return_id. Jika tidak ditemukan atau bukan milik
+// pemanggil (owned bernilai false), respons error sudah ditulis dan ok bernilai false.
+func (h *Handler) findReturn(c *fiber.Ctx, owned func(model.ReturnRequest) bool) (ret model.ReturnRequest, ok bool, err error) {
+	id, err := primitive.ObjectIDFromHex(c.Params("return_id"))
+	if err != nil {
+		return ret, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Return ID"})
+	}
+	ret, err = h.repos.Returns.FindByID(c.Context(), id)
+	if err != nil || !owned(ret) {
+		return ret, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Return not found"})
+	}
+	return ret, true, nil
+}
+
+// ownedByCustomer dan ownedBySeller membatasi findReturn ke pembeli atau toko yang login
+func ownedByCustomer(c *fiber.Ctx) func(model.ReturnRequest) bool {
+	userID, err := middleware.CurrentUserID(c)
+	return func(ret model.ReturnRequest) bool { return err == nil && ret.UserID == userID }
+}
+
+func ownedBySeller(c *fiber.Ctx) func(model.ReturnRequest) bool {
+	sellerID, err := middleware.CurrentSellerID(c)
+	return func(ret model.ReturnRequest) bool { return err == nil && ret.SellerID == sellerID }
+}
+
+func anyReturn(model.ReturnRequest) bool { return true }
+
+// POST /returns → Pembeli mengajukan retur untuk satu item order
+// (multipart: order_id, product_id, variant_id untuk produk bervarian, quantity, reason, photos)
+func (h *Handler) CreateReturnHandler(c *fiber.Ctx) error {
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid form data"})
+	}
+
+	orderID, err := primitive.ObjectIDFromHex(c.FormValue("order_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Order ID"})
+	}
+	productID, err := primitive.ObjectIDFromHex(c.FormValue("product_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Product ID"})
+	}
+	variantID := primitive.NilObjectID
+	if value := c.FormValue("variant_id"); value != "" {
+		if variantID, err = primitive.ObjectIDFromHex(value); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Variant ID"})
+		}
+	}
+	key := lineKey{productID, variantID}
+	quantity, err := strconv.Atoi(c.FormValue("quantity", "1"))
+	if err != nil || quantity < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Quantity must be greater than 0"})
+	}
+	reason := strings.TrimSpace(c.FormValue("reason"))
+	if reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason is required"})
+	}
+
+	// Foto bukti wajib ada
+	photos := form.File["photos"]
+	if len(photos) == 0 || len(photos) > MaxReturnPhotos {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Between 1 and %d photos are required", MaxReturnPhotos),
+		})
+	}
+	for _, photo := range photos {
+		if !strings.HasPrefix(photo.Header.Get("Content-Type"), "image/") {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Photos must be images"})
+		}
+	}
+
+	// Retur hanya untuk order milik pembeli yang sudah diterima, dalam batas waktu retur
+	order, err := h.repos.Orders.FindByID(c.Context(), orderID)
+	if err != nil || order.UserID != userID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+	if order.Status != model.OrderStatusDelivered && order.Status != model.OrderStatusCompleted {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only delivered orders can be returned"})
+	}
+	if time.Since(deliveredAt(order)) > returnWindow() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The return window for this order has closed"})
+	}
+
+	// Kuantitas tidak boleh melebihi sisa barang yang belum di-refund atau sedang diretur
+	ordered := 0
+	for _, item := range order.Items {
+		if (lineKey{item.ProductID, item.VariantID}) == key {
+			ordered += item.Quantity
+		}
+	}
+	if ordered == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Product is not part of this order"})
+	}
+	existing, err := h.repos.Returns.Find(c.Context(), repository.ReturnFilter{OrderID: &order.ID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch returns"})
+	}
+	taken := refundedQuantities(order)[key]
+	for _, ret := range existing {
+		if (lineKey{ret.ProductID, ret.VariantID}) == key && !closedReturnStatuses[ret.Status] {
+			taken += ret.Quantity
+		}
+	}
+	if quantity > ordered-taken {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":     "Return quantity exceeds the returnable quantity",
+			"available": ordered - taken,
+		})
+	}
+
+	ret := model.ReturnRequest{
+		ID:        primitive.NewObjectID(),
+		OrderID:   order.ID,
+		UserID:    userID,
+		SellerID:  order.SellerID,
+		ProductID: productID,
+		VariantID: variantID,
+		Quantity:  quantity,
+		Reason:    reason,
+		Status:    model.ReturnRequested,
+		CreatedAt: time.Now(),
+	}
+	ret.StatusHistory = []model.StatusChange{{
+		To:        model.ReturnRequested,
+		At:        ret.CreatedAt,
+		ActorID:   userID,
+		ActorRole: actorCustomer,
+	}}
+
+	// Semua foto dibaca dulu agar file yang tidak valid tidak meninggalkan foto lain di storage
+	contents := make([][]byte, len(photos))
+	for i, photo := range photos {
+		if contents[i], err = readUpload(photo, imaging.MaxFileSize); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Photos must not be larger than 5 MB"})
+		}
+	}
+
+	// Simpan foto bukti; foto yang sudah tersimpan dihapus lagi jika retur gagal dibuat
+	var keys []string
+	for i, photo := range photos {
+		key := fmt.Sprintf("%s%s-%d%s", returnPhotoPrefix, ret.ID.Hex(), i, filepath.Ext(photo.Filename))
+		location, err := h.putPublic(c.Context(), key, contents[i], photo.Header.Get("Content-Type"))
+		if err != nil {
+			log.Println("Error saving return photo:", err)
+			h.removeReturnPhotos(c.Context(), keys)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save photo"})
+		}
+		keys = append(keys, key)
+		ret.Photos = append(ret.Photos, location)
+	}
+
+	if err := h.repos.Returns.Create(c.Context(), &ret); err != nil {
+		h.removeReturnPhotos(c.Context(), keys)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create return request"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Return requested successfully", "data": ret})
+}
+
+// removeReturnPhotos menghapus foto bukti yang sudah diunggah untuk retur yang gagal dibuat
+func (h *Handler) removeReturnPhotos(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := h.blobs.Public.Delete(ctx, key); err != nil {
+			log.Println("Error removing return photo", key+":", err)
+		}
+	}
+}
+
+// GET /returns → Daftar retur milik pembeli yang login
+func (h *Handler) GetCustomerReturnsHandler(c *fiber.Ctx) error {
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	returns, err := h.repos.Returns.Find(c.Context(), repository.ReturnFilter{UserID: &userID, Status: c.Query("status")})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch returns"})
+	}
+	return c.JSON(fiber.Map{"message": "Returns fetched successfully", "data": returns})
+}
+
+// GET /seller/returns → Daftar retur untuk toko yang login, bisa difilter ?status=
+func (h *Handler) GetSellerReturnsHandler(c *fiber.Ctx) error {
+	sellerID, err := middleware.CurrentSellerID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	returns, err := h.repos.Returns.Find(c.Context(), repository.ReturnFilter{SellerID: &sellerID, Status: c.Query("status")})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch returns"})
+	}
+	return c.JSON(fiber.Map{"message": "Returns fetched successfully", "data": returns})
+}
+
+// GET /admin/returns → Daftar retur untuk admin, default yang sedang dieskalasi
+func (h *Handler) GetAdminReturnsHandler(c *fiber.Ctx) error {
+	returns, err := h.repos.Returns.Find(c.Context(), repository.ReturnFilter{Status: c.Query("status", model.ReturnEscalated)})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch returns"})
+	}
+	return c.JSON(fiber.Map{"message": "Returns fetched successfully", "data": returns})
+}
+
+// PUT /seller/returns/:return_id → Seller menyetujui atau menolak retur; penolakan wajib disertai note
+func (h *Handler) ReviewReturnHandler(c *fiber.Ctx) error {
+	var body struct {
+		Decision string `json:"decision"` // "approve" atau "reject"
+		Note     string `json:"note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	to := model.ReturnApproved
+	switch body.Decision {
+	case "approve":
+	case "reject":
+		to = model.ReturnRejected
+		if strings.TrimSpace(body.Note) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A note with the reason is required"})
+		}
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Decision must be approve or reject"})
+	}
+
+	ret, ok, err := h.findReturn(c, ownedBySeller(c))
+	if !ok {
+		return err
+	}
+
+	sellerID, _ := middleware.CurrentSellerID(c)
+	actor := statusActor{ID: sellerID, Role: actorSeller}
+	err = h.changeReturnStatus(c.Context(), ret, to, actor, body.Note, repository.Fields{"seller_note": body.Note})
+	if err != nil {
+		return writeTransitionError(c, err, "Failed to update return")
+	}
+	return c.JSON(fiber.Map{"message": "Return updated successfully"})
+}
+
+// PUT /returns/:return_id/shipment → Pembeli mengirim barang kembali dan mengisi nomor resi
+func (h *Handler) ShipReturnHandler(c *fiber.Ctx) error {
+	var body struct {
+		Courier        string `json:"courier"`
+		TrackingNumber string `json:"tracking_number"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if body.Courier == "" || body.TrackingNumber == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Courier and tracking number are required"})
+	}
+
+	ret, ok, err := h.findReturn(c, ownedByCustomer(c))
+	if !ok {
+		return err
+	}
+
+	actor := statusActor{ID: ret.UserID, Role: actorCustomer}
+	fields := repository.Fields{"courier": body.Courier, "tracking_number": body.TrackingNumber}
+	err = h.changeReturnStatus(c.Context(), ret, model.ReturnShipped, actor, "", fields)
+	if err != nil {
+		return writeTransitionError(c, err, "Failed to update return")
+	}
+	return c.JSON(fiber.Map{"message": "Return shipment recorded successfully"})
+}
+
+// PUT /returns/:return_id/cancel → Pembeli membatalkan retur sebelum barang dikirim
+func (h *Handler) CancelReturnHandler(c *fiber.Ctx) error {
+	ret, ok, err := h.findReturn(c, ownedByCustomer(c))
+	if !ok {
+		return err
+	}
+
+	actor := statusActor{ID: ret.UserID, Role: actorCustomer}
+	if err := h.changeReturnStatus(c.Context(), ret, model.ReturnCancelled, actor, "", nil); err != nil {
+		return writeTransitionError(c, err, "Failed to cancel return")
+	}
+	return c.JSON(fiber.Map{"message": "Return cancelled successfully"})
+}
+
+// POST /returns/:return_id/escalate → Pembeli meminta admin meninjau retur yang ditolak seller
+func (h *Handler) EscalateReturnHandler(c *fiber.Ctx) error {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if strings.TrimSpace(body.Reason) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason is required"})
+	}
+
+	ret, ok, err := h.findReturn(c, ownedByCustomer(c))
+	if !ok {
+		return err
+	}
+
+	actor := statusActor{ID: ret.UserID, Role: actorCustomer}
+	escalation := model.ReturnEscalation{Reason: body.Reason, OpenedAt: time.Now()}
+	err = h.changeReturnStatus(c.Context(), ret, model.ReturnEscalated, actor, body.Reason, repository.Fields{"escalation": escalation})
+	if err != nil {
+		return writeTransitionError(c, err, "Failed to escalate return")
+	}
+	return c.JSON(fiber.Map{"message": "Return escalated to admin"})
+}
+
+// PUT /admin/returns/:return_id/resolve → Admin memutuskan sengketa: approve melanjutkan retur, reject menutupnya
+func (h *Handler) ResolveReturnHandler(c *fiber.Ctx) error {
+	var body struct {
+		Decision string `json:"decision"` // "approve" atau "reject"
+		Note     string `json:"note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	to := model.ReturnApproved
+	switch body.Decision {
+	case "approve":
+	case "reject":
+		to = model.ReturnClosed
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Decision must be approve or reject"})
+	}
+
+	adminID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ret, ok, err := h.findReturn(c, anyReturn)
+	if !ok {
+		return err
+	}
+
+	fields := repository.Fields{
+		"escalation.resolution":  body.Note,
+		"escalation.resolved_by": adminID,
+		"escalation.resolved_at": time.Now(),
+	}
+	err = h.changeReturnStatus(c.Context(), ret, to, statusActor{ID: adminID, Role: actorAdmin}, body.Note, fields)
+	if err != nil {
+		return writeTransitionError(c, err, "Failed to resolve return")
+	}
+	return c.JSON(fiber.Map{"message": "Return resolved successfully"})
+}
+
+// receiveReturn menandai barang retur diterima lalu me-refund item tersebut di order.
+// Retur baru berpindah ke Refunded setelah payment gateway menerima refund-nya; jika gagal,
+// retur tetap Received dan bisa diproses ulang, yang mengirim ulang refund yang sudah tercatat.
+func (h *Handler) receiveReturn(ctx context.Context, ret model.ReturnRequest, actor statusActor, restock bool) error {
+	if ret.Status != model.ReturnReceived {
+		if err := h.changeReturnStatus(ctx, ret, model.ReturnReceived, actor, "", nil); err != nil {
+			return err
+		}
+		ret.Status = model.ReturnReceived
+	}
+
+	order, err := h.repos.Orders.FindByID(ctx, ret.OrderID)
+	if err != nil {
+		return err
+	}
+	// Refund retur ini mungkin sudah tercatat oleh percobaan sebelumnya
+	prefix := "Return " + ret.ID.Hex() + ":"
+	refund, found := refundWithPrefix(order, prefix)
+	switch {
+	case !found:
+		req := refundRequest{
+			Items:   []refundItemRequest{{ProductID: ret.ProductID, VariantID: ret.VariantID, Quantity: ret.Quantity}},
+			Reason:  prefix + " " + ret.Reason,
+			Restock: &restock,
+		}
+		if _, err := h.refundOrderItems(ctx, order, req, actor); err != nil {
+			return err
+		}
+	case refund.Status != model.RefundSucceeded:
+		checkout, err := h.repos.Checkouts.FindByID(ctx, order.CheckoutID)
+		if err != nil {
+			return err
+		}
+		if err := h.sendRefund(ctx, order.ID, checkout.PaymentReference, refund); err != nil {
+			return err
+		}
+	}
+
+	return h.changeReturnStatus(ctx, ret, model.ReturnRefunded, systemActor, "", nil)
+}
+
+// refundWithPrefix mencari refund order yang alasannya diawali prefix
+func refundWithPrefix(order model.Order, prefix string) (model.Refund, bool) {
+	for _, refund := range order.Refunds {
+		if strings.HasPrefix(refund.Reason, prefix) {
+			return refund, true
+		}
+	}
+	return model.Refund{}, false
+}
+
+// PUT /seller/returns/:return_id/receive → Seller mengonfirmasi barang retur diterima; dana langsung di-refund
+func (h *Handler) ReceiveReturnHandler(c *fiber.Ctx) error {
+	var body struct {
+		Restock bool `json:"restock"` // Kembalikan barang ke stok jika masih layak jual
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ret, ok, err := h.findReturn(c, ownedBySeller(c))
+	if !ok {
+		return err
+	}
+
+	sellerID, _ := middleware.CurrentSellerID(c)
+	if err := h.receiveReturn(c.Context(), ret, statusActor{ID: sellerID, Role: actorSeller}, body.Restock); err != nil {
+		return writeTransitionError(c, err, "Failed to process return")
+	}
+	return c.JSON(fiber.Map{"message": "Return received and refunded successfully"})
+}
+
+// PUT /admin/returns/:return_id/receive → Admin mengonfirmasi penerimaan retur atas nama seller
+func (h *Handler) AdminReceiveReturnHandler(c *fiber.Ctx) error {
+	var body struct {
+		Restock bool `json:"restock"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	adminID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ret, ok, err := h.findReturn(c, anyReturn)
+	if !ok {
+		return err
+	}
+
+	if err := h.receiveReturn(c.Context(), ret, statusActor{ID: adminID, Role: actorAdmin}, body.Restock); err != nil {
+		return writeTransitionError(c, err, "Failed to process return")
+	}
+	return c.JSON(fiber.Map{"message": "Return received and refunded successfully"})
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"be_ecommerce/services"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/textproto"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// failingReturns menolak semua retur baru
+type failingReturns struct {
+	repository.ReturnRepository
+}
+
+func (failingReturns) Create(ctx context.Context, ret *model.ReturnRequest) error {
+	return errors.New("database unavailable")
+}
+
+// deliveredOrder membuat order yang sudah dibayar lalu dikirim dan diterima pembeli, beserta route retur
+func (e *testEnv) deliveredOrder(t *testing.T, product primitive.ObjectID, quantity int) model.Order {
+	t.Helper()
+	e.app.Post("/returns", e.h.CreateReturnHandler)
+	e.app.Put("/seller/returns/:return_id", e.h.ReviewReturnHandler)
+	e.app.Put("/returns/:return_id/shipment", e.h.ShipReturnHandler)
+	e.app.Put("/seller/returns/:return_id/receive", e.h.ReceiveReturnHandler)
+
+	order := e.paidOrder(t, 50000*quantity+testShippingRate, orderItem(product, quantity))
+	seller := statusActor{ID: e.sellerID, Role: actorSeller}
+	for _, to := range []string{model.OrderStatusProcessing, model.OrderStatusShipped, model.OrderStatusDelivered} {
+		if err := e.h.changeOrderStatus(context.Background(), e.order(t, order.ID), to, seller, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return e.order(t, order.ID)
+}
+
+// requestReturn mengirim POST /returns dengan satu foto bukti
+func (e *testEnv) requestReturn(t *testing.T, order model.Order, product primitive.ObjectID, quantity string) (int, map[string]interface{}) {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("order_id", order.ID.Hex())
+	w.WriteField("product_id", product.Hex())
+	w.WriteField("quantity", quantity)
+	w.WriteField("reason", "Broken on arrival")
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="photos"; filename="proof.jpg"`)
+	header.Set("Content-Type", "image/jpeg")
+	part, err := w.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("jpeg"))
+	w.Close()
+
+	req := httptest.NewRequest(fiber.MethodPost, "/returns", &body)
+	req.Header.Set(fiber.HeaderContentType, w.FormDataContentType())
+	resp, err := e.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+// shippedReturn mengajukan retur lalu menyetujui dan mengirimkannya kembali
+func (e *testEnv) shippedReturn(t *testing.T, order model.Order, product primitive.ObjectID) primitive.ObjectID {
+	t.Helper()
+	status, body := e.requestReturn(t, order, product, "1")
+	if status != fiber.StatusCreated {
+		t.Fatalf("return status = %d, body = %v", status, body)
+	}
+	id := body["data"].(map[string]interface{})["id"].(string)
+
+	if status, body := e.do(t, fiber.MethodPut, "/seller/returns/"+id, fiber.Map{"decision": "approve"}); status != fiber.StatusOK {
+		t.Fatalf("approve status = %d, body = %v", status, body)
+	}
+	shipment := fiber.Map{"courier": "JNE", "tracking_number": "JNE123"}
+	if status, body := e.do(t, fiber.MethodPut, "/returns/"+id+"/shipment", shipment); status != fiber.StatusOK {
+		t.Fatalf("shipment status = %d, body = %v", status, body)
+	}
+	returnID, _ := primitive.ObjectIDFromHex(id)
+	return returnID
+}
+
+func (e *testEnv) returnStatus(t *testing.T, id primitive.ObjectID) string {
+	t.Helper()
+	ret, err := e.repos.Returns.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ret.Status
+}
+
+func TestReturnWorkflowRefundsAndRestocks(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.deliveredOrder(t, a, 2)
+
+	if status, _ := env.requestReturn(t, order, a, "3"); status != fiber.StatusConflict {
+		t.Errorf("return of more than ordered: status = %d, want 409", status)
+	}
+	id := env.shippedReturn(t, order, a)
+
+	status, body := env.do(t, fiber.MethodPut, "/seller/returns/"+id.Hex()+"/receive", fiber.Map{"restock": true})
+	if status != fiber.StatusOK {
+		t.Fatalf("receive status = %d, body = %v", status, body)
+	}
+	if got := env.returnStatus(t, id); got != model.ReturnRefunded {
+		t.Errorf("return status = %s, want Refunded", got)
+	}
+	order = env.order(t, order.ID)
+	if order.RefundedAmount != 50000 || len(order.Refunds) != 1 || order.Refunds[0].Status != model.RefundSucceeded {
+		t.Errorf("refunded = %d, refunds = %+v, want one succeeded refund of 50000", order.RefundedAmount, order.Refunds)
+	}
+	if got := env.stock(t, a); got != 4 {
+		t.Errorf("stock = %d, want 4", got)
+	}
+}
+
+func TestReturnStaysReceivedUntilRefundSucceeds(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.deliveredOrder(t, a, 1)
+	id := env.shippedReturn(t, order, a)
+	path := "/seller/returns/" + id.Hex() + "/receive"
+
+	env.h.payments = failingRefunds{env.mock}
+	if status, _ := env.do(t, fiber.MethodPut, path, fiber.Map{}); status != fiber.StatusBadGateway {
+		t.Fatalf("receive with failing gateway: status = %d, want 502", status)
+	}
+	if got := env.returnStatus(t, id); got != model.ReturnReceived {
+		t.Fatalf("return status = %s, want Received", got)
+	}
+
+	// Memproses ulang mengirim refund yang sama, bukan membuat refund baru
+	env.h.payments = env.mock
+	if status, body := env.do(t, fiber.MethodPut, path, fiber.Map{}); status != fiber.StatusOK {
+		t.Fatalf("retry status = %d, body = %v", status, body)
+	}
+	if got := env.returnStatus(t, id); got != model.ReturnRefunded {
+		t.Errorf("return status = %s, want Refunded", got)
+	}
+	order = env.order(t, order.ID)
+	if len(order.Refunds) != 1 || order.Refunds[0].Status != model.RefundSucceeded {
+		t.Errorf("refunds = %+v, want one succeeded refund", order.Refunds)
+	}
+}
+
+func TestFailedReturnRequestRemovesUploadedPhotos(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	a := env.product(t, env.sellerID, 50000, 5)
+	order := env.deliveredOrder(t, a, 1)
+
+	env.h.repos.Returns = failingReturns{env.repos.Returns}
+	if status, _ := env.requestReturn(t, order, a, "1"); status != fiber.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", status)
+	}
+
+	photos, _ := filepath.Glob(filepath.Join(env.blobDir, "public", returnPhotoPrefix, "*"))
+	if len(photos) != 0 {
+		t.Errorf("photos left in storage: %v", photos)
+	}
+}
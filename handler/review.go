@@ -1,18 +1,31 @@
 package handler
 
 import (
-	"be_ecommerce/config"
+	"be_ecommerce/middleware"
 	"be_ecommerce/model"
+	"be_ecommerce/repository"
 	"context"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// refreshProductRating menyimpan ulang rata-rata rating dan jumlah review di produk
+// agar katalog bisa difilter dan diurutkan berdasarkan rating. Kegagalan hanya dicatat.
+func (h *Handler) refreshProductRating(ctx context.Context, productID primitive.ObjectID) {
+	avgRating, reviewCount, err := h.repos.Reviews.RatingSummary(ctx, productID)
+	if err == nil {
+		err = h.repos.Products.Update(ctx, productID, nil, repository.Fields{"rating": avgRating, "reviews": reviewCount})
+	}
+	if err != nil && err != repository.ErrNotFound {
+		log.Println("Error refreshing rating of product", productID.Hex()+":", err)
+	}
+}
+
 // AddReview handles adding a new review
-func AddReview(c *fiber.Ctx) error {
+func (h *Handler) AddReview(c *fiber.Ctx) error {
 	var review model.Review
 	if err := c.BodyParser(&review); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -27,12 +40,14 @@ func AddReview(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validasi UserID
-	if review.UserID.IsZero() {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "User ID is required",
+	// UserID diambil dari token, bukan dari body request
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
 		})
 	}
+	review.UserID = userID
 
 	// Validasi nilai rating (harus antara 1.0 dan 5.0)
 	if review.Rating < 1.0 || review.Rating > 5.0 {
@@ -46,13 +61,13 @@ func AddReview(c *fiber.Ctx) error {
 	review.CreatedAt = time.Now().Unix()
 
 	// Simpan review ke database
-	collection := config.MongoClient.Database("ecommerce").Collection("reviews")
-	_, err := collection.InsertOne(context.Background(), review)
+	err = h.repos.Reviews.Create(c.Context(), &review)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to save review",
 		})
 	}
+	h.refreshProductRating(c.Context(), review.ProductID)
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message":   "Review added successfully",
@@ -61,7 +76,7 @@ func AddReview(c *fiber.Ctx) error {
 }
 
 // GetReviews handles fetching all reviews for a product
-func GetReviews(c *fiber.Ctx) error {
+func (h *Handler) GetReviews(c *fiber.Ctx) error {
 	productID := c.Params("product_id")
 
 	// Konversi ProductID ke ObjectID
@@ -73,21 +88,12 @@ func GetReviews(c *fiber.Ctx) error {
 	}
 
 	// Ambil review dari database
-	collection := config.MongoClient.Database("ecommerce").Collection("reviews")
-	cursor, err := collection.Find(context.Background(), bson.M{"product_id": objectID})
+	reviews, err := h.repos.Reviews.ListByProduct(c.Context(), objectID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to fetch reviews",
 		})
 	}
-	defer cursor.Close(context.Background())
-
-	var reviews []model.Review
-	if err := cursor.All(context.Background(), &reviews); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to parse reviews",
-		})
-	}
 
 	return c.JSON(fiber.Map{
 		"message": "Reviews fetched successfully",
@@ -95,7 +101,7 @@ func GetReviews(c *fiber.Ctx) error {
 	})
 }
 
-func GetProductRating(c *fiber.Ctx) error {
+func (h *Handler) GetProductRating(c *fiber.Ctx) error {
 	productID := c.Params("product_id")
 
 	// Konversi ProductID ke ObjectID
@@ -106,49 +112,24 @@ func GetProductRating(c *fiber.Ctx) error {
 		})
 	}
 
-	// Agregasi untuk menghitung rata-rata rating dan jumlah ulasan
-	collection := config.MongoClient.Database("ecommerce").Collection("reviews")
-	cursor, err := collection.Aggregate(context.Background(), bson.A{
-		bson.M{"$match": bson.M{"product_id": objectID}}, // Filter ulasan untuk produk tertentu
-		bson.M{"$group": bson.M{
-			"_id":        "$product_id",
-			"avg_rating": bson.M{"$avg": "$rating"},
-			"review_count": bson.M{"$sum": 1},
-		}},
-	})
+	// Hitung rata-rata rating dan jumlah ulasan; keduanya 0 jika belum ada ulasan
+	avgRating, reviewCount, err := h.repos.Reviews.RatingSummary(c.Context(), objectID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to calculate product rating",
 		})
 	}
-	defer cursor.Close(context.Background())
-
-	var results []bson.M
-	if err := cursor.All(context.Background(), &results); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to parse rating data",
-		})
-	}
-
-	// Jika tidak ada ulasan
-	if len(results) == 0 {
-		return c.JSON(fiber.Map{
-			"product_id":   productID,
-			"avg_rating":   0,
-			"review_count": 0,
-		})
-	}
 
 	// Kembalikan rata-rata rating dan jumlah ulasan
 	return c.JSON(fiber.Map{
 		"product_id":   productID,
-		"avg_rating":   results[0]["avg_rating"],
-		"review_count": results[0]["review_count"],
+		"avg_rating":   avgRating,
+		"review_count": reviewCount,
 	})
 }
 
 // UpdateReview handles updating an existing review
-func UpdateReview(c *fiber.Ctx) error {
+func (h *Handler) UpdateReview(c *fiber.Ctx) error {
 	reviewID := c.Params("review_id")
 
 	// Konversi ReviewID ke ObjectID
@@ -177,19 +158,28 @@ func UpdateReview(c *fiber.Ctx) error {
 		})
 	}
 
-	// Update review di database
-	collection := config.MongoClient.Database("ecommerce").Collection("reviews")
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, bson.M{
-		"$set": bson.M{
-			"rating":  updateData.Rating,
-			"comment": updateData.Comment,
-		},
-	})
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
+	// Update review di database, hanya jika review milik user yang login
+	err = h.repos.Reviews.Update(c.Context(), objectID, userID, updateData.Rating, updateData.Comment)
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Review not found",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update review",
 		})
 	}
+	if review, err := h.repos.Reviews.FindByID(c.Context(), objectID); err == nil {
+		h.refreshProductRating(c.Context(), review.ProductID)
+	}
 
 	return c.JSON(fiber.Map{
 		"message": "Review updated successfully",
@@ -197,7 +187,7 @@ func UpdateReview(c *fiber.Ctx) error {
 }
 
 // DeleteReview handles deleting a review
-func DeleteReview(c *fiber.Ctx) error {
+func (h *Handler) DeleteReview(c *fiber.Ctx) error {
 	reviewID := c.Params("review_id")
 
 	// Konversi ReviewID ke ObjectID
@@ -208,15 +198,47 @@ func DeleteReview(c *fiber.Ctx) error {
 		})
 	}
 
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
+	// Admin boleh menghapus review siapa pun, selain itu hanya pemilik review
+	owner := &userID
+	if middleware.HasRole(c, "admin") {
+		owner = nil
+	}
+
+	// Simpan product_id sebelum review dihapus untuk memperbarui rating produk
+	review, err := h.repos.Reviews.FindByID(c.Context(), objectID)
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Review not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to delete review",
+		})
+	}
+
 	// Hapus review dari database
-	collection := config.MongoClient.Database("ecommerce").Collection("reviews")
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": objectID})
+	err = h.repos.Reviews.Delete(c.Context(), objectID, owner)
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Review not found",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to delete review",
 		})
 	}
 
+	h.refreshProductRating(c.Context(), review.ProductID)
+
 	return c.JSON(fiber.Map{
 		"message": "Review deleted successfully",
 	})
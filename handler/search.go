@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"be_ecommerce/scheduler"
+	"be_ecommerce/search"
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultSuggestionCount = 8
+	maxSuggestionCount     = 20
+)
+
+// Bobot field produk di index pencarian: kecocokan di nama paling relevan
+const (
+	searchWeightName        = 3
+	searchWeightCategory    = 1.5
+	searchWeightStore       = 1.5
+	searchWeightDescription = 1
+)
+
+// searchNames berisi nama kategori, sub-kategori dan toko untuk dokumen index produk
+type searchNames struct {
+	categories map[primitive.ObjectID]string
+	stores     map[primitive.ObjectID]string
+}
+
+func (h *Handler) loadSearchNames(ctx context.Context) (searchNames, error) {
+	names := searchNames{categories: map[primitive.ObjectID]string{}, stores: map[primitive.ObjectID]string{}}
+
+	categories, err := h.repos.Categories.List(ctx)
+	if err != nil {
+		return names, err
+	}
+	for _, category := range categories {
+		names.categories[category.ID] = category.Name
+		for _, sub := range category.SubCategories {
+			names.categories[sub.ID] = sub.Name
+		}
+	}
+
+	stores, err := h.repos.Stores.List(ctx)
+	if err != nil {
+		return names, err
+	}
+	for _, store := range stores {
+		names.stores[store.ID] = store.Name
+	}
+	return names, nil
+}
+
+func (n searchNames) document(product model.Product) search.Document {
+	return search.Document{
+		ID: product.ID.Hex(),
+		Fields: []search.Field{
+			{Text: product.Name, Weight: searchWeightName},
+			{Text: n.categories[product.CategoryID] + " " + n.categories[product.SubCategoryID], Weight: searchWeightCategory},
+			{Text: n.stores[product.SellerID], Weight: searchWeightStore},
+			{Text: product.Description, Weight: searchWeightDescription},
+		},
+	}
+}
+
+// RebuildSearchIndex membangun ulang index pencarian dari semua produk di database
+func (h *Handler) RebuildSearchIndex(ctx context.Context) error {
+	names, err := h.loadSearchNames(ctx)
+	if err != nil {
+		return err
+	}
+	products, err := h.repos.Products.Find(ctx, repository.ProductFilter{})
+	if err != nil {
+		return err
+	}
+
+	docs := make([]search.Document, 0, len(products))
+	for _, product := range products {
+		docs = append(docs, names.document(product))
+	}
+	h.searchIndex.Replace(docs)
+	return nil
+}
+
+// SearchReindexJob membangun ulang index setiap SEARCH_REINDEX_INTERVAL (default 10m, 0 mematikan).
+// Index ada di memori setiap instance, jadi job ini berjalan di semua instance.
+func (h *Handler) SearchReindexJob() scheduler.Job {
+	return scheduler.Job{
+		Name:        "search-reindex",
+		Interval:    envDuration("SEARCH_REINDEX_INTERVAL", 10*time.Minute),
+		PerInstance: true,
+		Run:         h.RebuildSearchIndex,
+	}
+}
+
+// reindexProduct memperbarui satu produk di index setelah dibuat atau diubah. Kegagalan hanya
+// dicatat; produk akan masuk index pada rebuild berikutnya.
+func (h *Handler) reindexProduct(ctx context.Context, productID primitive.ObjectID) {
+	product, err := h.repos.Products.FindByID(ctx, productID)
+	if err == repository.ErrNotFound {
+		h.searchIndex.Delete(productID.Hex())
+		return
+	}
+	var names searchNames
+	if err == nil {
+		names, err = h.loadSearchNames(ctx)
+	}
+	if err != nil {
+		log.Println("Error indexing product", productID.Hex()+":", err)
+		return
+	}
+	h.searchIndex.Put(names.document(product))
+}
+
+// unindexProduct menghapus produk dari index setelah produk dihapus
+func (h *Handler) unindexProduct(productID primitive.ObjectID) {
+	h.searchIndex.Delete(productID.Hex())
+}
+
+// SearchHandler mencari produk berdasarkan nama, deskripsi, kategori dan nama toko,
+// diurutkan dari yang paling relevan. GET /search?q=...&limit=...&offset=...
+func (h *Handler) SearchHandler(c *fiber.Ctx) error {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Query q is required"})
+	}
+	if len(q) > maxProductQueryLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Search query is too long"})
+	}
+	limit := c.QueryInt("limit", defaultProductPageSize)
+	if limit < 1 || limit > maxProductPageSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Limit must be between 1 and " + strconv.Itoa(maxProductPageSize)})
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Offset must not be negative"})
+	}
+
+	result := h.searchIndex.Search(q, limit, offset)
+	ids := make([]primitive.ObjectID, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		if id, err := primitive.ObjectIDFromHex(hit.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	// Produk diambil ulang dari database agar data terbaru, kategori dan filter seller yang
+	// di-suspend tetap berlaku; urutan relevansi dari index dipertahankan
+	products := []bson.M{}
+	if len(ids) > 0 {
+		page, err := h.repos.Products.Search(c.Context(), repository.ProductQuery{IDs: ids, Limit: len(ids)})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to search products",
+				"error":   err.Error(),
+			})
+		}
+		byID := make(map[primitive.ObjectID]bson.M, len(page.Products))
+		for _, product := range page.Products {
+			if id, ok := product["_id"].(primitive.ObjectID); ok {
+				byID[id] = product
+			}
+		}
+		for i, id := range ids {
+			if product, ok := byID[id]; ok {
+				product["score"] = result.Hits[i].Score
+				products = append(products, product)
+			}
+		}
+	}
+
+	response := fiber.Map{
+		"products": products,
+		"pagination": fiber.Map{
+			"total":  result.Total,
+			"limit":  limit,
+			"offset": offset,
+		},
+	}
+	if result.DidYouMean != "" {
+		response["did_you_mean"] = result.DidYouMean
+	}
+	return c.JSON(response)
+}
+
+// SuggestHandler melengkapi kata terakhir yang sedang diketik untuk autocomplete.
+// GET /search/suggest?q=...&limit=...
+func (h *Handler) SuggestHandler(c *fiber.Ctx) error {
+	q := c.Query("q")
+	if len(q) > maxProductQueryLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Search query is too long"})
+	}
+	limit := c.QueryInt("limit", defaultSuggestionCount)
+	if limit < 1 || limit > maxSuggestionCount {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Limit must be between 1 and " + strconv.Itoa(maxSuggestionCount)})
+	}
+	return c.JSON(fiber.Map{"suggestions": h.searchIndex.Suggest(q, limit)})
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Status order yang mengembalikan stok item yang belum dikirim ke produk
+var stockReleasedStatuses = map[string]bool{
+	model.OrderStatusCancelled: true,
+	model.OrderStatusRefunded:  true,
+}
+
+// Status order yang barangnya belum dikirim, sehingga stok barang yang di-refund dikembalikan
+var unshippedStatuses = map[string]bool{
+	model.OrderStatusAwaitingPayment: true,
+	model.OrderStatusPaid:            true,
+	model.OrderStatusProcessing:      true,
+	model.OrderStatusLegacyPending:   true,
+	model.OrderStatusLegacyConfirmed: true,
+}
+
+// stockItems mengubah item order menjadi jumlah stok per produk dan varian
+func stockItems(items []model.OrderItem) []repository.StockItem {
+	result := make([]repository.StockItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, repository.StockItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+	return result
+}
+
+// placeCheckout mengurangi stok lalu menyimpan checkout beserta semua order anaknya dalam satu transaksi.
+// Jika cartUserID diisi, keranjang user tersebut ikut dikosongkan di transaksi yang sama.
+func (h *Handler) placeCheckout(ctx context.Context, checkout *model.Checkout, orders []model.Order, cartUserID string) error {
+	var items []repository.StockItem
+	for _, order := range orders {
+		items = append(items, stockItems(order.Items)...)
+	}
+
+	return h.repos.Tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.repos.Products.DecrementStock(ctx, items); err != nil {
+			return err
+		}
+		if err := h.repos.Checkouts.Create(ctx, checkout); err != nil {
+			return err
+		}
+		for i := range orders {
+			if err := h.repos.Orders.Create(ctx, &orders[i]); err != nil {
+				return err
+			}
+		}
+		if cartUserID != "" {
+			return h.repos.Carts.Clear(ctx, cartUserID)
+		}
+		return nil
+	})
+}
+
+// changeOrderStatus memindahkan order ke status to jika transisinya diizinkan untuk actor,
+// dan hanya jika status-nya belum berubah sejak dibaca. Perubahan dicatat di status_history
+// dan fields ikut disimpan. Saat order yang belum dikirim dibatalkan atau di-refund, stok item
+// dikembalikan di transaksi yang sama. Barang yang sudah dikirim masih dipegang pembeli, sehingga
+// stoknya hanya kembali lewat refund item (atau retur) yang ditandai Restocked.
+func (h *Handler) changeOrderStatus(ctx context.Context, order model.Order, to string, actor statusActor, note string, fields repository.Fields) error {
+	if err := checkTransition(order.Status, to, actor.Role); err != nil {
+		return err
+	}
+	return h.repos.Tx.WithTransaction(ctx, func(ctx context.Context) error {
+		return h.transitionOrder(ctx, order, to, actor, note, fields)
+	})
+}
+
+// transitionOrder adalah changeOrderStatus tanpa transaksi sendiri, untuk dijalankan di dalam
+// transaksi bersama perubahan lain (misalnya refund yang menyertai pembatalan)
+func (h *Handler) transitionOrder(ctx context.Context, order model.Order, to string, actor statusActor, note string, fields repository.Fields) error {
+	if err := checkTransition(order.Status, to, actor.Role); err != nil {
+		return err
+	}
+	restock := stockReleasedStatuses[to] && unshippedStatuses[order.Status]
+
+	change := model.StatusChange{
+		From:      order.Status,
+		To:        to,
+		At:        time.Now(),
+		ActorID:   actor.ID,
+		ActorRole: actor.Role,
+		Note:      note,
+	}
+	if err := h.repos.Orders.Transition(ctx, order.ID, change, fields); err != nil {
+		return err
+	}
+	if restock {
+		return h.repos.Products.RestoreStock(ctx, remainingStockItems(order))
+	}
+	return nil
+}
+
+// writeStockError mengirim 409 berisi daftar item yang stoknya kurang,
+// atau 500 dengan body fallback untuk error lainnya
+func writeStockError(c *fiber.Ctx, err error, fallback fiber.Map) error {
+	var stockErr *repository.OutOfStockError
+	if errors.As(err, &stockErr) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Some items are out of stock",
+			"code":    "out_of_stock",
+			"items":   stockErr.Items,
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fallback)
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"be_ecommerce/imaging"
+	"be_ecommerce/storage"
+	"context"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// readUpload membaca seluruh isi file unggahan; file yang lebih besar dari limit ditolak
+func readUpload(header *multipart.FileHeader, limit int64) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, imaging.ErrTooLarge
+	}
+	return data, nil
+}
+
+// putPublic menyimpan file di bucket publik dan mengembalikan URL permanennya
+func (h *Handler) putPublic(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	if err := h.blobs.Public.Put(ctx, key, data, contentType); err != nil {
+		return "", err
+	}
+	return h.blobs.Public.URL(key, 0)
+}
+
+// kycPhotoURL mengembalikan signed URL foto KYC yang berlaku sebentar. Path lama "uploads/seller_photos/..."
+// dari sebelum bucket privat dipetakan ke key yang sama setelah cmd/migrate-kyc-photos dijalankan.
+func (h *Handler) kycPhotoURL(photoPath string) string {
+	if photoPath == "" {
+		return ""
+	}
+	key := strings.TrimPrefix(photoPath, "uploads/")
+	location, err := h.blobs.Private.URL(key, storage.SignedURLTTL())
+	if err != nil {
+		log.Println("Error signing KYC photo URL", photoPath+":", err)
+		return ""
+	}
+	return location
+}
+
+// GET /files/private/* → File bucket privat local lewat signed URL (expires & signature).
+// Dengan backend S3 signed URL langsung mengarah ke S3, sehingga endpoint ini tidak dipakai.
+func (h *Handler) ServePrivateFile(c *fiber.Ctx) error {
+	local, ok := h.blobs.Private.(*storage.LocalStore)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "File not found"})
+	}
+	key, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid file path"})
+	}
+	if err := local.Verify(key, c.Query("expires"), c.Query("signature")); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Link is invalid or has expired"})
+	}
+
+	file, err := local.Get(c.Context(), key)
+	if err == storage.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "File not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to read file"})
+	}
+
+	// Foto KYC tidak boleh disimpan cache bersama (proxy/CDN)
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	c.Type(strings.TrimPrefix(filepath.Ext(key), "."))
+	return c.SendStream(file)
+}
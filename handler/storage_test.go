@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"be_ecommerce/services"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestServePrivateFileRequiresValidSignature(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	env.app.Get("/files/private/*", env.h.ServePrivateFile)
+	if err := env.blobs.Private.Put(context.Background(), "seller_photos/ktp.jpg", []byte("jpeg"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(location string) (int, string, string) {
+		t.Helper()
+		resp, err := env.app.Test(httptest.NewRequest(fiber.MethodGet, location, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), resp.Header.Get(fiber.HeaderCacheControl)
+	}
+
+	// Path lama uploads/seller_photos/... dipetakan ke key bucket privat yang sama
+	location := env.h.kycPhotoURL("uploads/seller_photos/ktp.jpg")
+	if !strings.HasPrefix(location, "/files/private/seller_photos/ktp.jpg?") {
+		t.Fatalf("URL = %q, want a signed /files/private URL", location)
+	}
+	status, body, cache := get(location)
+	if status != fiber.StatusOK || body != "jpeg" {
+		t.Fatalf("status = %d, body = %q, want 200 jpeg", status, body)
+	}
+	if cache != "private, no-store" {
+		t.Errorf("Cache-Control = %q, want private, no-store", cache)
+	}
+
+	query := location[strings.Index(location, "?"):]
+	for name, tampered := range map[string]string{
+		"other file":        "/files/private/seller_photos/other.jpg" + query,
+		"path traversal":    "/files/private/seller_photos/..%2F..%2Fpublic%2Fx.jpg" + query,
+		"without signature": "/files/private/seller_photos/ktp.jpg",
+		"forged signature":  "/files/private/seller_photos/ktp.jpg" + strings.Replace(query, "signature=", "signature=0", 1),
+	} {
+		if status, body, _ := get(tampered); status != fiber.StatusForbidden || body == "jpeg" {
+			t.Errorf("%s: status = %d, body = %q, want 403", name, status, body)
+		}
+	}
+}
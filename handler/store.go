@@ -1,17 +1,71 @@
 package handler
 
 import (
-	"be_ecommerce/config"
 	"be_ecommerce/model"
+	"be_ecommerce/repository"
 	"context"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var errStoreInactive = errors.New("store is not active")
+
+// saveStoreApplication membuat atau memperbarui toko milik user dengan status pending.
+// Toko yang sudah ada dipakai ulang agar produk dan order lama tetap merujuk ke toko yang sama.
+func (h *Handler) saveStoreApplication(ctx context.Context, user model.User, storeName, fullAddress string) (primitive.ObjectID, error) {
+	store := model.Store{
+		OwnerID:     user.ID,
+		Name:        storeName,
+		FullAddress: fullAddress,
+		Status:      "pending",
+	}
+	if user.SellerID != nil {
+		store.ID = *user.SellerID
+	}
+	if err := h.repos.Stores.Save(ctx, &store); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	if user.SellerID == nil {
+		if err := h.repos.Users.Update(ctx, user.ID, nil, repository.Fields{"seller_id": store.ID}); err != nil {
+			return primitive.NilObjectID, err
+		}
+	}
+	return store.ID, nil
+}
+
+// setStoreStatus menyamakan status toko dengan keputusan admin atas pengajuan seller
+func (h *Handler) setStoreStatus(ctx context.Context, ownerID primitive.ObjectID, status string) error {
+	return h.repos.Stores.SetStatusByOwner(ctx, ownerID, status)
+}
+
+// findActiveStore mengambil toko beserta pemiliknya; error jika toko belum disetujui atau pemiliknya di-suspend.
+// Jika byOwner true, id juga boleh berupa ID pemilik toko (dipakai tautan lama).
+func (h *Handler) findActiveStore(ctx context.Context, id primitive.ObjectID, byOwner bool) (model.Store, model.User, error) {
+	find := h.repos.Stores.FindByID
+	if byOwner {
+		find = h.repos.Stores.FindByIDOrOwner
+	}
+	store, err := find(ctx, id)
+	if err != nil {
+		return store, model.User{}, err
+	}
+
+	owner, err := h.repos.Users.FindByID(ctx, store.OwnerID)
+	if err != nil {
+		return store, owner, err
+	}
+
+	if !store.IsActive() || owner.IsSuspended() {
+		return store, owner, errStoreInactive
+	}
+	return store, owner, nil
+}
+
 // GetStoreDetails returns store information and its products
-func GetStoreDetails(c *fiber.Ctx) error {
+func (h *Handler) GetStoreDetails(c *fiber.Ctx) error {
 	storeID := c.Params("id")
 
 	// Konversi storeID ke ObjectID
@@ -22,50 +76,37 @@ func GetStoreDetails(c *fiber.Ctx) error {
 		})
 	}
 
-	// Ambil data toko dari database
-	userCollection := config.MongoClient.Database("ecommerce").Collection("users")
-	var store model.User
-	err = userCollection.FindOne(context.Background(), bson.M{"_id": objectID, "roles": "seller"}).Decode(&store)
+	// Ambil data toko; ID pemilik masih diterima agar tautan lama tetap berfungsi
+	store, owner, err := h.findActiveStore(c.Context(), objectID, true)
 	if err != nil {
+		if err == errStoreInactive {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Store is not active",
+			})
+		}
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "Store not found",
 		})
 	}
 
-	// Periksa apakah toko aktif
-	if store.StoreStatus == nil || *store.StoreStatus != "approved" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Store is not active",
-		})
-	}
-
 	// Ambil produk yang terkait dengan toko ini
-	productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-	cursor, err := productCollection.Find(context.Background(), bson.M{"seller_id": objectID})
+	products, err := h.repos.Products.Find(c.Context(), repository.ProductFilter{SellerID: &store.ID})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to fetch products",
 		})
 	}
-	defer cursor.Close(context.Background())
-
-	var products []model.Product
-	if err := cursor.All(context.Background(), &products); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to parse products",
-		})
-	}
 
 	// Kembalikan detail toko dan produk
 	return c.JSON(fiber.Map{
 		"store": fiber.Map{
 			"id":           store.ID,
-			"store_name":   store.StoreInfo.StoreName,
-			"full_address": store.StoreInfo.FullAddress,
-			"email":        store.Email,
-			"status":       *store.StoreStatus,
+			"owner_id":     store.OwnerID,
+			"store_name":   store.Name,
+			"full_address": store.FullAddress,
+			"email":        owner.Email,
+			"status":       store.Status,
 		},
 		"products": products,
 	})
 }
-
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"be_ecommerce/middleware"
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"be_ecommerce/utils"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const refreshTokenExpiry = 7 * 24 * time.Hour // Refresh token berlaku 7 hari
+
+// issueTokens membuat access token dan refresh token baru untuk user.
+// familyID diisi saat rotasi agar token baru tetap satu keluarga dengan token lama.
+// mfa menandai sesi yang sudah lolos 2FA dan ikut disimpan di refresh token.
+func (h *Handler) issueTokens(ctx context.Context, user model.User, familyID primitive.ObjectID, mfa bool) (string, string, primitive.ObjectID, error) {
+	var sellerID string
+	if user.SellerID != nil {
+		sellerID = user.SellerID.Hex()
+	}
+
+	accessToken, err := utils.GenerateJWT(user.ID.Hex(), user.Roles, sellerID, user.TokenVersion, mfa)
+	if err != nil {
+		return "", "", primitive.NilObjectID, err
+	}
+
+	refreshToken := utils.GenerateRandomToken(64)
+	if refreshToken == "" {
+		return "", "", primitive.NilObjectID, errors.New("failed to generate refresh token")
+	}
+
+	if familyID.IsZero() {
+		familyID = primitive.NewObjectID()
+	}
+
+	record := model.RefreshToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyID,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(refreshTokenExpiry),
+		MFA:       mfa,
+	}
+	if err := h.repos.RefreshTokens.Create(ctx, &record); err != nil {
+		return "", "", primitive.NilObjectID, err
+	}
+
+	return accessToken, refreshToken, record.ID, nil
+}
+
+// revokeUserTokens menaikkan token_version user sehingga semua access token lama ditolak.
+// Jika revokeSessions true, semua refresh token user juga dicabut (user harus login ulang).
+func (h *Handler) revokeUserTokens(ctx context.Context, userID primitive.ObjectID, revokeSessions bool) error {
+	if err := h.repos.Users.Increment(ctx, userID, "token_version", 1); err != nil {
+		return err
+	}
+
+	if revokeSessions {
+		return h.repos.RefreshTokens.RevokeUser(ctx, userID)
+	}
+	return nil
+}
+
+// RefreshToken menukar refresh token dengan access token baru dan merotasi refresh token
+func (h *Handler) RefreshToken(c *fiber.Ctx) error {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Refresh token is required",
+		})
+	}
+
+	ctx := context.Background()
+
+	record, err := h.repos.RefreshTokens.FindByHash(ctx, utils.HashToken(body.RefreshToken))
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Invalid refresh token",
+			})
+		}
+		log.Println("Error fetching refresh token:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to refresh token",
+		})
+	}
+
+	// Token yang sudah dirotasi dipakai lagi: kemungkinan dicuri, cabut seluruh keluarganya
+	if record.RevokedAt != nil {
+		log.Println("Refresh token reuse detected for user:", record.UserID.Hex())
+		if err := h.repos.RefreshTokens.RevokeFamily(ctx, record.FamilyID); err != nil {
+			log.Println("Error revoking token family:", err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Refresh token has been revoked",
+		})
+	}
+
+	if time.Now().After(record.ExpiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Refresh token expired",
+		})
+	}
+
+	user, err := h.repos.Users.FindByID(ctx, record.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "User not found",
+		})
+	}
+
+	if user.IsSuspended() {
+		return c.Status(fiber.StatusForbidden).JSON(suspendedResponse(user.Suspension))
+	}
+
+	if len(user.Roles) == 0 {
+		user.Roles = []string{"customer"}
+	}
+
+	accessToken, refreshToken, newID, err := h.issueTokens(ctx, user, record.FamilyID, record.MFA)
+	if err != nil {
+		log.Println("Error issuing tokens:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not generate token",
+		})
+	}
+
+	// Tandai token lama sudah dipakai; filter revoked_at mencegah dua request memakai token yang sama
+	if err := h.repos.RefreshTokens.Rotate(ctx, record.ID, newID); err != nil {
+		h.repos.RefreshTokens.Revoke(ctx, newID)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Refresh token has been revoked",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":        "success",
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"roles":         user.Roles,
+	})
+}
+
+// Logout mencabut refresh token milik sesi saat ini
+func (h *Handler) Logout(c *fiber.Ctx) error {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Refresh token is required",
+		})
+	}
+
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
+	// Cabut seluruh keluarga token agar token hasil rotasi sebelumnya ikut tidak berlaku
+	record, err := h.repos.RefreshTokens.FindByHash(context.Background(), utils.HashToken(body.RefreshToken))
+	if err != nil || record.UserID != userID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Session not found",
+		})
+	}
+
+	if err := h.repos.RefreshTokens.RevokeFamily(context.Background(), record.FamilyID); err != nil {
+		log.Println("Error revoking refresh token:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to logout",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Logged out successfully",
+	})
+}
+
+// LogoutAll mencabut semua sesi dan access token milik user
+func (h *Handler) LogoutAll(c *fiber.Ctx) error {
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
+	if err := h.revokeUserTokens(context.Background(), userID, true); err != nil {
+		log.Println("Error revoking user tokens:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to logout from all sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Logged out from all sessions",
+	})
+}
@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"be_ecommerce/middleware"
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"be_ecommerce/utils"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const recoveryCodeCount = 10 // Jumlah kode cadangan yang dibuat saat 2FA diaktifkan
+
+// totpIssuer adalah nama yang tampil di aplikasi authenticator
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "E-Commerce"
+}
+
+// currentUser mengambil data lengkap user yang sedang login
+func (h *Handler) currentUser(c *fiber.Ctx) (model.User, error) {
+	userID, err := middleware.CurrentUserID(c)
+	if err != nil {
+		return model.User{}, err
+	}
+	return h.repos.Users.FindByID(c.Context(), userID)
+}
+
+// SetupTwoFactor membuat secret TOTP baru (belum aktif) dan mengembalikan URI untuk QR code
+func (h *Handler) SetupTwoFactor(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
+	if user.TwoFactorEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Two-factor authentication is already enabled",
+		})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		log.Println("Error generating TOTP secret:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to start two-factor setup",
+		})
+	}
+
+	err = h.repos.Users.Update(c.Context(), user.ID, nil, repository.Fields{"totp_pending_secret": secret})
+	if err != nil {
+		log.Println("Error saving TOTP secret:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to start two-factor setup",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":          "Scan the QR code with your authenticator app, then confirm with a code",
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(totpIssuer(), user.Email, secret),
+	})
+}
+
+// EnableTwoFactor mengaktifkan 2FA setelah user membuktikan aplikasi authenticator sudah terpasang
+func (h *Handler) EnableTwoFactor(c *fiber.Ctx) error {
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+		})
+	}
+
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
+	if user.TwoFactorEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Two-factor authentication is already enabled",
+		})
+	}
+	if user.TOTPPendingSecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Start two-factor setup first",
+		})
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPPendingSecret, body.Code, time.Now())
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid code",
+		})
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		log.Println("Error generating recovery codes:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to enable two-factor authentication",
+		})
+	}
+
+	err = h.repos.Users.Update(c.Context(), user.ID, nil, repository.Fields{
+		"two_factor_enabled":   true,
+		"totp_secret":          user.TOTPPendingSecret,
+		"totp_last_step":       step,
+		"recovery_code_hashes": hashes,
+	}, "totp_pending_secret")
+	if err != nil {
+		log.Println("Error enabling two-factor authentication:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to enable two-factor authentication",
+		})
+	}
+
+	// Sesi lama dibuat tanpa 2FA: cabut semuanya dan terbitkan sesi baru yang sudah terverifikasi
+	if err := h.revokeUserTokens(c.Context(), user.ID, true); err != nil {
+		log.Println("Error revoking tokens after enabling 2FA:", err)
+	}
+	user.TokenVersion++
+
+	token, refreshToken, _, err := h.issueTokens(c.Context(), user, primitive.NilObjectID, true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Could not generate token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled. Store your recovery codes somewhere safe, they are shown only once.",
+		"recovery_codes": codes,
+		"token":          token,
+		"refresh_token":  refreshToken,
+	})
+}
+
+// DisableTwoFactor mematikan 2FA; membutuhkan password dan kode 2FA yang valid
+func (h *Handler) DisableTwoFactor(c *fiber.Ctx) error {
+	var body struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+		})
+	}
+
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
+	if !user.TwoFactorEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Two-factor authentication is not enabled",
+		})
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)) != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid password",
+		})
+	}
+
+	if ok, err := h.checkSecondFactor(c, user, body.Code); !ok {
+		return err
+	}
+
+	err = h.repos.Users.Update(c.Context(), user.ID, nil, repository.Fields{"two_factor_enabled": false},
+		"totp_secret", "totp_pending_secret", "totp_last_step", "recovery_code_hashes")
+	if err != nil {
+		log.Println("Error disabling two-factor authentication:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to disable two-factor authentication",
+		})
+	}
+
+	// Sesi yang ditandai 2FA tidak lagi sah, user harus login ulang
+	if err := h.revokeUserTokens(c.Context(), user.ID, true); err != nil {
+		log.Println("Error revoking tokens after disabling 2FA:", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Two-factor authentication disabled, please log in again",
+	})
+}
+
+// RegenerateRecoveryCodes mengganti seluruh kode cadangan dengan yang baru
+func (h *Handler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+		})
+	}
+
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
+	if !user.TwoFactorEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Two-factor authentication is not enabled",
+		})
+	}
+
+	if ok, err := h.checkSecondFactor(c, user, body.Code); !ok {
+		return err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		log.Println("Error generating recovery codes:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to generate recovery codes",
+		})
+	}
+
+	err = h.repos.Users.Update(c.Context(), user.ID, nil, repository.Fields{"recovery_code_hashes": hashes})
+	if err != nil {
+		log.Println("Error saving recovery codes:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to generate recovery codes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "New recovery codes generated, the old ones no longer work",
+		"recovery_codes": codes,
+	})
+}
+
+// LoginTwoFactor menyelesaikan login dengan challenge token dari /login dan kode TOTP atau kode cadangan
+func (h *Handler) LoginTwoFactor(c *fiber.Ctx) error {
+	var body struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+		})
+	}
+
+	claims, err := utils.ValidateChallengeJWT(body.ChallengeToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid or expired challenge, please log in again",
+		})
+	}
+
+	userID, _ := claims["user_id"].(string)
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid or expired challenge, please log in again",
+		})
+	}
+
+	user, err := h.repos.Users.FindByID(c.Context(), objectID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid or expired challenge, please log in again",
+		})
+	}
+
+	// Challenge dari sebelum reset password/logout-all atau sebelum 2FA terkunci tidak berlaku lagi
+	version, _ := claims["ver"].(float64)
+	challengeVersion, _ := claims["cver"].(float64)
+	if int(version) != user.TokenVersion || int(challengeVersion) != user.ChallengeVersion || !user.TwoFactorEnabled {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid or expired challenge, please log in again",
+		})
+	}
+
+	if user.IsSuspended() {
+		return c.Status(fiber.StatusForbidden).JSON(suspendedResponse(user.Suspension))
+	}
+
+	if ok, err := h.checkSecondFactor(c, user, body.Code); !ok {
+		return err
+	}
+
+	return h.completeLogin(c, user, true)
+}
+
+// checkSecondFactor memverifikasi kode 2FA dengan pembatasan percobaan.
+// Jika tidak valid, respons error sudah ditulis dan ok bernilai false.
+func (h *Handler) checkSecondFactor(c *fiber.Ctx, user model.User, code string) (bool, error) {
+	key := "2fa-user:" + user.ID.Hex()
+	if wait := h.lockedFor(c.Context(), key); wait > 0 {
+		return false, tooManyAttempts(c, wait)
+	}
+
+	if !h.verifySecondFactor(c.Context(), user, code) {
+		if h.recordFailure(c.Context(), key, accountAttemptPolicy) {
+			// Challenge token yang sudah terbit dibatalkan, sehingga setelah kunci habis user harus
+			// login ulang dengan password alih-alih lanjut menebak kode dengan token yang sama
+			if err := h.repos.Users.Increment(c.Context(), user.ID, "challenge_version", 1); err != nil {
+				log.Println("Error invalidating 2FA challenge:", err)
+			}
+			return false, tooManyAttempts(c, h.lockedFor(c.Context(), key))
+		}
+		return false, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid code",
+		})
+	}
+
+	h.resetAttempts(c.Context(), key)
+	return true, nil
+}
+
+// verifySecondFactor menerima kode TOTP (sekali pakai per periode) atau kode cadangan (sekali pakai)
+func (h *Handler) verifySecondFactor(ctx context.Context, user model.User, code string) bool {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false
+	}
+
+	if step, ok := utils.ValidateTOTPAfter(user.TOTPSecret, code, user.TOTPLastStep, time.Now()); ok {
+		// Update bersyarat: kode yang sama tidak bisa dipakai dua kali meski request bersamaan
+		return h.repos.Users.AdvanceTOTPStep(ctx, user.ID, step) == nil
+	}
+
+	code = strings.ToLower(code)
+	for _, hash := range user.RecoveryCodeHashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) != nil {
+			continue
+		}
+		// Kode cadangan langsung dihapus setelah dipakai
+		if h.repos.Users.RemoveRecoveryCode(ctx, user.ID, hash) == nil {
+			log.Println("Recovery code used for user:", user.ID.Hex())
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// newRecoveryCodes membuat kode cadangan beserta hash bcrypt untuk disimpan
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		hashes = append(hashes, string(hash))
+	}
+	return codes, hashes, nil
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
-	"be_ecommerce/config"
+	"be_ecommerce/middleware"
 	"be_ecommerce/model"
+	"be_ecommerce/repository"
 	"be_ecommerce/utils"
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,47 +16,29 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Helper function to get user collection
-func getUserCollection() *mongo.Collection {
-	return config.MongoClient.Database("ecommerce").Collection("users")
-}
-
 // CRUD for Customers
-func GetCustomers(c *fiber.Ctx) error {
-	collection := getUserCollection()
-
+func (h *Handler) GetCustomers(c *fiber.Ctx) error {
 	// Query untuk mendapatkan semua pelanggan dengan role "customer"
-	filter := bson.M{"roles": "customer"}
-	cursor, err := collection.Find(context.Background(), filter)
+	customers, err := h.repos.Users.Find(c.Context(), repository.UserFilter{AllRoles: []string{"customer"}})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error fetching customers",
 		})
 	}
-	defer cursor.Close(context.Background())
-
-	// Parsing hasil query ke dalam slice
-	var customers []bson.M
-	if err := cursor.All(context.Background(), &customers); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Error parsing customers",
-		})
-	}
 
 	// Transform data untuk menyederhanakan output
 	transformedCustomers := make([]map[string]interface{}, 0)
 	for _, customer := range customers {
 		transformed := map[string]interface{}{
-			"id":        customer["_id"],
-			"username":  customer["username"],
-			"email":     customer["email"],
-			"roles":     customer["roles"],
+			"id":        customer.ID,
+			"username":  customer.Username,
+			"email":     customer.Email,
+			"roles":     customer.Roles,
+			"suspension": customer.Suspension,
 		}
 		transformedCustomers = append(transformedCustomers, transformed)
 	}
@@ -62,48 +47,19 @@ func GetCustomers(c *fiber.Ctx) error {
 	return c.JSON(transformedCustomers)
 }
 
-func GetUserProfile(c *fiber.Ctx) error {
-	// Ambil token dari header Authorization
-	token := c.Get("Authorization")
-	if token == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Authorization token is required",
-		})
-	}
-
-	// Hapus prefix "Bearer " dari token
-	token = strings.TrimPrefix(token, "Bearer ")
-
-	// Validasi token dan ambil klaim
-	claims, err := utils.ValidateJWT(token)
+func (h *Handler) GetUserProfile(c *fiber.Ctx) error {
+	// Ambil user_id dari token yang sudah diverifikasi middleware
+	objectID, err := middleware.CurrentUserID(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Invalid or expired token",
-		})
-	}
-
-	// Ambil user_id dari klaim
-	userID, ok := claims["user_id"].(string)
-	if !ok || userID == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"message": "Invalid token payload",
 		})
 	}
 
-	// Konversi user_id ke ObjectID
-	objectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid user ID format",
-		})
-	}
-
 	// Ambil data pengguna dari database
-	collection := config.MongoClient.Database("ecommerce").Collection("users")
-	var user model.User
-	err = collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&user)
+	user, err := h.repos.Users.FindByID(c.Context(), objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == repository.ErrNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"message": "User not found",
 			})
@@ -116,10 +72,13 @@ func GetUserProfile(c *fiber.Ctx) error {
 	// Siapkan respons dengan data user (hapus password)
 	user.Password = ""
 	response := fiber.Map{
-		"id":       user.ID.Hex(),
-		"username": user.Username,
-		"email":    user.Email,
-		"roles":    user.Roles,
+		"id":             user.ID.Hex(),
+		"username":       user.Username,
+		"email":          user.Email,
+		"roles":          user.Roles,
+		"email_verified": user.EmailVerified,
+
+		"two_factor_enabled": user.TwoFactorEnabled,
 	}
 
 	// Tambahkan `store_status` jika ada
@@ -139,47 +98,15 @@ func GetUserProfile(c *fiber.Ctx) error {
 	})
 }
 
-func EditProfile(c *fiber.Ctx) error {
-	// Ambil token dari header Authorization
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Authorization token is required",
-		})
-	}
-
-	// Periksa format Bearer token
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-	if token == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Invalid token format",
-		})
-	}
-
-	// Validasi token
-	claims, err := utils.ValidateJWT(token)
+func (h *Handler) EditProfile(c *fiber.Ctx) error {
+	// Ambil user_id dari token yang sudah diverifikasi middleware
+	objectID, err := middleware.CurrentUserID(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Invalid or expired token",
-		})
-	}
-
-	// Ambil user ID dari klaim token
-	userID, ok := claims["user_id"].(string)
-	if !ok || userID == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"message": "Invalid token payload",
 		})
 	}
 
-	// Konversi user ID ke ObjectID
-	objectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid user ID",
-		})
-	}
-
 	// Parsing data dari request body
 	var updatedData struct {
 		Username string `json:"username"`
@@ -198,14 +125,9 @@ func EditProfile(c *fiber.Ctx) error {
 	}
 
 	// Update username di database
-	collection := config.MongoClient.Database("ecommerce").Collection("users")
-	_, err = collection.UpdateOne(
-		context.Background(),
-		bson.M{"_id": objectID},
-		bson.M{"$set": bson.M{
-			"username": updatedData.Username,
-		}},
-	)
+	err = h.repos.Users.Update(c.Context(), objectID, nil, repository.Fields{
+		"username": updatedData.Username,
+	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update profile",
@@ -217,7 +139,7 @@ func EditProfile(c *fiber.Ctx) error {
 	})
 }
 
-func VerifyOTP(c *fiber.Ctx) error {
+func (h *Handler) VerifyOTP(c *fiber.Ctx) error {
 	// Parsing data dari request body
 	var body struct {
 		Email      string `json:"email"`
@@ -230,44 +152,98 @@ func VerifyOTP(c *fiber.Ctx) error {
 		})
 	}
 
-	log.Println("Verifying OTP for email:", body.Email, "with token:", body.ResetToken)
-
-	// Cek token reset
-	collection := config.MongoClient.Database("ecommerce").Collection("users")
-	var user model.User
-	err := collection.FindOne(
-		context.Background(),
-		bson.M{"email": body.Email, "reset_token": body.ResetToken},
-	).Decode(&user)
+	ipKey := "otp-ip:" + c.IP()
+	if wait := h.lockedFor(c.Context(), ipKey); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
 
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			log.Println("Invalid or expired token for email:", body.Email)
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"message": "Invalid OTP",
-			})
+	if _, err := h.checkResetToken(context.Background(), body.Email, body.ResetToken); err != nil {
+		if err == errOTPInvalid || err == errOTPExhausted {
+			h.recordFailure(c.Context(), ipKey, ipAttemptPolicy)
 		}
-		log.Println("Database error:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Server error",
-		})
+		return writeOTPError(c, err)
+	}
+
+	log.Println("OTP verified successfully for email:", utils.MaskEmail(body.Email))
+	return c.JSON(fiber.Map{
+		"message": "OTP verified successfully",
+	})
+}
+
+const (
+	resetEmailCooldown  = 60 * time.Second // Jeda minimal antar pengiriman OTP reset password
+	resetEmailWindow    = 24 * time.Hour   // Jendela waktu untuk batas pengiriman
+	resetEmailMaxPerDay = 5                // Maksimal OTP reset password dalam satu jendela
+)
+
+var (
+	errOTPInvalid   = errors.New("invalid otp")
+	errOTPExpired   = errors.New("otp expired")
+	errOTPExhausted = errors.New("too many invalid otp attempts")
+)
+
+// checkResetToken mencocokkan OTP dengan hash yang tersimpan secara constant-time.
+// Setiap percobaan lebih dulu memakai satu jatah reset_attempts secara atomik (batasnya ada di filter
+// update), sehingga percobaan paralel tidak bisa melewati maxOTPAttempts. Setelah jatah habis oleh
+// OTP yang salah, OTP dibatalkan dan user harus meminta OTP baru.
+func (h *Handler) checkResetToken(ctx context.Context, email, token string) (model.User, error) {
+	if token == "" {
+		return model.User{}, errOTPInvalid
+	}
+
+	user, err := h.repos.Users.ConsumeResetAttempt(ctx, email, maxOTPAttempts)
+	switch err {
+	case nil:
+	case repository.ErrLimitReached:
+		return model.User{}, errOTPExhausted
+	case repository.ErrNotFound:
+		return model.User{}, errOTPInvalid
+	default:
+		return model.User{}, err
 	}
 
-	// Periksa apakah token telah kedaluwarsa
 	if time.Now().After(user.ResetTokenExpiry) {
-		log.Println("OTP expired for email:", body.Email)
+		return model.User{}, errOTPExpired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(token)), []byte(user.ResetToken)) != 1 {
+		if user.ResetAttempts >= maxOTPAttempts {
+			log.Println("OTP invalidated after too many attempts for:", utils.MaskEmail(user.Email))
+			h.repos.Users.UpdateIf(ctx, user.ID, "reset_token", user.ResetToken, nil, "reset_token", "reset_token_expiry")
+			return model.User{}, errOTPExhausted
+		}
+		return model.User{}, errOTPInvalid
+	}
+
+	// OTP yang benar tidak menghabiskan jatah; VerifyOTP lalu ResetPassword memakai OTP yang sama
+	h.repos.Users.ReleaseResetAttempt(ctx, user.ID, user.ResetToken)
+	return user, nil
+}
+
+// writeOTPError memetakan error checkResetToken ke respons HTTP
+func writeOTPError(c *fiber.Ctx, err error) error {
+	switch err {
+	case errOTPInvalid:
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid OTP",
+		})
+	case errOTPExpired:
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"message": "OTP expired",
 		})
+	case errOTPExhausted:
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Too many invalid attempts, please request a new OTP",
+		})
+	default:
+		log.Println("Database error while verifying OTP:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Server error",
+		})
 	}
-
-	log.Println("OTP verified successfully for email:", body.Email)
-	return c.JSON(fiber.Map{
-		"message": "OTP verified successfully",
-	})
 }
 
-func SendPasswordResetEmail(c *fiber.Ctx) error {
+func (h *Handler) SendPasswordResetEmail(c *fiber.Ctx) error {
 	// Parsing email dari request body
 	var body struct {
 		Email string `json:"email"`
@@ -280,12 +256,10 @@ func SendPasswordResetEmail(c *fiber.Ctx) error {
 	}
 
 	// Cek apakah email terdaftar di database
-	collection := config.MongoClient.Database("ecommerce").Collection("users")
-	var user model.User
-	err := collection.FindOne(context.Background(), bson.M{"email": body.Email}).Decode(&user)
+	user, err := h.repos.Users.FindByEmail(context.Background(), body.Email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			log.Println("Email not found in database:", body.Email)
+		if err == repository.ErrNotFound {
+			log.Println("Email not found in database:", utils.MaskEmail(body.Email))
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"message": "Email not found",
 			})
@@ -296,19 +270,47 @@ func SendPasswordResetEmail(c *fiber.Ctx) error {
 		})
 	}
 
+	// Pembatasan: OTP baru mereset jatah percobaan, sehingga pengiriman ulang dibatasi jeda dan batas harian
+	now := time.Now()
+	if wait := resetEmailCooldown - now.Sub(user.ResetEmailSentAt); wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, fmt.Sprintf("%d", int(wait.Seconds())+1))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"message": "Please wait before requesting another OTP",
+		})
+	}
+	windowStart, sendCount := user.ResetEmailWindowStart, user.ResetEmailSendCount+1
+	if now.Sub(windowStart) > resetEmailWindow {
+		windowStart, sendCount = now, 1
+	}
+	if sendCount > resetEmailMaxPerDay {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"message": "Too many OTP requests, try again later",
+		})
+	}
+
 	// Generate OTP dan expiry time
 	resetToken := utils.GenerateRandomToken(6) // Contoh fungsi utilitas untuk membuat token OTP
-	expiry := time.Now().Add(10 * time.Minute) // OTP berlaku selama 10 menit
-
-	// Simpan OTP dan expiry ke database
-	_, err = collection.UpdateOne(
-		context.Background(),
-		bson.M{"email": body.Email},
-		bson.M{"$set": bson.M{
-			"reset_token":       resetToken,
-			"reset_token_expiry": expiry,
-		}},
-	)
+	expiry := now.Add(10 * time.Minute)        // OTP berlaku selama 10 menit
+
+	// Simpan OTP dan expiry ke database. Syarat reset_email_sent_at memastikan permintaan paralel
+	// tidak lolos jeda bersamaan: hanya satu yang cocok dengan nilai yang dibaca di atas.
+	var sentAt interface{}
+	if !user.ResetEmailSentAt.IsZero() {
+		sentAt = user.ResetEmailSentAt
+	}
+	err = h.repos.Users.UpdateIf(context.Background(), user.ID, "reset_email_sent_at", sentAt, repository.Fields{
+		"reset_token":              utils.HashToken(resetToken), // Simpan hash, bukan OTP asli
+		"reset_token_expiry":       expiry,
+		"reset_attempts":           0,
+		"reset_email_sent_at":      now,
+		"reset_email_window_start": windowStart,
+		"reset_email_send_count":   sendCount,
+	})
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"message": "Please wait before requesting another OTP",
+		})
+	}
 	if err != nil {
 		log.Println("Error saving OTP to database:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -325,13 +327,13 @@ func SendPasswordResetEmail(c *fiber.Ctx) error {
 		})
 	}
 
-	log.Println("Password reset email sent successfully to:", body.Email)
+	log.Println("Password reset email sent successfully to:", utils.MaskEmail(body.Email))
 	return c.JSON(fiber.Map{
 		"message": "Password reset email sent successfully",
 	})
 }
 
-func ResetPassword(c *fiber.Ctx) error {
+func (h *Handler) ResetPassword(c *fiber.Ctx) error {
 	// Parsing data dari request body
 	var body struct {
 		Email       string `json:"email"`
@@ -344,29 +346,18 @@ func ResetPassword(c *fiber.Ctx) error {
 		})
 	}
 
+	ipKey := "otp-ip:" + c.IP()
+	if wait := h.lockedFor(c.Context(), ipKey); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
 	// Validasi reset token
-	collection := config.MongoClient.Database("ecommerce").Collection("users")
-	var user model.User
-	err := collection.FindOne(context.Background(), bson.M{
-		"email":       body.Email,
-		"reset_token": body.ResetToken,
-	}).Decode(&user)
+	user, err := h.checkResetToken(context.Background(), body.Email, body.ResetToken)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"message": "Invalid or expired reset token",
-			})
+		if err == errOTPInvalid || err == errOTPExhausted {
+			h.recordFailure(c.Context(), ipKey, ipAttemptPolicy)
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to verify reset token",
-		})
-	}
-
-	// Cek apakah token sudah kedaluwarsa
-	if time.Now().After(user.ResetTokenExpiry) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Reset token expired",
-		})
+		return writeOTPError(c, err)
 	}
 
 	// Hash password baru
@@ -378,20 +369,21 @@ func ResetPassword(c *fiber.Ctx) error {
 	}
 
 	// Update password dan hapus reset token
-	_, err = collection.UpdateOne(
-		context.Background(),
-		bson.M{"email": body.Email},
-		bson.M{
-			"$set": bson.M{"password": hashedPassword},
-			"$unset": bson.M{"reset_token": "", "reset_token_expiry": ""},
-		},
-	)
+	err = h.repos.Users.Update(context.Background(), user.ID, nil, repository.Fields{"password": hashedPassword},
+		"reset_token", "reset_token_expiry", "reset_attempts")
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update password",
 		})
 	}
 
+	// Password berubah: semua sesi lama harus login ulang
+	if err := h.revokeUserTokens(context.Background(), user.ID, true); err != nil {
+		log.Println("Error revoking tokens after password reset:", err)
+	}
+	// Pemilik akun sudah terbukti, buka kunci login akun ini
+	h.resetAttempts(context.Background(), "login-email:"+strings.ToLower(user.Email))
+
 	return c.JSON(fiber.Map{
 		"message": "Password reset successfully",
 	})
@@ -407,7 +399,7 @@ func hashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
-func CreateCustomer(c *fiber.Ctx) error {
+func (h *Handler) CreateCustomer(c *fiber.Ctx) error {
 	var user model.User
 	if err := c.BodyParser(&user); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -430,8 +422,7 @@ func CreateCustomer(c *fiber.Ctx) error {
 	user.ID = primitive.NewObjectID()
 
 	// Simpan ke database
-	collection := getUserCollection()
-	_, err = collection.InsertOne(context.Background(), user)
+	err = h.repos.Users.Create(c.Context(), &user)
 	if err != nil {
 		log.Println("Error creating customer:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -443,7 +434,7 @@ func CreateCustomer(c *fiber.Ctx) error {
 		"message": "Customer created successfully",
 	})
 }
-func UpdateCustomer(c *fiber.Ctx) error {
+func (h *Handler) UpdateCustomer(c *fiber.Ctx) error {
 	// Parsing body request
 	var body struct {
 		UserID  string                 `json:"user_id"` // ID pengguna
@@ -470,29 +461,23 @@ func UpdateCustomer(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validasi: pastikan tidak ada field sensitif yang diperbarui
-	disallowedFields := []string{"_id", "password", "roles"}
-	for _, field := range disallowedFields {
-		if _, exists := body.Updates[field]; exists {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": fmt.Sprintf("Field '%s' cannot be updated", field),
-			})
-		}
-	}
-
-	// Pastikan data yang akan diupdate tidak kosong
-	if len(body.Updates) == 0 {
+	// Validasi: hanya field profil yang boleh diperbarui
+	updates, message := profileUpdates(body.Updates)
+	if message != "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "No updates provided",
+			"message": message,
 		})
 	}
 
 	// Update pengguna di database
-	collection := getUserCollection()
-	filter := bson.M{"_id": userID, "roles": "customer"}
-	update := bson.M{"$set": body.Updates}
+	err = h.repos.Users.Update(c.Context(), userID, []string{"customer"}, updates)
 
-	result, err := collection.UpdateOne(context.Background(), filter, update)
+	// Periksa apakah ada dokumen yang diperbarui
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Customer not found or no changes made",
+		})
+	}
 	if err != nil {
 		log.Println("Error updating customer:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -500,19 +485,12 @@ func UpdateCustomer(c *fiber.Ctx) error {
 		})
 	}
 
-	// Periksa apakah ada dokumen yang diperbarui
-	if result.MatchedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "Customer not found or no changes made",
-		})
-	}
-
 	return c.JSON(fiber.Map{
 		"message": "Customer updated successfully",
 	})
 }
 
-func DeleteCustomer(c *fiber.Ctx) error {
+func (h *Handler) DeleteCustomer(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -521,9 +499,8 @@ func DeleteCustomer(c *fiber.Ctx) error {
 		})
 	}
 
-	collection := getUserCollection()
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": userID, "roles": "customer"})
-	if err != nil {
+	err = h.repos.Users.Delete(c.Context(), userID, "customer")
+	if err != nil && err != repository.ErrNotFound {
 		log.Println("Error deleting customer:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error deleting customer",
@@ -535,49 +512,65 @@ func DeleteCustomer(c *fiber.Ctx) error {
 	})
 }
 
-// CRUD for Sellers
-func GetSellers(c *fiber.Ctx) error {
-	collection := getUserCollection()
+// adminEditableFields adalah field profil yang boleh diubah admin lewat endpoint CRUD user.
+// Password, roles, token_version, suspension dan data toko diubah lewat alurnya masing-masing.
+var adminEditableFields = map[string]bool{"username": true}
 
-	// Query to fetch all users with the role "seller"
-	filter := bson.M{
-		"$or": []bson.M{
-			{"roles": "seller"},
-			{"store_status": "rejected"},
-		},
+// profileUpdates memastikan updates hanya berisi adminEditableFields berupa string yang tidak kosong.
+// Jika tidak valid, message berisi pesan error untuk client.
+func profileUpdates(updates map[string]interface{}) (fields repository.Fields, message string) {
+	if len(updates) == 0 {
+		return nil, "No updates provided"
+	}
+	fields = repository.Fields{}
+	for field, value := range updates {
+		if !adminEditableFields[field] {
+			return nil, fmt.Sprintf("Field '%s' cannot be updated", field)
+		}
+		text, ok := value.(string)
+		if !ok || strings.TrimSpace(text) == "" {
+			return nil, fmt.Sprintf("Field '%s' must be a non-empty string", field)
+		}
+		fields[field] = strings.TrimSpace(text)
 	}
-	cursor, err := collection.Find(context.Background(), filter)
+	return fields, ""
+}
+
+// CRUD for Sellers
+func (h *Handler) GetSellers(c *fiber.Ctx) error {
+	// Query to fetch all users with the role "seller" (termasuk pengajuan yang ditolak)
+	sellers, err := h.repos.Users.Find(c.Context(), repository.UserFilter{
+		AllRoles:      []string{"seller"},
+		OrStoreStatus: "rejected",
+	})
 	if err != nil {
 		log.Println("Error fetching sellers:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error fetching sellers",
 		})
 	}
-	defer cursor.Close(context.Background())
-
-	// Parse query results into a slice
-	var sellers []bson.M
-	if err := cursor.All(context.Background(), &sellers); err != nil {
-		log.Println("Error decoding sellers:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Error decoding sellers",
-		})
-	}
 
 	// Transform the data for simplified output
 	transformedSellers := make([]map[string]interface{}, 0)
 	for _, seller := range sellers {
+		var storeInfo model.StoreInfo
+		if seller.StoreInfo != nil {
+			storeInfo = *seller.StoreInfo
+		}
 		transformed := map[string]interface{}{
-			"id":           seller["_id"],
-			"username":     seller["username"],
-			"email":        seller["email"],
-			"roles":        seller["roles"],
-			"store_status": seller["store_status"],
+			"id":           seller.ID,
+			"username":     seller.Username,
+			"email":        seller.Email,
+			"roles":        seller.Roles,
+			"store_status": seller.StoreStatus,
+			"store_id":     seller.SellerID,
+			"suspension":   seller.Suspension,
 			"store_info": map[string]interface{}{
-				"store_name":   getStringOrDefault(seller, "store_info", "store_name"),
-				"full_address": getStringOrDefault(seller, "store_info", "full_address"),
-				"nik":          getStringOrDefault(seller, "store_info", "nik"),
-				"photo_path":   getStringOrDefault(seller, "store_info", "photo_path"),
+				"store_name":   storeInfo.StoreName,
+				"full_address": storeInfo.FullAddress,
+				"nik":          storeInfo.NIK,
+				"photo_path":   storeInfo.PhotoPath,
+				"photo_url":    h.kycPhotoURL(storeInfo.PhotoPath), // Foto KYC hanya lewat signed URL
 			},
 		}
 
@@ -591,40 +584,22 @@ func GetSellers(c *fiber.Ctx) error {
 	})
 }
 
-// Helper function to safely get nested strings
-func getStringOrDefault(doc bson.M, key string, nestedKey string) string {
-	if outer, ok := doc[key].(bson.M); ok {
-		if value, ok := outer[nestedKey].(string); ok {
-			return value
-		}
-	}
-	return ""
-}
-
 // GetUserByID retrieves a user by their ID
-func GetUserByID(c *fiber.Ctx) error {
+func (h *Handler) GetUserByID(c *fiber.Ctx) error {
 	// Ambil ID dari URL parameter
 	userID := c.Params("id")
 
-	// Buat filter untuk query
-	var filter bson.M
-	if len(userID) == 24 {
-		// Jika ID valid sebagai ObjectID
-		objectID, err := primitive.ObjectIDFromHex(userID)
-		if err == nil {
-			filter = bson.M{"_id": objectID}
-		}
-	} else {
-		// Jika ID bukan ObjectID, gunakan sebagai string
-		filter = bson.M{"_id": userID}
+	// Semua user disimpan dengan _id berupa ObjectID
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "User not found",
+		})
 	}
 
-	// Ambil koleksi user
-	userCollection := config.MongoClient.Database("ecommerce").Collection("users")
-
 	// Cari user berdasarkan ID
-	var user model.User
-	err := userCollection.FindOne(context.Background(), filter).Decode(&user)
+	user, err := h.repos.Users.FindByID(c.Context(), objectID)
 	if err != nil {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{
 			"status":  "error",
@@ -671,7 +646,7 @@ func GetUserByID(c *fiber.Ctx) error {
 	})
 }
 
-func GetSellerByID(c *fiber.Ctx) error {
+func (h *Handler) GetSellerByID(c *fiber.Ctx) error {
 	id := c.Params("id")
 
 	// Konversi ID ke ObjectID MongoDB
@@ -683,10 +658,8 @@ func GetSellerByID(c *fiber.Ctx) error {
 	}
 
 	// Cari seller berdasarkan ID dan role "seller"
-	collection := getUserCollection()
-	var seller model.User
-	err = collection.FindOne(context.Background(), bson.M{"_id": userID, "roles": "seller"}).Decode(&seller)
-	if err != nil {
+	seller, err := h.repos.Users.FindByID(c.Context(), userID)
+	if err != nil || !contains(seller.Roles, "seller") {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "Seller not found",
 		})
@@ -721,42 +694,15 @@ func GetSellerByID(c *fiber.Ctx) error {
 			}
 			return "unknown"
 		}(),
-		"suspended": func() bool {
-			if seller.StoreStatus != nil && *seller.StoreStatus == "suspended" {
-				return true
-			}
-			return false
-		}(),
+		"store_id":   seller.SellerID,
+		"suspended":  seller.IsSuspended(),
+		"suspension": seller.Suspension,
 	}
 
 	return c.JSON(response)
 }
 
-func CreateSeller(c *fiber.Ctx) error {
-	var user model.User
-	if err := c.BodyParser(&user); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid request body",
-		})
-	}
-
-	user.Roles = []string{"seller"}
-	user.ID = primitive.NewObjectID()
-	collection := getUserCollection()
-	_, err := collection.InsertOne(context.Background(), user)
-	if err != nil {
-		log.Println("Error creating seller:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Error creating seller",
-		})
-	}
-
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Seller created successfully",
-	})
-}
-
-func UpdateSeller(c *fiber.Ctx) error {
+func (h *Handler) UpdateSeller(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -765,16 +711,21 @@ func UpdateSeller(c *fiber.Ctx) error {
 		})
 	}
 
-	var updates bson.M
-	if err := c.BodyParser(&updates); err != nil {
+	var body map[string]interface{}
+	if err := c.BodyParser(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid request body",
 		})
 	}
+	updates, message := profileUpdates(body)
+	if message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": message,
+		})
+	}
 
-	collection := getUserCollection()
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": userID, "roles": "seller"}, bson.M{"$set": updates})
-	if err != nil {
+	err = h.repos.Users.Update(c.Context(), userID, []string{"seller"}, updates)
+	if err != nil && err != repository.ErrNotFound {
 		log.Println("Error updating seller:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error updating seller",
@@ -786,36 +737,12 @@ func UpdateSeller(c *fiber.Ctx) error {
 	})
 }
 
-func UpdateProductForSeller(c *fiber.Ctx) error {
-    // Ambil token dari header Authorization
-    token := c.Get("Authorization")
-    if token == "" {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "message": "Unauthorized: Missing token",
-        })
-    }
-
-    // Validasi token dan ambil klaim
-    claims, err := utils.ValidateJWT(token[7:]) // Hapus prefix "Bearer "
+func (h *Handler) UpdateProductForSeller(c *fiber.Ctx) error {
+    // Ambil toko (seller_id) dari token yang sudah diverifikasi middleware
+    sellerID, err := middleware.CurrentSellerID(c)
     if err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "message": "Unauthorized: Invalid token",
-        })
-    }
-
-    // Ambil user_id dari klaim
-    userID, ok := claims["user_id"].(string)
-    if !ok || userID == "" {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "message": "Unauthorized: Invalid user ID",
-        })
-    }
-
-    // Konversi user_id ke ObjectID
-    sellerID, err := primitive.ObjectIDFromHex(userID)
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "message": "Invalid User ID format",
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+            "message": "Forbidden: Seller store not found",
         })
     }
 
@@ -829,10 +756,8 @@ func UpdateProductForSeller(c *fiber.Ctx) error {
     }
 
     // Cari produk berdasarkan ID dan pastikan milik seller
-    productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-    var existingProduct model.Product
-    err = productCollection.FindOne(context.Background(), bson.M{"_id": objectID, "seller_id": sellerID}).Decode(&existingProduct)
-    if err != nil {
+    existingProduct, err := h.repos.Products.FindByID(c.Context(), objectID)
+    if err != nil || existingProduct.SellerID != sellerID {
         return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
             "message": "Forbidden: You do not have permission to update this product",
         })
@@ -847,7 +772,7 @@ func UpdateProductForSeller(c *fiber.Ctx) error {
         })
     }
 
-    updateData := bson.M{}
+    updateData := repository.Fields{}
 
     if len(form.Value["name"]) > 0 {
         updateData["name"] = form.Value["name"][0]
@@ -892,31 +817,37 @@ func UpdateProductForSeller(c *fiber.Ctx) error {
         }
     }
 
-    // **Update Image jika ada upload file baru**
-    fileHeaders := form.File["image"]
-    if len(fileHeaders) > 0 {
-        file := fileHeaders[0]
-        imagePath := fmt.Sprintf("./uploads/%s", file.Filename)
-
-        // Simpan gambar baru
-        if err := c.SaveFile(file, imagePath); err != nil {
-            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-                "message": "Failed to save image",
-                "error":   err.Error(),
-            })
+    // **Update Image jika ada upload file baru**: gambar baru menggantikan sampul galeri
+    var added, removed []model.ProductImage
+    if fileHeaders := form.File["image"]; len(fileHeaders) > 0 {
+        images, old, err := h.replaceCover(c.Context(), existingProduct, fileHeaders[0])
+        if err != nil {
+            return writeImageError(c, err)
+        }
+        for key, value := range galleryFields(existingProduct, images) {
+            updateData[key] = value
         }
+        added, removed = newImages(existingProduct.Images, images), old
+    }
 
-        updateData["image"] = imagePath
+    // Harga, diskon dan stok produk bervarian adalah ringkasan varian, diatur lewat /seller/products/:id/variants
+    if existingProduct.HasVariants() {
+        delete(updateData, "price")
+        delete(updateData, "discount")
+        delete(updateData, "stock")
     }
 
     // Update produk di database
-    _, err = productCollection.UpdateOne(context.Background(), bson.M{"_id": objectID}, bson.M{"$set": updateData})
+    err = h.repos.Products.Update(c.Context(), objectID, &sellerID, updateData)
     if err != nil {
+        h.cleanupProductImages(c.Context(), added)
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
             "message": "Failed to update product",
             "error":   err.Error(),
         })
     }
+    h.reindexProduct(c.Context(), objectID)
+    h.cleanupProductImages(c.Context(), removed)
 
     return c.JSON(fiber.Map{
         "message": "Product updated successfully",
@@ -924,36 +855,12 @@ func UpdateProductForSeller(c *fiber.Ctx) error {
     })
 }
 
-func DeleteProductForSeller(c *fiber.Ctx) error {
-    // Ambil token dari header Authorization
-    token := c.Get("Authorization")
-    if token == "" {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "message": "Unauthorized: Missing token",
-        })
-    }
-
-    // Validasi token dan ambil klaim
-    claims, err := utils.ValidateJWT(token[7:]) // Hapus prefix "Bearer "
+func (h *Handler) DeleteProductForSeller(c *fiber.Ctx) error {
+    // Ambil toko (seller_id) dari token yang sudah diverifikasi middleware
+    sellerID, err := middleware.CurrentSellerID(c)
     if err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "message": "Unauthorized: Invalid token",
-        })
-    }
-
-    // Ambil user_id dari klaim
-    userID, ok := claims["user_id"].(string)
-    if !ok || userID == "" {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "message": "Unauthorized: Invalid user ID",
-        })
-    }
-
-    // Konversi user_id ke ObjectID
-    sellerID, err := primitive.ObjectIDFromHex(userID)
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "message": "Invalid User ID format",
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+            "message": "Forbidden: Seller store not found",
         })
     }
 
@@ -966,21 +873,24 @@ func DeleteProductForSeller(c *fiber.Ctx) error {
         })
     }
 
+    // Galeri produk dibersihkan setelah produk dihapus
+    product, _ := h.repos.Products.FindByID(c.Context(), objectID)
+
     // Hapus hanya jika produk milik seller
-    productCollection := config.MongoClient.Database("ecommerce").Collection("products")
-    result, err := productCollection.DeleteOne(context.Background(), bson.M{"_id": objectID, "seller_id": sellerID})
+    err = h.repos.Products.Delete(c.Context(), objectID, &sellerID)
+    if err == repository.ErrNotFound {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+            "message": "Forbidden: You do not have permission to delete this product",
+        })
+    }
     if err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
             "message": "Failed to delete product",
             "error":   err.Error(),
         })
     }
-
-    if result.DeletedCount == 0 {
-        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-            "message": "Forbidden: You do not have permission to delete this product",
-        })
-    }
+    h.unindexProduct(objectID)
+    h.cleanupProductImages(c.Context(), product.Images)
 
     return c.JSON(fiber.Map{
         "message": "Product deleted successfully",
@@ -988,7 +898,7 @@ func DeleteProductForSeller(c *fiber.Ctx) error {
     })
 }
 
-func DeleteSeller(c *fiber.Ctx) error {
+func (h *Handler) DeleteSeller(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -997,9 +907,8 @@ func DeleteSeller(c *fiber.Ctx) error {
 		})
 	}
 
-	collection := getUserCollection()
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": userID, "roles": "seller"})
-	if err != nil {
+	err = h.repos.Users.Delete(c.Context(), userID, "seller")
+	if err != nil && err != repository.ErrNotFound {
 		log.Println("Error deleting seller:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error deleting seller",
@@ -1012,9 +921,8 @@ func DeleteSeller(c *fiber.Ctx) error {
 }
 
 // CRUD for Users with Both Roles (Customer and Seller)
-func GetCustomerSellers(c *fiber.Ctx) error {
-	collection := getUserCollection()
-	cursor, err := collection.Find(context.Background(), bson.M{"roles": bson.M{"$all": []string{"customer", "seller"}}})
+func (h *Handler) GetCustomerSellers(c *fiber.Ctx) error {
+	customerSellers, err := h.repos.Users.Find(c.Context(), repository.UserFilter{AllRoles: []string{"customer", "seller"}})
 	if err != nil {
 		log.Println("Error fetching customer-sellers:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -1022,42 +930,10 @@ func GetCustomerSellers(c *fiber.Ctx) error {
 		})
 	}
 
-	var customerSellers []model.User
-	if err = cursor.All(context.Background(), &customerSellers); err != nil {
-		log.Println("Error decoding customer-sellers:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Error decoding customer-sellers",
-		})
-	}
-
 	return c.JSON(customerSellers)
 }
 
-func CreateCustomerSeller(c *fiber.Ctx) error {
-	var user model.User
-	if err := c.BodyParser(&user); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid request body",
-		})
-	}
-
-	user.Roles = []string{"customer", "seller"}
-	user.ID = primitive.NewObjectID()
-	collection := getUserCollection()
-	_, err := collection.InsertOne(context.Background(), user)
-	if err != nil {
-		log.Println("Error creating customer-seller:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Error creating customer-seller",
-		})
-	}
-
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Customer-seller created successfully",
-	})
-}
-
-func UpdateCustomerSeller(c *fiber.Ctx) error {
+func (h *Handler) UpdateCustomerSeller(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -1066,16 +942,21 @@ func UpdateCustomerSeller(c *fiber.Ctx) error {
 		})
 	}
 
-	var updates bson.M
-	if err := c.BodyParser(&updates); err != nil {
+	var body map[string]interface{}
+	if err := c.BodyParser(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid request body",
 		})
 	}
+	updates, message := profileUpdates(body)
+	if message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": message,
+		})
+	}
 
-	collection := getUserCollection()
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": userID, "roles": bson.M{"$all": []string{"customer", "seller"}}}, bson.M{"$set": updates})
-	if err != nil {
+	err = h.repos.Users.Update(c.Context(), userID, []string{"customer", "seller"}, updates)
+	if err != nil && err != repository.ErrNotFound {
 		log.Println("Error updating customer-seller:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error updating customer-seller",
@@ -1087,7 +968,7 @@ func UpdateCustomerSeller(c *fiber.Ctx) error {
 	})
 }
 
-func DeleteCustomerSeller(c *fiber.Ctx) error {
+func (h *Handler) DeleteCustomerSeller(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -1096,9 +977,8 @@ func DeleteCustomerSeller(c *fiber.Ctx) error {
 		})
 	}
 
-	collection := getUserCollection()
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": userID, "roles": bson.M{"$all": []string{"customer", "seller"}}})
-	if err != nil {
+	err = h.repos.Users.Delete(c.Context(), userID, "customer", "seller")
+	if err != nil && err != repository.ErrNotFound {
 		log.Println("Error deleting customer-seller:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error deleting customer-seller",
@@ -1111,7 +991,24 @@ func DeleteCustomerSeller(c *fiber.Ctx) error {
 }
 
 // Suspend User Account
-func SuspendUser(c *fiber.Ctx) error {
+func (h *Handler) SuspendUser(c *fiber.Ctx) error {
+	return h.suspendAccount(c, nil, "User")
+}
+
+func (h *Handler) UnsuspendUser(c *fiber.Ctx) error {
+	return h.unsuspendAccount(c, nil, "User")
+}
+
+func (h *Handler) SuspendSeller(c *fiber.Ctx) error {
+	return h.suspendAccount(c, []string{"seller"}, "Seller")
+}
+
+func (h *Handler) UnsuspendSeller(c *fiber.Ctx) error {
+	return h.unsuspendAccount(c, []string{"seller"}, "Seller")
+}
+
+// suspendAccount menyimpan detail suspend (alasan, admin, dan masa berlaku) ke user yang memiliki roles
+func (h *Handler) suspendAccount(c *fiber.Ctx, roles []string, label string) error {
 	id := c.Params("id")
 	userID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -1120,112 +1017,101 @@ func SuspendUser(c *fiber.Ctx) error {
 		})
 	}
 
-	collection := getUserCollection()
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": userID}, bson.M{"$set": bson.M{"suspended": true}})
-	if err != nil {
-		log.Println("Error suspending user:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Error suspending user",
+	var body struct {
+		Reason    string     `json:"reason"`
+		ExpiresAt *time.Time `json:"expires_at"` // Opsional, kosong berarti permanen
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
 		})
 	}
-
-	return c.JSON(fiber.Map{
-		"message": "User account suspended successfully",
-	})
-}
-
-func UnsuspendUser(c *fiber.Ctx) error {
-	id := c.Params("id")
-	userID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
+	if strings.TrimSpace(body.Reason) == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid ID",
+			"message": "Suspension reason is required",
 		})
 	}
-
-	collection := getUserCollection()
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": userID}, bson.M{"$set": bson.M{"suspended": false}})
-	if err != nil {
-		log.Println("Error unsuspending user:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Error unsuspending user",
+	if body.ExpiresAt != nil && body.ExpiresAt.Before(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "expires_at must be in the future",
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"message": "User account unsuspended successfully",
-	})
-}
-func SuspendSeller(c *fiber.Ctx) error {
-	sellerID := c.Params("id")
-	log.Println("Suspend request received for seller ID:", sellerID)
-
-	objectID, err := primitive.ObjectIDFromHex(sellerID)
+	actorID, err := middleware.CurrentUserID(c)
 	if err != nil {
-		log.Println("Invalid seller ID format:", sellerID)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+	if actorID == userID {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid seller ID format",
+			"message": "You cannot suspend your own account",
 		})
 	}
 
-	collection := getUserCollection()
-	filter := bson.M{"_id": objectID, "roles": bson.M{"$all": []string{"seller"}}}
-	update := bson.M{"$set": bson.M{"suspended": true}}
+	suspension := model.Suspension{
+		Reason:      body.Reason,
+		SuspendedBy: actorID,
+		SuspendedAt: time.Now(),
+		ExpiresAt:   body.ExpiresAt,
+	}
 
-	result, err := collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Users.Update(context.Background(), userID, roles, repository.Fields{"suspension": suspension}, "suspended")
+	if err == repository.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": label + " not found",
+		})
+	}
 	if err != nil {
-		log.Println("Error suspending seller:", err)
+		log.Println("Error suspending account:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to suspend seller",
+			"message": "Failed to suspend " + strings.ToLower(label),
 		})
 	}
 
-	if result.ModifiedCount == 0 {
-		log.Println("Seller not found or already suspended:", sellerID)
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "Seller not found or already suspended",
-		})
+	// Cabut semua token dan sesi akun yang di-suspend
+	if err := h.revokeUserTokens(context.Background(), userID, true); err != nil {
+		log.Println("Error revoking tokens for suspended account:", err)
 	}
 
-	log.Println("Seller suspended successfully:", sellerID)
+	log.Println(label, "suspended:", userID.Hex(), "by", actorID.Hex())
 	return c.JSON(fiber.Map{
-		"message": "Seller suspended successfully",
+		"message":    label + " suspended successfully",
+		"suspension": suspension,
 	})
 }
 
-func UnsuspendSeller(c *fiber.Ctx) error {
-	sellerID := c.Params("id")
-	log.Println("Received unsuspend request for seller ID:", sellerID)
-
-	objectID, err := primitive.ObjectIDFromHex(sellerID)
+// unsuspendAccount menghapus status suspend dari user yang memiliki roles
+func (h *Handler) unsuspendAccount(c *fiber.Ctx, roles []string, label string) error {
+	id := c.Params("id")
+	userID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		log.Println("Invalid ObjectID:", sellerID)
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid seller ID format",
+			"message": "Invalid ID",
 		})
 	}
 
-	collection := getUserCollection()
-	filter := bson.M{"_id": objectID, "roles": bson.M{"$all": []string{"seller"}}}
-	update := bson.M{"$set": bson.M{"suspended": false}}
-
-	result, err := collection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		log.Println("Database error:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to unsuspend seller",
-		})
+	user, err := h.repos.Users.FindByID(context.Background(), userID)
+	if err == nil && user.Suspension == nil {
+		err = repository.ErrNotFound
 	}
-
-	if result.ModifiedCount == 0 {
-		log.Println("No matching seller found or already unsuspended:", sellerID)
+	if err == nil {
+		err = h.repos.Users.Update(context.Background(), userID, roles, nil, "suspension", "suspended")
+	}
+	if err == repository.ErrNotFound {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "Seller not found or already unsuspended",
+			"message": label + " not found or not suspended",
+		})
+	}
+	if err != nil {
+		log.Println("Error unsuspending account:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to unsuspend " + strings.ToLower(label),
 		})
 	}
 
-	log.Println("Seller unsuspended successfully:", sellerID)
+	log.Println(label, "unsuspended:", userID.Hex())
 	return c.JSON(fiber.Map{
-		"message": "Seller unsuspended successfully",
+		"message": label + " unsuspended successfully",
 	})
 }
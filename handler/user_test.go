@@ -0,0 +1,25 @@
+package handler
+
+import "testing"
+
+func TestProfileUpdatesAllowsOnlyProfileFields(t *testing.T) {
+	fields, message := profileUpdates(map[string]interface{}{"username": " budi "})
+	if message != "" || fields["username"] != "budi" {
+		t.Errorf("fields = %v, message = %q, want trimmed username", fields, message)
+	}
+
+	for _, updates := range []map[string]interface{}{
+		{},
+		{"username": ""},
+		{"username": 42},
+		{"password": "secret"},
+		{"roles": []interface{}{"admin"}},
+		{"token_version": 0},
+		{"suspension": nil},
+		{"username": "budi", "email_verified": true},
+	} {
+		if fields, message := profileUpdates(updates); message == "" {
+			t.Errorf("profileUpdates(%v) = %v, want it rejected", updates, fields)
+		}
+	}
+}
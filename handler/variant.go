@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"be_ecommerce/middleware"
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Batas option dan varian per produk
+const (
+	maxProductOptions   = 3
+	maxOptionValues     = 50
+	maxProductVariants  = 100
+	maxOptionNameLength = 30
+	maxSKULength        = 64
+)
+
+// productVariant mengembalikan varian yang dipilih untuk produk. Produk dengan varian wajib memilih
+// salah satu variannya, sedangkan produk tanpa varian tidak boleh diberi variantID.
+// Varian kosong (ID nol) berarti produk dijual tanpa varian.
+func productVariant(product model.Product, variantID primitive.ObjectID) (model.ProductVariant, error) {
+	if !product.HasVariants() {
+		if !variantID.IsZero() {
+			return model.ProductVariant{}, errUnknownVariant
+		}
+		return model.ProductVariant{}, nil
+	}
+	if variantID.IsZero() {
+		return model.ProductVariant{}, errVariantRequired
+	}
+	variant, ok := product.Variant(variantID)
+	if !ok {
+		return model.ProductVariant{}, errUnknownVariant
+	}
+	return variant, nil
+}
+
+// selectVariant adalah productVariant untuk variant_id berupa string dari keranjang
+func selectVariant(product model.Product, variantID string) (model.ProductVariant, error) {
+	id := primitive.NilObjectID
+	if variantID != "" {
+		var err error
+		if id, err = primitive.ObjectIDFromHex(variantID); err != nil {
+			return model.ProductVariant{}, errUnknownVariant
+		}
+	}
+	return productVariant(product, id)
+}
+
+// writeVariantError memetakan error selectVariant ke respons HTTP
+func writeVariantError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errVariantRequired) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Please choose a variant of this product"})
+	}
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Variant not found"})
+}
+
+// variantsRequest adalah body PUT .../variants; mengganti semua option dan varian produk
+type variantsRequest struct {
+	Options  []model.ProductOption `json:"options"`
+	Variants []variantInput        `json:"variants"`
+}
+
+type variantInput struct {
+	ID       string            `json:"id"` // Kosong untuk varian baru; isi agar varian di keranjang tetap berlaku
+	SKU      string            `json:"sku"`
+	Options  map[string]string `json:"options"`
+	Price    int               `json:"price"`
+	Discount int               `json:"discount"`
+	Stock    int               `json:"stock"`
+	Image    string            `json:"image"` // Salah satu gambar produk
+}
+
+// buildVariants memvalidasi permintaan dan menghasilkan option serta varian yang siap disimpan.
+// Setiap varian harus memiliki tepat satu nilai untuk setiap option dan kombinasinya tidak boleh kembar.
+func buildVariants(product model.Product, req variantsRequest) ([]model.ProductOption, []model.ProductVariant, error) {
+	if len(req.Options) == 0 {
+		if len(req.Variants) > 0 {
+			return nil, nil, errors.New("Variants require at least one option")
+		}
+		return nil, nil, nil
+	}
+	if len(req.Options) > maxProductOptions {
+		return nil, nil, fmt.Errorf("A product can have at most %d options", maxProductOptions)
+	}
+	if len(req.Variants) == 0 || len(req.Variants) > maxProductVariants {
+		return nil, nil, fmt.Errorf("Between 1 and %d variants are required", maxProductVariants)
+	}
+
+	// Option: nama unik, nilai unik per option
+	options := make([]model.ProductOption, 0, len(req.Options))
+	allowed := map[string]map[string]bool{}
+	for _, option := range req.Options {
+		name := strings.TrimSpace(option.Name)
+		if name == "" || len(name) > maxOptionNameLength {
+			return nil, nil, fmt.Errorf("Option names must be between 1 and %d characters", maxOptionNameLength)
+		}
+		if allowed[name] != nil {
+			return nil, nil, fmt.Errorf("Duplicate option %q", name)
+		}
+		if len(option.Values) == 0 || len(option.Values) > maxOptionValues {
+			return nil, nil, fmt.Errorf("Option %q must have between 1 and %d values", name, maxOptionValues)
+		}
+		allowed[name] = map[string]bool{}
+		values := make([]string, 0, len(option.Values))
+		for _, value := range option.Values {
+			value = strings.TrimSpace(value)
+			if value == "" || allowed[name][value] {
+				return nil, nil, fmt.Errorf("Values of option %q must be unique and not empty", name)
+			}
+			allowed[name][value] = true
+			values = append(values, value)
+		}
+		options = append(options, model.ProductOption{Name: name, Values: values})
+	}
+
+	// Gambar varian dipilih dari gambar yang sudah dimiliki produk
+	images := map[string]bool{product.Image: true}
+	for _, image := range product.Images {
+		for _, path := range image.Paths() {
+			images[path] = true
+		}
+	}
+	for _, variant := range product.Variants {
+		images[variant.Image] = true
+	}
+
+	variants := make([]model.ProductVariant, 0, len(req.Variants))
+	skus := map[string]bool{}
+	combinations := map[string]bool{}
+	for _, input := range req.Variants {
+		sku := strings.TrimSpace(input.SKU)
+		if sku == "" || len(sku) > maxSKULength {
+			return nil, nil, fmt.Errorf("SKU must be between 1 and %d characters", maxSKULength)
+		}
+		if skus[strings.ToLower(sku)] {
+			return nil, nil, fmt.Errorf("Duplicate SKU %q", sku)
+		}
+		skus[strings.ToLower(sku)] = true
+		if input.Price <= 0 {
+			return nil, nil, fmt.Errorf("Price of variant %q must be greater than 0", sku)
+		}
+		if input.Discount < 0 || input.Discount > 100 {
+			return nil, nil, fmt.Errorf("Discount of variant %q must be between 0 and 100", sku)
+		}
+		if input.Stock < 0 {
+			return nil, nil, fmt.Errorf("Stock of variant %q must not be negative", sku)
+		}
+		if input.Image != "" && !images[input.Image] {
+			return nil, nil, fmt.Errorf("Image of variant %q must be one of the product images", sku)
+		}
+
+		variant := model.ProductVariant{
+			ID:       primitive.NewObjectID(),
+			SKU:      sku,
+			Options:  map[string]string{},
+			Price:    input.Price,
+			Discount: input.Discount,
+			Stock:    input.Stock,
+			Image:    input.Image,
+		}
+		// Varian lama mempertahankan ID dan jumlah terjualnya
+		if input.ID != "" {
+			id, err := primitive.ObjectIDFromHex(input.ID)
+			existing, ok := product.Variant(id)
+			if err != nil || !ok {
+				return nil, nil, fmt.Errorf("Variant %q does not belong to this product", input.ID)
+			}
+			variant.ID = existing.ID
+			variant.Sold = existing.Sold
+		}
+
+		if len(input.Options) != len(options) {
+			return nil, nil, fmt.Errorf("Variant %q must have exactly one value for every option", sku)
+		}
+		key := make([]string, 0, len(options))
+		for _, option := range options {
+			value := strings.TrimSpace(input.Options[option.Name])
+			if !allowed[option.Name][value] {
+				return nil, nil, fmt.Errorf("Variant %q has an invalid value for option %q", sku, option.Name)
+			}
+			variant.Options[option.Name] = value
+			key = append(key, value)
+		}
+		combination := strings.Join(key, "\x00")
+		if combinations[combination] {
+			return nil, nil, fmt.Errorf("Variant %q duplicates another variant's options", sku)
+		}
+		combinations[combination] = true
+
+		variants = append(variants, variant)
+	}
+	return options, variants, nil
+}
+
+// saveVariants mengganti varian produk; sellerID nil berarti admin (produk toko mana pun)
+func (h *Handler) saveVariants(c *fiber.Ctx, sellerID *primitive.ObjectID) error {
+	productID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid product ID format"})
+	}
+	var req variantsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid request body"})
+	}
+
+	product, err := h.repos.Products.FindByID(c.Context(), productID)
+	if err != nil || (sellerID != nil && product.SellerID != *sellerID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Product not found"})
+	}
+
+	options, variants, err := buildVariants(product, req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	err = h.repos.Products.SetVariants(c.Context(), productID, sellerID, options, variants)
+	switch {
+	case err == repository.ErrNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Product not found"})
+	case err == repository.ErrDuplicateSKU:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "SKU is already used by another product"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update product variants",
+			"error":   err.Error(),
+		})
+	}
+	h.reindexProduct(c.Context(), productID)
+
+	return c.JSON(fiber.Map{
+		"message":  "Product variants updated successfully",
+		"options":  options,
+		"variants": variants,
+	})
+}
+
+// PUT /products/:id/variants → Admin mengatur varian produk mana pun
+func (h *Handler) UpdateProductVariants(c *fiber.Ctx) error {
+	return h.saveVariants(c, nil)
+}
+
+// PUT /seller/products/:id/variants → Seller mengatur varian produk tokonya
+func (h *Handler) UpdateSellerProductVariants(c *fiber.Ctx) error {
+	sellerID, err := middleware.CurrentSellerID(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Forbidden: Seller store not found"})
+	}
+	return h.saveVariants(c, &sellerID)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/services"
+	"context"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func sizeVariants(variants ...variantInput) variantsRequest {
+	return variantsRequest{
+		Options:  []model.ProductOption{{Name: "Size", Values: []string{"M", "L"}}},
+		Variants: variants,
+	}
+}
+
+func TestBuildVariantsValidation(t *testing.T) {
+	existing := model.ProductVariant{ID: primitive.NewObjectID(), SKU: "TS-M", Sold: 7}
+	product := model.Product{Image: "/uploads/shirt.jpg", Variants: []model.ProductVariant{existing}}
+	m := variantInput{SKU: "TS-M", Options: map[string]string{"Size": "M"}, Price: 100000, Stock: 2}
+	l := variantInput{SKU: "TS-L", Options: map[string]string{"Size": "L"}, Price: 110000, Stock: 3}
+	with := func(v variantInput, change func(*variantInput)) variantInput {
+		change(&v)
+		return v
+	}
+
+	invalid := []struct {
+		name string
+		req  variantsRequest
+	}{
+		{"variants without options", variantsRequest{Variants: []variantInput{m}}},
+		{"options without variants", sizeVariants()},
+		{"duplicate option value", variantsRequest{Options: []model.ProductOption{{Name: "Size", Values: []string{"M", "M"}}}, Variants: []variantInput{m}}},
+		{"duplicate SKU", sizeVariants(m, with(l, func(v *variantInput) { v.SKU = "ts-m" }))},
+		{"duplicate combination", sizeVariants(m, with(l, func(v *variantInput) { v.Options = map[string]string{"Size": "M"} }))},
+		{"unknown option value", sizeVariants(with(m, func(v *variantInput) { v.Options = map[string]string{"Size": "XL"} }))},
+		{"missing option", sizeVariants(with(m, func(v *variantInput) { v.Options = nil }))},
+		{"zero price", sizeVariants(with(m, func(v *variantInput) { v.Price = 0 }))},
+		{"negative stock", sizeVariants(with(m, func(v *variantInput) { v.Stock = -1 }))},
+		{"foreign image", sizeVariants(with(m, func(v *variantInput) { v.Image = "/uploads/other.jpg" }))},
+		{"foreign variant ID", sizeVariants(with(m, func(v *variantInput) { v.ID = primitive.NewObjectID().Hex() }))},
+	}
+	for _, tt := range invalid {
+		if _, _, err := buildVariants(product, tt.req); err == nil {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+
+	// Varian lama mempertahankan ID dan jumlah terjualnya; varian baru mendapat ID baru
+	options, variants, err := buildVariants(product, sizeVariants(
+		with(m, func(v *variantInput) { v.ID = existing.ID.Hex(); v.Image = product.Image }),
+		l,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options) != 1 || len(variants) != 2 {
+		t.Fatalf("options = %+v, variants = %+v", options, variants)
+	}
+	if variants[0].ID != existing.ID || variants[0].Sold != 7 {
+		t.Errorf("existing variant = %+v, want ID and sold kept", variants[0])
+	}
+	if variants[1].ID.IsZero() || variants[1].ID == existing.ID || variants[1].Sold != 0 {
+		t.Errorf("new variant = %+v, want a fresh ID", variants[1])
+	}
+}
+
+// variantProduct menyimpan produk env.sellerID dengan varian ukuran M (diskon 10%) dan L
+func (e *testEnv) variantProduct(t *testing.T) (model.Product, model.ProductVariant, model.ProductVariant) {
+	t.Helper()
+	id := e.product(t, e.sellerID, 1, 0)
+	_, variants, err := buildVariants(model.Product{}, sizeVariants(
+		variantInput{SKU: "TS-M", Options: map[string]string{"Size": "M"}, Price: 100000, Discount: 10, Stock: 2},
+		variantInput{SKU: "TS-L", Options: map[string]string{"Size": "L"}, Price: 110000, Stock: 3},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := []model.ProductOption{{Name: "Size", Values: []string{"M", "L"}}}
+	if err := e.repos.Products.SetVariants(context.Background(), id, nil, options, variants); err != nil {
+		t.Fatal(err)
+	}
+	product, err := e.repos.Products.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product, variants[0], variants[1]
+}
+
+func TestVariantSummaryUsesCheapestVariantAndTotalStock(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	product, _, _ := env.variantProduct(t)
+
+	if product.Price != 100000 || product.Discount != 10 || product.Stock != 5 {
+		t.Errorf("price = %d, discount = %d, stock = %d, want 100000, 10 and 5", product.Price, product.Discount, product.Stock)
+	}
+}
+
+func TestCheckoutChargesAndDecrementsTheChosenVariant(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	product, m, l := env.variantProduct(t)
+
+	item := orderItem(product.ID, 2)
+	status, body := env.do(t, fiber.MethodPost, "/checkout", orderBody(2*100000+testShippingRate, item))
+	if status != fiber.StatusBadRequest || body["message"] != "Please choose a variant of this product" {
+		t.Fatalf("without variant: status = %d, body = %v, want 400", status, body)
+	}
+
+	// Varian M didiskon 10%: 2 × 90.000
+	item.VariantID = m.ID
+	status, body = env.do(t, fiber.MethodPost, "/checkout", orderBody(2*90000+testShippingRate, item))
+	if status != fiber.StatusCreated {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	checkoutID, _ := primitive.ObjectIDFromHex(body["checkout_id"].(string))
+	orders, err := env.repos.Orders.ListByCheckout(context.Background(), checkoutID)
+	if err != nil || len(orders) != 1 || len(orders[0].Items) != 1 {
+		t.Fatalf("orders = %+v, err = %v", orders, err)
+	}
+	if got := orders[0].Items[0]; got.SKU != "TS-M" || got.Price != 90000 || got.Variant == "" {
+		t.Errorf("item = %+v, want SKU TS-M at 90000 with a variant label", got)
+	}
+
+	product, err = env.repos.Products.FindByID(context.Background(), product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stock := map[primitive.ObjectID]int{}
+	for _, variant := range product.Variants {
+		stock[variant.ID] = variant.Stock
+	}
+	if stock[m.ID] != 0 || stock[l.ID] != 3 || product.Stock != 3 {
+		t.Errorf("stock M = %d, L = %d, product = %d, want 0, 3 and 3", stock[m.ID], stock[l.ID], product.Stock)
+	}
+
+	// Stok varian M sudah habis meskipun varian L masih ada
+	status, body = env.do(t, fiber.MethodPost, "/checkout", orderBody(90000+testShippingRate, model.OrderItem{ProductID: product.ID, VariantID: m.ID, Quantity: 1}))
+	if status != fiber.StatusConflict || body["code"] != "out_of_stock" {
+		t.Errorf("status = %d, body = %v, want 409 out_of_stock", status, body)
+	}
+}
+
+func TestVariantSKUIsUniqueAcrossProducts(t *testing.T) {
+	env := newTestEnv(t, services.MockOutcomePending)
+	env.app.Put("/products/:id/variants", env.h.UpdateProductVariants)
+	env.variantProduct(t)
+	other := env.product(t, env.sellerID, 1, 0)
+
+	status, body := env.do(t, fiber.MethodPut, "/products/"+other.Hex()+"/variants", sizeVariants(
+		variantInput{SKU: "TS-M", Options: map[string]string{"Size": "M"}, Price: 100000, Stock: 1},
+	))
+	if status != fiber.StatusConflict {
+		t.Fatalf("status = %d, body = %v, want 409", status, body)
+	}
+}
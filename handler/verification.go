@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/repository"
+	"be_ecommerce/utils"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	verifyTokenExpiry    = 24 * time.Hour   // Token verifikasi berlaku 24 jam
+	verifyEmailCooldown  = 60 * time.Second // Jeda minimal antar pengiriman ulang
+	verifyEmailWindow    = 24 * time.Hour   // Jendela waktu untuk batas pengiriman
+	verifyEmailMaxPerDay = 5                // Maksimal email verifikasi dalam satu jendela
+)
+
+// sendVerificationEmail membuat token verifikasi baru, menyimpannya (dalam bentuk hash), lalu mengirimkannya ke email user
+func (h *Handler) sendVerificationEmail(ctx context.Context, user model.User) error {
+	token := utils.GenerateRandomToken(32)
+	now := time.Now()
+
+	// Hitung ulang jendela pembatasan jika sudah lewat
+	windowStart := user.VerifyEmailWindowStart
+	sendCount := user.VerifyEmailSendCount + 1
+	if now.Sub(windowStart) > verifyEmailWindow {
+		windowStart = now
+		sendCount = 1
+	}
+
+	err := h.repos.Users.Update(ctx, user.ID, nil, repository.Fields{
+		"verify_token_hash":         utils.HashToken(token),
+		"verify_token_expiry":       now.Add(verifyTokenExpiry),
+		"verify_email_sent_at":      now,
+		"verify_email_window_start": windowStart,
+		"verify_email_send_count":   sendCount,
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nYour email verification code: %s\n\nThe code expires in 24 hours.", user.Username, token)
+	return utils.SendEmail(user.Email, "Verify your email", body)
+}
+
+// VerifyEmail menandai email user sebagai terverifikasi jika token cocok
+func (h *Handler) VerifyEmail(c *fiber.Ctx) error {
+	var body struct {
+		Email string `json:"email"`
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Email == "" || body.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Email and token are required",
+		})
+	}
+
+	user, err := h.repos.Users.FindByEmail(context.Background(), body.Email)
+	if err != nil && err != repository.ErrNotFound {
+		log.Println("Database error while verifying email:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Server error",
+		})
+	}
+	if err != nil || user.VerifyTokenHash == "" ||
+		subtle.ConstantTimeCompare([]byte(utils.HashToken(body.Token)), []byte(user.VerifyTokenHash)) != 1 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid verification token",
+		})
+	}
+
+	if time.Now().After(user.VerifyTokenExpiry) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Verification token expired",
+		})
+	}
+
+	err = h.repos.Users.Update(context.Background(), user.ID, nil, repository.Fields{"email_verified": true},
+		"verify_token_hash", "verify_token_expiry")
+	if err != nil {
+		log.Println("Error marking email as verified:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to verify email",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Email verified successfully",
+	})
+}
+
+// ResendVerificationEmail mengirim ulang token verifikasi dengan pembatasan frekuensi
+func (h *Handler) ResendVerificationEmail(c *fiber.Ctx) error {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Email is required",
+		})
+	}
+
+	user, err := h.repos.Users.FindByEmail(context.Background(), body.Email)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Email not found",
+			})
+		}
+		log.Println("Database error while fetching user:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to fetch user data",
+		})
+	}
+
+	if user.EmailVerified {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Email already verified",
+		})
+	}
+
+	// Pembatasan: jeda antar pengiriman dan batas harian
+	now := time.Now()
+	if wait := verifyEmailCooldown - now.Sub(user.VerifyEmailSentAt); wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, fmt.Sprintf("%d", int(wait.Seconds())+1))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"message": "Please wait before requesting another verification email",
+		})
+	}
+	if now.Sub(user.VerifyEmailWindowStart) <= verifyEmailWindow && user.VerifyEmailSendCount >= verifyEmailMaxPerDay {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"message": "Too many verification emails requested, try again later",
+		})
+	}
+
+	if err := h.sendVerificationEmail(context.Background(), user); err != nil {
+		log.Println("Error sending verification email:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to send email",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Verification email sent",
+	})
+}
@@ -0,0 +1,162 @@
+// Package imaging memvalidasi gambar yang diunggah dan membuat rendition berukuran tetap.
+// Semua decoder dan encoder murni Go (tanpa cgo): JPEG, PNG, GIF dan WebP bisa diunggah,
+// sedangkan rendition selalu disimpan sebagai JPEG karena golang.org/x/image belum punya encoder WebP.
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	// Decoder yang didaftarkan ke image.Decode
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Batas gambar yang diterima
+const (
+	MaxFileSize  = 5 << 20 // 5 MB
+	MinDimension = 200     // Sisi terpendek minimal, agar rendition medium tidak pecah
+	MaxDimension = 6000    // Sisi terpanjang maksimal, mencegah decompression bomb
+	jpegQuality  = 85
+)
+
+// Ukuran rendition: sisi terpanjang dalam piksel. Gambar yang lebih kecil tidak diperbesar.
+const (
+	Thumbnail = "thumbnail"
+	Medium    = "medium"
+	Large     = "large"
+)
+
+var renditionSizes = []struct {
+	name string
+	size int
+}{
+	{Thumbnail, 200},
+	{Medium, 600},
+	{Large, 1200},
+}
+
+// Format yang diterima beserta ekstensi file aslinya, berdasarkan isi file (bukan nama atau header client)
+var allowedTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var (
+	ErrTooLarge          = fmt.Errorf("image must not be larger than %d MB", MaxFileSize>>20)
+	ErrUnsupportedFormat = errors.New("image must be a JPEG, PNG, GIF or WebP file")
+	ErrInvalidDimensions = fmt.Errorf("image must be between %d and %d pixels on each side", MinDimension, MaxDimension)
+	ErrCorrupt           = errors.New("image could not be decoded")
+)
+
+// Processed adalah gambar yang sudah divalidasi beserta rendition JPEG-nya
+type Processed struct {
+	Hash        string // SHA-256 isi file asli, dipakai sebagai nama file
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	Original    []byte
+	Renditions  map[string][]byte // Thumbnail, Medium, Large → JPEG
+}
+
+// Process memvalidasi ukuran file, format dan dimensi gambar, lalu membuat semua rendition
+func Process(data []byte) (Processed, error) {
+	var result Processed
+	if len(data) > MaxFileSize {
+		return result, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	extension, ok := allowedTypes[contentType]
+	if !ok {
+		return result, ErrUnsupportedFormat
+	}
+
+	// Dimensi dibaca dari header dulu agar gambar raksasa ditolak sebelum di-decode
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return result, ErrCorrupt
+	}
+	if !validDimensions(config.Width, config.Height) {
+		return result, ErrInvalidDimensions
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return result, ErrCorrupt
+	}
+
+	sum := sha256.Sum256(data)
+	result = Processed{
+		Hash:        hex.EncodeToString(sum[:]),
+		ContentType: contentType,
+		Extension:   extension,
+		Width:       config.Width,
+		Height:      config.Height,
+		Original:    data,
+		Renditions:  map[string][]byte{},
+	}
+	for _, rendition := range renditionSizes {
+		encoded, err := renderJPEG(img, rendition.size)
+		if err != nil {
+			return result, err
+		}
+		result.Renditions[rendition.name] = encoded
+	}
+	return result, nil
+}
+
+// RenditionNames mengembalikan nama semua rendition dari yang terkecil
+func RenditionNames() []string {
+	names := make([]string, 0, len(renditionSizes))
+	for _, rendition := range renditionSizes {
+		names = append(names, rendition.name)
+	}
+	return names
+}
+
+func validDimensions(width, height int) bool {
+	return width >= MinDimension && height >= MinDimension && width <= MaxDimension && height <= MaxDimension
+}
+
+// renderJPEG memperkecil img sehingga sisi terpanjangnya maksimal size, di atas latar putih
+// karena JPEG tidak mendukung transparansi
+func renderJPEG(img image.Image, size int) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, maxInt(1, height*size/width)
+		} else {
+			width, height = maxInt(1, width*size/height), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,110 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x%height, color.NRGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessCreatesRenditions(t *testing.T) {
+	data := pngImage(t, 1600, 800)
+	processed, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed.ContentType != "image/png" || processed.Extension != ".png" {
+		t.Errorf("type = %s %s, want image/png .png", processed.ContentType, processed.Extension)
+	}
+	if processed.Width != 1600 || processed.Height != 800 {
+		t.Errorf("size = %dx%d, want 1600x800", processed.Width, processed.Height)
+	}
+	if !bytes.Equal(processed.Original, data) {
+		t.Error("original was modified")
+	}
+
+	// Sisi terpanjang diperkecil ke ukuran rendition dengan rasio yang sama
+	want := map[string][2]int{Thumbnail: {200, 100}, Medium: {600, 300}, Large: {1200, 600}}
+	if len(processed.Renditions) != len(want) {
+		t.Fatalf("renditions = %d, want %d", len(processed.Renditions), len(want))
+	}
+	for name, size := range want {
+		config, err := jpeg.DecodeConfig(bytes.NewReader(processed.Renditions[name]))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if config.Width != size[0] || config.Height != size[1] {
+			t.Errorf("%s = %dx%d, want %dx%d", name, config.Width, config.Height, size[0], size[1])
+		}
+	}
+}
+
+func TestProcessDoesNotUpscale(t *testing.T) {
+	processed, err := Process(pngImage(t, 300, 400))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(processed.Renditions[Large]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 300 || config.Height != 400 {
+		t.Errorf("large = %dx%d, want the original 300x400", config.Width, config.Height)
+	}
+}
+
+func TestProcessHashIsContentAddressed(t *testing.T) {
+	a, err := Process(pngImage(t, 200, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Process(pngImage(t, 200, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Process(pngImage(t, 201, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Hash != b.Hash || a.Hash == c.Hash || len(a.Hash) != 64 {
+		t.Errorf("hashes = %s, %s, %s; want equal hashes only for equal content", a.Hash, b.Hash, c.Hash)
+	}
+}
+
+func TestProcessRejectsInvalidImages(t *testing.T) {
+	valid := pngImage(t, 200, 200)
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"too large", make([]byte, MaxFileSize+1), ErrTooLarge},
+		{"not an image", []byte("<html><body>hello</body></html>"), ErrUnsupportedFormat},
+		{"pdf", []byte("%PDF-1.4\n"), ErrUnsupportedFormat},
+		{"too small", pngImage(t, MinDimension-1, 500), ErrInvalidDimensions},
+		{"too big", pngImage(t, MaxDimension+1, MinDimension), ErrInvalidDimensions},
+		{"truncated", valid[:len(valid)/2], ErrCorrupt},
+		{"png signature only", valid[:16], ErrCorrupt},
+	}
+	for _, tt := range tests {
+		if _, err := Process(tt.data); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -2,7 +2,14 @@ package main
 
 import (
 	"be_ecommerce/config"
+	"be_ecommerce/handler"
+	"be_ecommerce/middleware"
+	"be_ecommerce/repository"
 	"be_ecommerce/router"
+	"be_ecommerce/scheduler"
+	"be_ecommerce/services"
+	"be_ecommerce/storage"
+	"context"
 	"log"
 	"os"
 
@@ -14,9 +21,42 @@ import (
 func main() {
 	// Initialize MongoDB connection
 	config.CreateDBConnection()
+	config.EnsureIndexes()
 
-	// Initialize Fiber app
-	app := fiber.New()
+	// Handler mengakses data lewat repository di atas database ecommerce
+	repos := repository.NewMongo(config.MongoClient.Database("ecommerce"))
+
+	// Payment gateway dipilih lewat PAYMENT_PROVIDER (midtrans atau mock)
+	payments, err := services.NewPaymentProvider()
+	if err != nil {
+		log.Fatalf("Failed to configure payment provider: %v", err)
+	}
+
+	// File unggahan disimpan lewat STORAGE_BACKEND (local atau s3)
+	stores, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure storage: %v", err)
+	}
+	h := handler.New(repos, payments, stores)
+
+	// Index pencarian produk ada di memori; jika gagal, GET /search kosong sampai rebuild berikutnya
+	if err := h.RebuildSearchIndex(context.Background()); err != nil {
+		log.Printf("Warning: failed to build search index: %v", err)
+	}
+
+	// Job terjadwal; aman dijalankan di beberapa instance karena dibagi lewat lease di MongoDB
+	jobs := scheduler.New(repos.Leases)
+	jobs.Add(h.PaymentExpiryJob())
+	jobs.Add(h.SearchReindexJob())
+	go jobs.Start(context.Background())
+
+	// Initialize Fiber app. Body sampai DefaultBodyLimit dibaca seperti biasa; body yang lebih besar
+	// di-stream dan hanya diterima route upload yang dibatasi middleware.MultipartLimit
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    middleware.DefaultBodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true, // Form besar diurai MultipartLimit setelah batasnya diperiksa
+	})
 
 	// Use logger middleware
 	app.Use(logger.New())
@@ -24,8 +64,11 @@ func main() {
 	// Use CORS middleware (can customize it in config/cors.go)
 	app.Use(cors.New()) // Default CORS settings
 
+	// Baca body request biasa sebelum route; harus terpasang sebelum SetupRoutes
+	app.Use(middleware.ReadBody(middleware.DefaultBodyLimit))
+
 	// Register routes
-	router.SetupRoutes(app)
+	router.SetupRoutes(app, h, repos)
 
 	// Start the server
 	port := os.Getenv("PORT")
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"be_ecommerce/repository"
+	"be_ecommerce/utils"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kunci c.Locals yang diisi oleh Protected
+const (
+	LocalUserID   = "user_id"
+	LocalRoles    = "roles"
+	LocalSellerID = "seller_id"
+
+	LocalEmailVerified = "email_verified"
+	LocalMFA           = "mfa"
+)
+
+// mfaRequiredRoles berisi role yang wajib login dengan 2FA, diatur lewat env REQUIRE_2FA_ROLES (contoh: "admin")
+var mfaRequiredRoles = parseRoleList(os.Getenv("REQUIRE_2FA_ROLES"))
+
+func parseRoleList(value string) map[string]bool {
+	roles := map[string]bool{}
+	for _, role := range strings.Split(value, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles[role] = true
+		}
+	}
+	return roles
+}
+
+// RoleRequiresMFA memeriksa apakah kebijakan mewajibkan 2FA untuk role tertentu
+func RoleRequiresMFA(role string) bool {
+	return mfaRequiredRoles[role]
+}
+
+// Protected memverifikasi JWT dari header Authorization, memastikan token belum dicabut,
+// lalu menyimpan user_id, roles, dan seller_id ke c.Locals untuk dipakai handler berikutnya
+func Protected(users repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authHeader := c.Get("Authorization")
+		if authHeader == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Authorization token is required",
+			})
+		}
+
+		// Hapus prefix "Bearer "
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+		if token == authHeader || token == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Invalid token format",
+			})
+		}
+
+		claims, err := utils.ValidateJWT(token)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Invalid or expired token",
+			})
+		}
+
+		userID, ok := claims["user_id"].(string)
+		objectID, err := primitive.ObjectIDFromHex(userID)
+		if !ok || err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Invalid token payload",
+			})
+		}
+
+		// Ambil kondisi user terbaru agar pencabutan token dan perubahan role langsung berlaku
+		user, err := users.FindByID(c.Context(), objectID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "User not found",
+			})
+		}
+
+		// Token dari versi lama (logout-all, perubahan role, suspend) ditolak
+		version, ok := claims["ver"].(float64)
+		if !ok || int(version) != user.TokenVersion {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Token has been revoked",
+			})
+		}
+
+		// Akun yang di-suspend tidak boleh melakukan aksi apa pun
+		if user.IsSuspended() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Account is suspended",
+				"reason":  user.Suspension.Reason,
+			})
+		}
+
+		c.Locals(LocalUserID, userID)
+		c.Locals(LocalRoles, user.Roles)
+		if user.SellerID != nil {
+			c.Locals(LocalSellerID, user.SellerID.Hex())
+		}
+		c.Locals(LocalEmailVerified, user.EmailVerified)
+		mfa, _ := claims["mfa"].(bool)
+		c.Locals(LocalMFA, mfa)
+
+		return c.Next()
+	}
+}
+
+// RequireRole hanya meneruskan request jika user memiliki salah satu role yang diberikan.
+// Jika kebijakan mewajibkan 2FA untuk role tersebut, sesi juga harus login dengan 2FA.
+// Harus dipasang setelah Protected.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		needsMFA := false
+		for _, role := range roles {
+			if !HasRole(c, role) {
+				continue
+			}
+			if !RoleRequiresMFA(role) || HasMFA(c) {
+				return c.Next()
+			}
+			needsMFA = true
+		}
+		if needsMFA {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message":             "Two-factor authentication is required for this role",
+				"two_factor_required": true,
+			})
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Forbidden: insufficient role",
+		})
+	}
+}
+
+// RequireVerifiedEmail hanya meneruskan request jika email user sudah diverifikasi.
+// Harus dipasang setelah Protected.
+func RequireVerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if verified, _ := c.Locals(LocalEmailVerified).(bool); !verified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Please verify your email address first",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// CurrentUserID mengembalikan ObjectID user yang sedang login
+func CurrentUserID(c *fiber.Ctx) (primitive.ObjectID, error) {
+	userID, ok := c.Locals(LocalUserID).(string)
+	if !ok || userID == "" {
+		return primitive.NilObjectID, errors.New("user ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(userID)
+}
+
+// CurrentSellerID mengembalikan seller_id milik user yang sedang login
+func CurrentSellerID(c *fiber.Ctx) (primitive.ObjectID, error) {
+	sellerID, ok := c.Locals(LocalSellerID).(string)
+	if !ok || sellerID == "" {
+		return primitive.NilObjectID, errors.New("seller ID not found in context")
+	}
+	return primitive.ObjectIDFromHex(sellerID)
+}
+
+// CurrentRoles mengembalikan daftar role user yang sedang login
+func CurrentRoles(c *fiber.Ctx) []string {
+	roles, _ := c.Locals(LocalRoles).([]string)
+	return roles
+}
+
+// HasMFA memeriksa apakah sesi saat ini login dengan 2FA
+func HasMFA(c *fiber.Ctx) bool {
+	mfa, _ := c.Locals(LocalMFA).(bool)
+	return mfa
+}
+
+// HasRole memeriksa apakah user yang sedang login memiliki role tertentu
+func HasRole(c *fiber.Ctx, role string) bool {
+	for _, r := range CurrentRoles(c) {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultBodyLimit adalah batas body request biasa (JSON, form tanpa file). Dipasang sebagai
+// fiber.Config.BodyLimit bersama StreamRequestBody, sehingga body yang lebih besar tidak dibaca
+// ke memori sebelum route-nya diketahui; hanya route dengan MultipartLimit yang menerimanya.
+const DefaultBodyLimit = 4 * 1024 * 1024
+
+// multipartFieldsLimit adalah ruang untuk field teks dan header multipart di luar isi file
+const multipartFieldsLimit = 1024 * 1024
+
+// localLargeBody menandai request yang body-nya melebihi DefaultBodyLimit dan belum dibaca
+const localLargeBody = "large_body"
+
+// ReadBody dipasang global sebelum semua route. Body sampai limit langsung dibaca ke memori, sama seperti
+// tanpa StreamRequestBody. Body yang lebih besar dibiarkan di koneksi untuk MultipartLimit, dan koneksinya
+// ditutup setelah respons karena sisa body bisa tidak terbaca (misalnya request ditolak Protected).
+func ReadBody(limit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := c.Request()
+		length := req.Header.ContentLength()
+		switch {
+		case !req.IsBodyStream() || (length >= 0 && length <= limit):
+			req.Body()
+			return c.Next()
+		case length < 0:
+			// Chunked: panjangnya baru diketahui setelah dibaca
+			body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(limit)+1))
+			if err == nil && len(body) <= limit {
+				req.SetBody(body)
+				return c.Next()
+			}
+		}
+		c.Context().SetConnectionClose()
+		c.Locals(localLargeBody, true)
+		return c.Next()
+	}
+}
+
+// RejectLargeBody dipasang setelah route upload; route lain menolak body yang melebihi batas ReadBody
+func RejectLargeBody(c *fiber.Ctx) error {
+	if large, _ := c.Locals(localLargeBody).(bool); large {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"message": "Request body is too large"})
+	}
+	return c.Next()
+}
+
+// MultipartLimit membatasi unggahan di satu route: paling banyak maxFiles file dengan ukuran maxFileSize
+// per file. Form diurai di sini (file besar ditulis sementara ke disk, bukan ke memori), sehingga handler
+// hanya menerima form yang sudah lolos batas. Body selain multipart tetap memakai DefaultBodyLimit.
+func MultipartLimit(maxFiles, maxFileSize int) fiber.Handler {
+	maxBody := maxFiles*maxFileSize + multipartFieldsLimit
+	return func(c *fiber.Ctx) error {
+		if !strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+			return RejectLargeBody(c)
+		}
+		if large, _ := c.Locals(localLargeBody).(bool); large {
+			length := c.Request().Header.ContentLength()
+			if length < 0 {
+				return c.Status(fiber.StatusLengthRequired).JSON(fiber.Map{"message": "Content-Length is required for large uploads"})
+			}
+			if length > maxBody {
+				return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+					"message": fmt.Sprintf("Upload at most %d files of %d MB each", maxFiles, maxFileSize>>20),
+				})
+			}
+		}
+
+		form, err := c.MultipartForm()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid form data"})
+		}
+		files := 0
+		for _, headers := range form.File {
+			for _, header := range headers {
+				files++
+				if header.Size > int64(maxFileSize) {
+					return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+						"message": fmt.Sprintf("Each file must not be larger than %d MB", maxFileSize>>20),
+					})
+				}
+			}
+		}
+		if files > maxFiles {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"message": fmt.Sprintf("At most %d files can be uploaded at once", maxFiles),
+			})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const testBodyLimit = 1024
+
+// newBodyLimitApp menyusun app seperti main dan router dengan batas kecil: POST /upload menerima
+// paling banyak 2 file 1 KB, route lain hanya body sampai testBodyLimit
+func newBodyLimitApp() *fiber.App {
+	app := fiber.New(fiber.Config{BodyLimit: testBodyLimit, StreamRequestBody: true, DisablePreParseMultipartForm: true})
+	app.Use(ReadBody(testBodyLimit))
+	app.Post("/upload", MultipartLimit(2, 1024), func(c *fiber.Ctx) error {
+		form, err := c.MultipartForm()
+		if err != nil {
+			return err
+		}
+		return c.SendString(strings.Repeat("f", len(form.File["files"])))
+	})
+	app.Use(RejectLargeBody)
+	app.Post("/echo", func(c *fiber.Ctx) error {
+		return c.Send(c.Body())
+	})
+	return app
+}
+
+func multipartBody(t *testing.T, sizes ...int) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, size := range sizes {
+		part, err := w.CreateFormFile("files", "photo.jpg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(bytes.Repeat([]byte{'x'}, size))
+	}
+	w.Close()
+	return &body, w.FormDataContentType()
+}
+
+func send(t *testing.T, app *fiber.App, path, contentType string, body io.Reader, chunked bool) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodPost, path, body)
+	req.Header.Set(fiber.HeaderContentType, contentType)
+	if chunked {
+		req.ContentLength = -1
+		req.TransferEncoding = []string{"chunked"}
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestReadBodyLimitsRegularRoutes(t *testing.T) {
+	app := newBodyLimitApp()
+	small := strings.Repeat("a", testBodyLimit)
+
+	if status, body := send(t, app, "/echo", fiber.MIMEApplicationJSON, strings.NewReader(small), false); status != fiber.StatusOK || body != small {
+		t.Errorf("body within limit: status = %d, echoed %d bytes", status, len(body))
+	}
+	if status, body := send(t, app, "/echo", fiber.MIMEApplicationJSON, strings.NewReader(small), true); status != fiber.StatusOK || body != small {
+		t.Errorf("chunked body within limit: status = %d, echoed %d bytes", status, len(body))
+	}
+	if status, _ := send(t, app, "/echo", fiber.MIMEApplicationJSON, strings.NewReader(small+"a"), false); status != fiber.StatusRequestEntityTooLarge {
+		t.Errorf("body over limit: status = %d, want 413", status)
+	}
+	if status, _ := send(t, app, "/echo", fiber.MIMEApplicationJSON, strings.NewReader(small+"a"), true); status != fiber.StatusRequestEntityTooLarge {
+		t.Errorf("chunked body over limit: status = %d, want 413", status)
+	}
+
+	// Multipart di route tanpa MultipartLimit tetap memakai batas global
+	body, contentType := multipartBody(t, 800, 800)
+	if status, _ := send(t, app, "/echo", contentType, body, false); status != fiber.StatusRequestEntityTooLarge {
+		t.Errorf("multipart on regular route: status = %d, want 413", status)
+	}
+}
+
+func TestMultipartLimit(t *testing.T) {
+	app := newBodyLimitApp()
+
+	body, contentType := multipartBody(t, 800, 800)
+	if status, resp := send(t, app, "/upload", contentType, body, false); status != fiber.StatusOK || resp != "ff" {
+		t.Errorf("two files over the global limit: status = %d, body = %q", status, resp)
+	}
+
+	body, contentType = multipartBody(t, 10, 10, 10)
+	if status, _ := send(t, app, "/upload", contentType, body, false); status != fiber.StatusRequestEntityTooLarge {
+		t.Errorf("too many files: status = %d, want 413", status)
+	}
+
+	body, contentType = multipartBody(t, 1025)
+	if status, _ := send(t, app, "/upload", contentType, body, false); status != fiber.StatusRequestEntityTooLarge {
+		t.Errorf("file over the per-file limit: status = %d, want 413", status)
+	}
+
+	body, contentType = multipartBody(t, 800, 800)
+	if status, _ := send(t, app, "/upload", contentType, body, true); status != fiber.StatusLengthRequired {
+		t.Errorf("chunked upload over the global limit: status = %d, want 411", status)
+	}
+}
@@ -2,6 +2,7 @@ package model
 
 type CartItem struct {
     ProductID   string `json:"product_id" bson:"product_id"`
+    VariantID   string `json:"variant_id,omitempty" bson:"variant_id,omitempty"` // Wajib untuk produk dengan varian
     ProductName string `json:"product_name" bson:"product_name"`
     Price       int    `json:"price" bson:"price"`
     Quantity    int    `json:"quantity" bson:"quantity"`
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Checkout adalah transaksi induk dari satu kali checkout pembeli.
+// Item dipecah menjadi satu Order per toko (OrderIDs), tetapi dibayar sekali lewat Midtrans
+// dengan PaymentReference sebagai order_id di Midtrans.
+type Checkout struct {
+	ID               primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID           primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	OrderIDs         []primitive.ObjectID `bson:"order_ids" json:"order_ids"`
+	Subtotal         int                  `bson:"subtotal" json:"subtotal"`
+	ShippingCost     int                  `bson:"shipping_cost" json:"shipping_cost"`
+	TotalAmount      int                  `bson:"total_amount" json:"total_amount"` // Jumlah TotalAmount semua order anak
+	ShippingAddress  string               `bson:"shipping_address" json:"shipping_address"`
+	Status           string               `bson:"status" json:"status"` // AwaitingPayment, Paid, Cancelled atau Refunded
+	PaymentReference string               `bson:"payment_reference,omitempty" json:"payment_reference,omitempty"`
+	PaymentToken     string               `bson:"payment_token,omitempty" json:"payment_token,omitempty"`
+	PaymentStatus    string               `bson:"payment_status,omitempty" json:"payment_status,omitempty"` // transaction_status terakhir dari Midtrans
+	PaymentDate      time.Time            `bson:"payment_date,omitempty" json:"payment_date"`
+	CreatedAt        time.Time            `bson:"created_at" json:"created_at"`
+}
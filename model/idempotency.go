@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Status IdempotencyRecord
+const (
+	IdempotencyProcessing = "processing" // Request pertama masih berjalan
+	IdempotencyCompleted  = "completed"  // Respons sudah disimpan dan diputar ulang untuk retry
+)
+
+// IdempotencyRecord menyimpan respons request dengan header Idempotency-Key
+// agar retry dengan key yang sama tidak membuat checkout atau tagihan ganda
+type IdempotencyRecord struct {
+	ID             string             `bson:"_id" json:"id"` // user_id + method + path + key
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	RequestHash    string             `bson:"request_hash" json:"request_hash"` // SHA-256 body request
+	Status         string             `bson:"status" json:"status"`
+	ResponseStatus int                `bson:"response_status,omitempty" json:"response_status,omitempty"`
+	ResponseType   string             `bson:"response_type,omitempty" json:"response_type,omitempty"`
+	ResponseBody   []byte             `bson:"response_body,omitempty" json:"response_body,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	LockedUntil    time.Time          `bson:"locked_until" json:"locked_until"` // Record processing yang melewati ini dianggap terbengkalai
+	ExpiresAt      time.Time          `bson:"expires_at" json:"expires_at"`     // Dihapus TTL index setelah masa retensi
+}
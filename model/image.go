@@ -0,0 +1,20 @@
+package model
+
+// ProductImage adalah satu gambar di galeri produk. File disimpan dengan nama hash isinya,
+// sehingga gambar yang sama yang diunggah ulang (oleh produk mana pun) memakai file yang sama.
+type ProductImage struct {
+	Hash       string            `json:"hash" bson:"hash"`
+	Original   string            `json:"original" bson:"original"`
+	Renditions map[string]string `json:"renditions" bson:"renditions"` // thumbnail, medium, large → path JPEG
+	Width      int               `json:"width" bson:"width"`
+	Height     int               `json:"height" bson:"height"`
+}
+
+// Paths mengembalikan semua path file gambar, termasuk rendition
+func (i ProductImage) Paths() []string {
+	paths := []string{i.Original}
+	for _, path := range i.Renditions {
+		paths = append(paths, path)
+	}
+	return paths
+}
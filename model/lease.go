@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Lease menandai instance server yang sedang memegang sebuah job terjadwal.
+// Instance lain hanya bisa mengambilnya setelah ExpiresAt lewat.
+type Lease struct {
+	Name      string    `bson:"_id" json:"name"`
+	Owner     string    `bson:"owner" json:"owner"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// LoginAttempt mencatat kegagalan berturut-turut untuk satu kunci (akun atau IP)
+type LoginAttempt struct {
+	Key         string    `json:"key" bson:"_id"` // Contoh: "login-email:user@mail.com", "login-ip:10.0.0.1"
+	Failures    int       `json:"failures" bson:"failures"`
+	LastFailure time.Time `json:"last_failure" bson:"last_failure"`
+	LockedUntil time.Time `json:"locked_until" bson:"locked_until"`
+	ExpiresAt   time.Time `json:"expires_at" bson:"expires_at"` // Dipakai TTL index untuk pembersihan otomatis
+}
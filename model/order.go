@@ -11,22 +11,89 @@ type Order struct {
 	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
 	SellerID	   primitive.ObjectID `bson:"seller_id" json:"seller_id"`
+	CheckoutID     primitive.ObjectID `bson:"checkout_id,omitempty" json:"checkout_id"` // Checkout induk; kosong untuk order lama
 	Items          []OrderItem        `bson:"items" json:"items"`
-	TotalAmount    int                `bson:"total_amount" json:"total_amount"`
+	Subtotal       int                `bson:"subtotal" json:"subtotal"`         // Jumlah harga item setelah diskon, dihitung server
+	TotalAmount    int                `bson:"total_amount" json:"total_amount"` // Subtotal + ShippingCost
 	ShippingCost   int                `bson:"shipping_cost" json:"shipping_cost"`
 	ShippingAddress string            `bson:"shipping_address" json:"shipping_address"`
-	Status string 					  `bson:"status" json:"status" validate:"oneof=Pending Processing Shipped Delivered"`
+	Status string 					  `bson:"status" json:"status" validate:"oneof=AwaitingPayment Paid Processing Shipped Delivered Completed Cancelled Refunded"`
+	StatusHistory  []StatusChange     `bson:"status_history,omitempty" json:"status_history"` // Append-only, urut dari yang terlama
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
 	PaymentDate time.Time 			  `bson:"payment_date,omitempty" json:"payment_date"`
 	PaymentToken   string             `bson:"payment_token,omitempty" json:"payment_token"`
+	CancelReason   string             `bson:"cancel_reason,omitempty" json:"cancel_reason,omitempty"`
+	RefundedAmount int                `bson:"refunded_amount,omitempty" json:"refunded_amount"` // Jumlah semua refund, termasuk yang gagal diproses gateway
+	Refunds        []Refund           `bson:"refunds,omitempty" json:"refunds"`
+	ArchivedAt     *time.Time         `bson:"archived_at,omitempty" json:"archived_at,omitempty"` // Diisi saat seller "menghapus" order; data tetap disimpan
+}
+
+// Status order. Transisi yang diizinkan per role ada di handler (orderTransitions).
+const (
+	OrderStatusAwaitingPayment = "AwaitingPayment"
+	OrderStatusPaid            = "Paid"
+	OrderStatusProcessing      = "Processing"
+	OrderStatusShipped         = "Shipped"
+	OrderStatusDelivered       = "Delivered"
+	OrderStatusCompleted       = "Completed"
+	OrderStatusCancelled       = "Cancelled"
+	OrderStatusRefunded        = "Refunded"
+
+	// Status lama sebelum lifecycle di atas; diperlakukan sama dengan AwaitingPayment dan Paid
+	OrderStatusLegacyPending   = "Pending"
+	OrderStatusLegacyConfirmed = "Confirmed"
+)
+
+// StatusChange adalah satu entri timeline perubahan status order
+type StatusChange struct {
+	From      string             `bson:"from,omitempty" json:"from,omitempty"` // Kosong untuk status awal
+	To        string             `bson:"to" json:"to"`
+	At        time.Time          `bson:"at" json:"at"`
+	ActorID   primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id"`
+	ActorRole string             `bson:"actor_role" json:"actor_role"` // customer, seller, admin atau system
+	Note      string             `bson:"note,omitempty" json:"note,omitempty"`
+}
+
+// Status refund
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed" // Gagal di payment gateway, bisa dicoba ulang oleh admin
+)
+
+// Refund adalah pengembalian dana sebagian atau seluruh order lewat payment gateway
+type Refund struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	Items     []RefundItem       `bson:"items,omitempty" json:"items"`
+	Shipping  int                `bson:"shipping,omitempty" json:"shipping"` // Ongkir yang ikut dikembalikan
+	Amount    int                `bson:"amount" json:"amount"`
+	Reason    string             `bson:"reason" json:"reason"`
+	Status    string             `bson:"status" json:"status"`
+	Error     string             `bson:"error,omitempty" json:"error,omitempty"`
+	ActorID   primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id"`
+	ActorRole string             `bson:"actor_role" json:"actor_role"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// RefundItem adalah jumlah barang dari satu OrderItem yang dananya dikembalikan
+type RefundItem struct {
+	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	VariantID primitive.ObjectID `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	Quantity  int                `bson:"quantity" json:"quantity"`
+	Amount    int                `bson:"amount" json:"amount"`
+	Restocked bool               `bson:"restocked" json:"restocked"` // Stok barang ini sudah dikembalikan ke produk
 }
 
 // OrderItem menyimpan item dalam sebuah order
 type OrderItem struct {
 	ProductID primitive.ObjectID `bson:"product_id,omitempty" json:"product_id"`
+	VariantID primitive.ObjectID `bson:"variant_id,omitempty" json:"variant_id,omitempty"` // Kosong untuk produk tanpa varian
+	SKU       string             `bson:"sku,omitempty" json:"sku,omitempty"`
+	Variant   string             `bson:"variant,omitempty" json:"variant,omitempty"` // Label varian saat checkout, contoh: "M / Merah"
 	Name      string             `bson:"name" json:"name"`
 	Quantity  int                `bson:"quantity" json:"quantity"`
-	Price     int                `bson:"price" json:"price"`
+	Price     int                `bson:"price" json:"price"`                           // Harga satuan setelah diskon saat checkout
+	Discount  int                `bson:"discount,omitempty" json:"discount,omitempty"` // Diskon (persen) yang diterapkan
 	SellerID  primitive.ObjectID `bson:"seller_id,omitempty" json:"seller_id"`
 	Product   *Product           `bson:"product,omitempty" json:"product"`  // Tambahkan informasi produk langsung di OrderItem
 }
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PaymentNotification menyimpan setiap notification dari payment gateway apa adanya untuk audit,
+// termasuk yang ditolak karena signature tidak valid
+type PaymentNotification struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Provider          string             `bson:"provider" json:"provider"`
+	PaymentReference  string             `bson:"payment_reference" json:"payment_reference"` // order_id di Midtrans
+	TransactionID     string             `bson:"transaction_id,omitempty" json:"transaction_id,omitempty"`
+	TransactionStatus string             `bson:"transaction_status,omitempty" json:"transaction_status,omitempty"`
+	FraudStatus       string             `bson:"fraud_status,omitempty" json:"fraud_status,omitempty"`
+	SignatureValid    bool               `bson:"signature_valid" json:"signature_valid"`
+	Result            string             `bson:"result" json:"result"` // Hasil pemrosesan, contoh: "paid", "ignored"
+	Raw               string             `bson:"raw" json:"raw"`       // Body request asli
+	ReceivedAt        time.Time          `bson:"received_at" json:"received_at"`
+}
@@ -9,9 +9,20 @@ type Product struct {
 	Price         int                `json:"price" bson:"price"`
 	Stock 		  int 				 `json:"stock" bson:"stock"`
 	Discount      int                `json:"discount" bson:"discount"`
-	Image         string             `json:"image" bson:"image"`
+	Image         string             `json:"image" bson:"image"` // Rendition medium gambar pertama di Images
+	Images        []ProductImage     `json:"images,omitempty" bson:"images,omitempty"`
 	Description   string             `json:"description" bson:"description"`
-	SellerID      primitive.ObjectID `json:"seller_id" bson:"seller_id"`
+	SellerID      primitive.ObjectID `json:"seller_id" bson:"seller_id"` // _id toko di koleksi stores
 	CategoryID    primitive.ObjectID `json:"category_id" bson:"category_id"`
 	SubCategoryID primitive.ObjectID `json:"sub_category_id" bson:"sub_category_id"`
+
+	// Ringkasan yang disimpan di produk agar bisa difilter dan diurutkan dengan index
+	Rating        float64            `json:"rating" bson:"rating"`   // Rata-rata rating review
+	ReviewCount   int                `json:"reviews" bson:"reviews"` // Jumlah review
+	Sold          int                `json:"sold" bson:"sold"`       // Unit terjual yang tidak dikembalikan ke stok
+
+	// Produk dengan varian (ukuran, warna, ...) dijual per varian. Price, Discount dan Stock produk
+	// menjadi ringkasan: harga varian termurah dan jumlah stok semua varian.
+	Options       []ProductOption    `json:"options,omitempty" bson:"options,omitempty"`
+	Variants      []ProductVariant   `json:"variants,omitempty" bson:"variants,omitempty"`
 }
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Status permintaan retur. Transisi yang diizinkan per role ada di handler (returnTransitions).
+const (
+	ReturnRequested = "Requested" // Dibuka pembeli, menunggu keputusan seller
+	ReturnApproved  = "Approved"  // Pembeli boleh mengirim barang kembali
+	ReturnRejected  = "Rejected"  // Ditolak seller; pembeli bisa eskalasi ke admin
+	ReturnEscalated = "Escalated" // Menunggu keputusan admin
+	ReturnShipped   = "Shipped"   // Barang sedang dikirim kembali ke seller
+	ReturnReceived  = "Received"  // Barang sudah diterima seller
+	ReturnRefunded  = "Refunded"  // Dana sudah di-refund lewat order
+	ReturnCancelled = "Cancelled" // Dibatalkan pembeli
+	ReturnClosed    = "Closed"    // Ditolak final oleh admin
+)
+
+// ReturnRequest adalah permintaan retur (RMA) untuk satu OrderItem setelah barang diterima
+type ReturnRequest struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrderID        primitive.ObjectID `bson:"order_id" json:"order_id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	SellerID       primitive.ObjectID `bson:"seller_id" json:"seller_id"`
+	ProductID      primitive.ObjectID `bson:"product_id" json:"product_id"`
+	VariantID      primitive.ObjectID `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	Quantity       int                `bson:"quantity" json:"quantity"`
+	Reason         string             `bson:"reason" json:"reason"`
+	Photos         []string           `bson:"photos" json:"photos"` // Path foto bukti dari pembeli
+	Status         string             `bson:"status" json:"status"`
+	SellerNote     string             `bson:"seller_note,omitempty" json:"seller_note,omitempty"`
+	Courier        string             `bson:"courier,omitempty" json:"courier,omitempty"`
+	TrackingNumber string             `bson:"tracking_number,omitempty" json:"tracking_number,omitempty"`
+	Escalation     *ReturnEscalation  `bson:"escalation,omitempty" json:"escalation,omitempty"`
+	StatusHistory  []StatusChange     `bson:"status_history" json:"status_history"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ReturnEscalation mencatat sengketa retur yang diteruskan ke admin
+type ReturnEscalation struct {
+	Reason     string             `bson:"reason" json:"reason"`
+	OpenedAt   time.Time          `bson:"opened_at" json:"opened_at"`
+	Resolution string             `bson:"resolution,omitempty" json:"resolution,omitempty"`
+	ResolvedBy primitive.ObjectID `bson:"resolved_by,omitempty" json:"resolved_by"`
+	ResolvedAt time.Time          `bson:"resolved_at,omitempty" json:"resolved_at"`
+}
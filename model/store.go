@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store adalah toko milik seller. products.seller_id, orders.seller_id dan users.seller_id
+// semuanya merujuk ke _id toko ini, bukan ke _id user pemiliknya.
+type Store struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	OwnerID     primitive.ObjectID `json:"owner_id" bson:"owner_id"`
+	Name        string             `json:"store_name" bson:"store_name"`
+	FullAddress string             `json:"full_address" bson:"full_address"`
+	Status      string             `json:"status" bson:"status"` // pending, approved, rejected
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// IsActive memeriksa apakah toko sudah disetujui admin
+func (s *Store) IsActive() bool {
+	return s.Status == "approved"
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken menyimpan refresh token (dalam bentuk hash) untuk satu sesi login
+type RefreshToken struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	TokenHash  string              `json:"-" bson:"token_hash"`
+	FamilyID   primitive.ObjectID  `json:"family_id" bson:"family_id"` // Sama untuk semua token hasil rotasi dari satu login
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+	ExpiresAt  time.Time           `json:"expires_at" bson:"expires_at"`
+	RevokedAt  *time.Time          `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	ReplacedBy *primitive.ObjectID `json:"replaced_by,omitempty" bson:"replaced_by,omitempty"`
+	MFA        bool                `json:"mfa" bson:"mfa"` // Sesi ini login dengan 2FA, diwariskan saat rotasi
+}
@@ -12,6 +12,13 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// RegisterRequest represents a user registration payload
+type RegisterRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
 // User represents the user schema for MongoDB
 type User struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"`
@@ -19,17 +26,55 @@ type User struct {
 	Email       string             `json:"email" bson:"email"`
 	Password    string             `json:"password" bson:"password"` // Tambahkan field Password
 	Roles       []string           `json:"roles" bson:"roles"`
-	SellerID    *primitive.ObjectID  `bson:"seller_id,omitempty" json:"seller_id,omitempty"`
+	SellerID    *primitive.ObjectID  `bson:"seller_id,omitempty" json:"seller_id,omitempty"` // _id toko di koleksi stores
 	StoreStatus *string            `json:"store_status,omitempty" bson:"store_status,omitempty"`
 	StoreInfo   *StoreInfo         `json:"store_info,omitempty" bson:"store_info,omitempty"`
-	ResetToken       string             `json:"reset_token,omitempty" bson:"reset_token,omitempty"`
-	ResetTokenExpiry time.Time          `json:"reset_token_expiry,omitempty" bson:"reset_token_expiry,omitempty"`
+	ResetToken       string             `json:"-" bson:"reset_token,omitempty"` // Disimpan dalam bentuk hash SHA-256
+	ResetTokenExpiry time.Time          `json:"-" bson:"reset_token_expiry,omitempty"`
+	ResetAttempts    int                `json:"-" bson:"reset_attempts,omitempty"` // Jumlah OTP salah untuk token saat ini
+	TokenVersion     int                `json:"-" bson:"token_version"` // Dinaikkan untuk mencabut semua access token lama
+	Suspension       *Suspension        `json:"suspension,omitempty" bson:"suspension,omitempty"`
+
+	ResetEmailSentAt      time.Time `json:"-" bson:"reset_email_sent_at,omitempty"`
+	ResetEmailWindowStart time.Time `json:"-" bson:"reset_email_window_start,omitempty"`
+	ResetEmailSendCount   int       `json:"-" bson:"reset_email_send_count,omitempty"`
+
+	EmailVerified          bool      `json:"email_verified" bson:"email_verified"`
+	VerifyTokenHash        string    `json:"-" bson:"verify_token_hash,omitempty"`
+	VerifyTokenExpiry      time.Time `json:"-" bson:"verify_token_expiry,omitempty"`
+	VerifyEmailSentAt      time.Time `json:"-" bson:"verify_email_sent_at,omitempty"`
+	VerifyEmailWindowStart time.Time `json:"-" bson:"verify_email_window_start,omitempty"`
+	VerifyEmailSendCount   int       `json:"-" bson:"verify_email_send_count,omitempty"`
+
+	TwoFactorEnabled   bool     `json:"two_factor_enabled" bson:"two_factor_enabled"`
+	TOTPSecret         string   `json:"-" bson:"totp_secret,omitempty"`
+	TOTPPendingSecret  string   `json:"-" bson:"totp_pending_secret,omitempty"` // Secret yang belum dikonfirmasi saat enrollment
+	TOTPLastStep       int64    `json:"-" bson:"totp_last_step,omitempty"`      // Mencegah kode yang sama dipakai dua kali
+	ChallengeVersion   int      `json:"-" bson:"challenge_version,omitempty"`   // Dinaikkan untuk membatalkan challenge token 2FA yang sudah terbit
+	RecoveryCodeHashes []string `json:"-" bson:"recovery_code_hashes,omitempty"` // Kode cadangan sekali pakai (bcrypt)
+}
+
+// Suspension menyimpan detail suspend akun oleh admin
+type Suspension struct {
+	Reason      string             `json:"reason" bson:"reason"`
+	SuspendedBy primitive.ObjectID `json:"suspended_by" bson:"suspended_by"`
+	SuspendedAt time.Time          `json:"suspended_at" bson:"suspended_at"`
+	ExpiresAt   *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // Kosong berarti suspend permanen
+}
+
+// IsSuspended memeriksa apakah user sedang dalam masa suspend
+func (u *User) IsSuspended() bool {
+	if u.Suspension == nil {
+		return false
+	}
+	return u.Suspension.ExpiresAt == nil || time.Now().Before(*u.Suspension.ExpiresAt)
 }
 type StoreInfo struct {
 	StoreName   string `json:"store_name" bson:"store_name"`
 	FullAddress string `json:"full_address" bson:"full_address"`
 	NIK         string `json:"nik" bson:"nik"`
 	PhotoSelfie string `json:"photo_selfie" bson:"photo_selfie"`
+	PhotoPath   string `json:"photo_path,omitempty" bson:"photo_path,omitempty"` // Lokasi file selfie hasil upload
 }
 
 // StoreInfo represents additional information for becoming a seller
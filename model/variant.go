@@ -0,0 +1,51 @@
+package model
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProductOption adalah jenis pilihan produk beserta nilainya, contoh: Ukuran S, M, L
+type ProductOption struct {
+	Name   string   `json:"name" bson:"name"`
+	Values []string `json:"values" bson:"values"`
+}
+
+// ProductVariant adalah satu kombinasi nilai option yang dijual dengan SKU, harga dan stoknya sendiri
+type ProductVariant struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
+	SKU      string             `json:"sku" bson:"sku"`
+	Options  map[string]string  `json:"options" bson:"options"` // Nama option → nilai
+	Price    int                `json:"price" bson:"price"`
+	Discount int                `json:"discount" bson:"discount"`
+	Stock    int                `json:"stock" bson:"stock"`
+	Image    string             `json:"image,omitempty" bson:"image,omitempty"`
+	Sold     int                `json:"sold" bson:"sold"`
+}
+
+// HasVariants memeriksa apakah produk dijual per varian
+func (p *Product) HasVariants() bool {
+	return len(p.Variants) > 0
+}
+
+// Variant mencari varian produk berdasarkan ID
+func (p *Product) Variant(id primitive.ObjectID) (ProductVariant, bool) {
+	for _, variant := range p.Variants {
+		if variant.ID == id {
+			return variant, true
+		}
+	}
+	return ProductVariant{}, false
+}
+
+// VariantLabel menggabungkan nilai option varian sesuai urutan option produk, contoh: "M / Merah"
+func (p *Product) VariantLabel(variant ProductVariant) string {
+	values := make([]string, 0, len(p.Options))
+	for _, option := range p.Options {
+		if value, ok := variant.Options[option.Name]; ok {
+			values = append(values, value)
+		}
+	}
+	return strings.Join(values, " / ")
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// NewMemory membuat semua repository yang menyimpan data di memori.
+// Cocok untuk pengujian handler tanpa MongoDB; data hilang saat proses berhenti.
+func NewMemory() Repositories {
+	users := &memoryUsers{}
+	categories := &memoryCategories{}
+	repos := Repositories{
+		Products:    &memoryProducts{users: users, categories: categories},
+		Orders:      &memoryOrders{},
+		Checkouts:   &memoryCheckouts{},
+		Payments:    &memoryPaymentNotifications{},
+		Returns:     &memoryReturns{},
+		Idempotency: &memoryIdempotency{},
+		Users:       users,
+		Carts:       &memoryCarts{carts: map[string]model.Cart{}},
+		Reviews:     &memoryReviews{},
+		Categories:  categories,
+		Leases:      &memoryLeases{},
+
+		Stores:        &memoryStores{},
+		Favorites:     &memoryFavorites{},
+		RefreshTokens: &memoryRefreshTokens{},
+		LoginAttempts: &memoryLoginAttempts{},
+	}
+
+	// Lease tidak ikut transaksi, sama seperti di MongoDB tidak pernah ditulis di dalam Tx
+	tx := &memoryTx{}
+	for _, r := range []interface{}{
+		repos.Products, repos.Orders, repos.Checkouts, repos.Payments, repos.Returns, repos.Idempotency,
+		repos.Users, repos.Carts, repos.Reviews, repos.Categories,
+		repos.Stores, repos.Favorites, repos.RefreshTokens, repos.LoginAttempts,
+	} {
+		tx.tables = append(tx.tables, r.(snapshotter))
+	}
+	repos.Tx = tx
+	return repos
+}
+
+// snapshotter menyalin isi repository memori agar bisa dikembalikan saat transaksi gagal
+type snapshotter interface {
+	snapshot() (restore func(), err error)
+}
+
+// memoryTx menjalankan transaksi satu per satu. Isi semua tabel disalin sebelum fn berjalan
+// dan dikembalikan jika fn gagal, sehingga perubahan yang sudah terjadi ikut dibatalkan seperti
+// abort transaksi MongoDB. Tulisan di luar Tx selama fn berjalan ikut tertimpa saat rollback.
+type memoryTx struct {
+	mu     sync.Mutex
+	tables []snapshotter
+}
+
+func (t *memoryTx) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	restores := make([]func(), 0, len(t.tables))
+	for _, table := range t.tables {
+		restore, err := table.snapshot()
+		if err != nil {
+			return err
+		}
+		restores = append(restores, restore)
+	}
+	if err := fn(ctx); err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+		return err
+	}
+	return nil
+}
+
+// table menyimpan dokumen berurutan sesuai waktu insert, sama seperti urutan natural MongoDB
+type table[T any] struct {
+	mu   sync.RWMutex
+	rows []T
+}
+
+func (t *table[T]) insert(doc T) error {
+	doc, err := clone(doc)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows = append(t.rows, doc)
+	return nil
+}
+
+func (t *table[T]) snapshot() (func(), error) {
+	t.mu.RLock()
+	rows := make([]T, len(t.rows))
+	for i := range t.rows {
+		doc, err := clone(t.rows[i])
+		if err != nil {
+			t.mu.RUnlock()
+			return nil, err
+		}
+		rows[i] = doc
+	}
+	t.mu.RUnlock()
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.rows = rows
+	}, nil
+}
+
+// first mengembalikan salinan dokumen pertama yang cocok
+func (t *table[T]) first(match func(*T) bool) (T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for i := range t.rows {
+		if match(&t.rows[i]) {
+			return clone(t.rows[i])
+		}
+	}
+	var zero T
+	return zero, ErrNotFound
+}
+
+// all mengembalikan salinan semua dokumen yang cocok
+func (t *table[T]) all(match func(*T) bool) ([]T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	result := []T{}
+	for i := range t.rows {
+		if match(&t.rows[i]) {
+			doc, err := clone(t.rows[i])
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, doc)
+		}
+	}
+	return result, nil
+}
+
+// update menjalankan fn pada dokumen pertama yang cocok
+func (t *table[T]) update(match func(*T) bool, fn func(*T) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.rows {
+		if match(&t.rows[i]) {
+			return fn(&t.rows[i])
+		}
+	}
+	return ErrNotFound
+}
+
+// remove menghapus dokumen pertama yang cocok
+func (t *table[T]) remove(match func(*T) bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.rows {
+		if match(&t.rows[i]) {
+			t.rows = append(t.rows[:i], t.rows[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// clone membuat salinan dalam lewat BSON agar pemanggil tidak bisa mengubah data tersimpan
+func clone[T any](doc T) (T, error) {
+	var out T
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return out, err
+	}
+	err = bson.Unmarshal(raw, &out)
+	return out, err
+}
+
+// applyFields meniru $set dan $unset MongoDB pada struct dengan nama field BSON,
+// termasuk path bertitik seperti "store_info.store_name"
+func applyFields[T any](doc *T, set Fields, unset ...string) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		return err
+	}
+
+	for path, value := range set {
+		parent, key := walkPath(m, path, true)
+		parent[key] = value
+	}
+	for _, path := range unset {
+		if parent, key := walkPath(m, path, false); parent != nil {
+			delete(parent, key)
+		}
+	}
+
+	raw, err = bson.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var out T
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		return err
+	}
+	*doc = out
+	return nil
+}
+
+// fieldValue membaca field dengan nama BSON (boleh bertitik); ok bernilai false jika field tidak ada
+func fieldValue[T any](doc *T, path string) (value interface{}, ok bool, err error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, false, err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		return nil, false, err
+	}
+	parent, key := walkPath(m, path, false)
+	if parent == nil {
+		return nil, false, nil
+	}
+	value, ok = parent[key]
+	return value, ok, nil
+}
+
+// fieldEquals meniru filter {path: expected} MongoDB: expected nil cocok dengan field yang tidak ada
+func fieldEquals[T any](doc *T, path string, expected interface{}) bool {
+	value, ok, err := fieldValue(doc, path)
+	if err != nil {
+		return false
+	}
+	if expected == nil || !ok {
+		return expected == nil && value == nil
+	}
+	// Bandingkan dalam bentuk BSON agar time.Time sama dengan primitive.DateTime, int dengan int32, dst.
+	a, errA := bson.Marshal(bson.D{{Key: "v", Value: value}})
+	b, errB := bson.Marshal(bson.D{{Key: "v", Value: expected}})
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+// incrementField meniru $inc MongoDB pada field angka; field yang belum ada dianggap 0
+func incrementField[T any](doc *T, path string, delta int) error {
+	value, _, err := fieldValue(doc, path)
+	if err != nil {
+		return err
+	}
+	var current int64
+	switch v := value.(type) {
+	case int32:
+		current = int64(v)
+	case int64:
+		current = v
+	case float64:
+		current = int64(v)
+	}
+	return applyFields(doc, Fields{path: current + int64(delta)})
+}
+
+// walkPath mengembalikan dokumen induk dan key terakhir dari path bertitik
+func walkPath(m bson.M, path string, create bool) (bson.M, string) {
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part].(bson.M)
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			next = bson.M{}
+			m[part] = next
+		}
+		m = next
+	}
+	return m, parts[len(parts)-1]
+}
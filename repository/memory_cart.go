@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"sync"
+)
+
+type memoryCarts struct {
+	mu    sync.RWMutex
+	carts map[string]model.Cart
+}
+
+func (r *memoryCarts) Get(ctx context.Context, userID string) (model.Cart, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cart, ok := r.carts[userID]
+	if !ok {
+		return model.Cart{}, ErrNotFound
+	}
+	return clone(cart)
+}
+
+func (r *memoryCarts) Save(ctx context.Context, cart model.Cart) error {
+	cart, err := clone(cart)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.carts[cart.UserID] = cart
+	return nil
+}
+
+func (r *memoryCarts) RemoveItem(ctx context.Context, userID, productID, variantID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cart, ok := r.carts[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	products := []model.CartItem{}
+	for _, item := range cart.Products {
+		if item.ProductID != productID || item.VariantID != variantID {
+			products = append(products, item)
+		}
+	}
+	if len(products) == len(cart.Products) {
+		return ErrNotFound
+	}
+	cart.Products = products
+	r.carts[userID] = cart
+	return nil
+}
+
+func (r *memoryCarts) Clear(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.carts, userID)
+	return nil
+}
+
+func (r *memoryCarts) snapshot() (func(), error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	carts := make(map[string]model.Cart, len(r.carts))
+	for userID, cart := range r.carts {
+		cart, err := clone(cart)
+		if err != nil {
+			return nil, err
+		}
+		carts[userID] = cart
+	}
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.carts = carts
+	}, nil
+}
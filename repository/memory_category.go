@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryCategories struct {
+	table[model.Category]
+}
+
+func (r *memoryCategories) Create(ctx context.Context, category *model.Category) error {
+	if category.ID.IsZero() {
+		category.ID = primitive.NewObjectID()
+	}
+	return r.insert(*category)
+}
+
+func (r *memoryCategories) FindByID(ctx context.Context, id primitive.ObjectID) (model.Category, error) {
+	return r.first(func(c *model.Category) bool { return c.ID == id })
+}
+
+func (r *memoryCategories) FindByName(ctx context.Context, name string) (model.Category, error) {
+	return r.first(func(c *model.Category) bool { return c.Name == name })
+}
+
+func (r *memoryCategories) List(ctx context.Context) ([]model.Category, error) {
+	return r.all(func(*model.Category) bool { return true })
+}
+
+func (r *memoryCategories) Rename(ctx context.Context, id primitive.ObjectID, name string) error {
+	return r.update(categoryByID(id), func(c *model.Category) error {
+		c.Name = name
+		return nil
+	})
+}
+
+func (r *memoryCategories) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.remove(categoryByID(id))
+}
+
+func (r *memoryCategories) AddSubCategory(ctx context.Context, categoryID primitive.ObjectID, sub model.SubCategory) error {
+	return r.update(categoryByID(categoryID), func(c *model.Category) error {
+		c.SubCategories = append(c.SubCategories, sub)
+		return nil
+	})
+}
+
+func (r *memoryCategories) RenameSubCategory(ctx context.Context, categoryID, subID primitive.ObjectID, name string) error {
+	return r.update(categoryByID(categoryID), func(c *model.Category) error {
+		for i := range c.SubCategories {
+			if c.SubCategories[i].ID == subID {
+				c.SubCategories[i].Name = name
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+}
+
+func (r *memoryCategories) RemoveSubCategory(ctx context.Context, categoryID, subID primitive.ObjectID) error {
+	return r.update(categoryByID(categoryID), func(c *model.Category) error {
+		subs := []model.SubCategory{}
+		for _, sub := range c.SubCategories {
+			if sub.ID != subID {
+				subs = append(subs, sub)
+			}
+		}
+		c.SubCategories = subs
+		return nil
+	})
+}
+
+func categoryByID(id primitive.ObjectID) func(*model.Category) bool {
+	return func(c *model.Category) bool { return c.ID == id }
+}
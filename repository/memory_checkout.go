@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryCheckouts struct {
+	table[model.Checkout]
+}
+
+func (r *memoryCheckouts) Create(ctx context.Context, checkout *model.Checkout) error {
+	if checkout.ID.IsZero() {
+		checkout.ID = primitive.NewObjectID()
+	}
+	return r.insert(*checkout)
+}
+
+func (r *memoryCheckouts) FindByID(ctx context.Context, id primitive.ObjectID) (model.Checkout, error) {
+	return r.first(func(c *model.Checkout) bool { return c.ID == id })
+}
+
+func (r *memoryCheckouts) FindForUser(ctx context.Context, id, userID primitive.ObjectID) (model.Checkout, error) {
+	return r.first(func(c *model.Checkout) bool { return c.ID == id && c.UserID == userID })
+}
+
+func (r *memoryCheckouts) FindByPaymentReference(ctx context.Context, reference string) (model.Checkout, error) {
+	return r.first(func(c *model.Checkout) bool { return c.PaymentReference == reference })
+}
+
+func (r *memoryCheckouts) ListAwaitingPayment(ctx context.Context, createdBefore time.Time, limit int) ([]model.Checkout, error) {
+	checkouts, err := r.all(func(c *model.Checkout) bool {
+		return c.Status == model.OrderStatusAwaitingPayment && c.CreatedAt.Before(createdBefore)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(checkouts, func(i, j int) bool { return checkouts[i].CreatedAt.Before(checkouts[j].CreatedAt) })
+	if len(checkouts) > limit {
+		checkouts = checkouts[:limit]
+	}
+	return checkouts, nil
+}
+
+func (r *memoryCheckouts) Update(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return r.update(func(c *model.Checkout) bool { return c.ID == id }, func(c *model.Checkout) error {
+		return applyFields(c, fields)
+	})
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"sync"
+)
+
+type memoryFavorites struct {
+	table[model.Favorite]
+	add sync.Mutex // Cek dan insert di Add harus atomik
+}
+
+func (r *memoryFavorites) Get(ctx context.Context, userID string) (model.Favorite, error) {
+	return r.first(func(f *model.Favorite) bool { return f.UserID == userID })
+}
+
+func (r *memoryFavorites) Add(ctx context.Context, userID, productID string) (bool, error) {
+	r.add.Lock()
+	defer r.add.Unlock()
+
+	added := false
+	err := r.update(func(f *model.Favorite) bool { return f.UserID == userID }, func(f *model.Favorite) error {
+		for _, id := range f.ProductIDs {
+			if id == productID {
+				return nil
+			}
+		}
+		f.ProductIDs = append(f.ProductIDs, productID)
+		added = true
+		return nil
+	})
+	if err != ErrNotFound {
+		return added, err
+	}
+	return true, r.insert(model.Favorite{UserID: userID, ProductIDs: []string{productID}})
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryIdempotency struct {
+	table[model.IdempotencyRecord]
+	reserve sync.Mutex // Cek dan insert di Reserve harus atomik
+}
+
+func (r *memoryIdempotency) Reserve(ctx context.Context, record *model.IdempotencyRecord) (model.IdempotencyRecord, bool, error) {
+	r.reserve.Lock()
+	defer r.reserve.Unlock()
+
+	now := time.Now()
+	existing, err := r.first(func(rec *model.IdempotencyRecord) bool { return rec.ID == record.ID })
+	if err == nil {
+		stale := !existing.ExpiresAt.After(now) ||
+			(existing.Status == model.IdempotencyProcessing && !existing.LockedUntil.After(now))
+		if !stale {
+			return existing, false, nil
+		}
+		if err := r.remove(func(rec *model.IdempotencyRecord) bool { return rec.ID == record.ID }); err != nil {
+			return existing, false, err
+		}
+	} else if err != ErrNotFound {
+		return existing, false, err
+	}
+
+	return model.IdempotencyRecord{}, true, r.insert(*record)
+}
+
+// isReservation mencocokkan reservasi processing tertentu, bukan reservasi baru yang mengambil alih key yang sama
+func isReservation(id string, createdAt time.Time) func(*model.IdempotencyRecord) bool {
+	return func(rec *model.IdempotencyRecord) bool {
+		return rec.ID == id && rec.CreatedAt.Equal(createdAt) && rec.Status == model.IdempotencyProcessing
+	}
+}
+
+func (r *memoryIdempotency) Extend(ctx context.Context, id string, createdAt, lockedUntil time.Time) error {
+	return r.update(isReservation(id, createdAt), func(rec *model.IdempotencyRecord) error {
+		rec.LockedUntil = lockedUntil
+		return nil
+	})
+}
+
+func (r *memoryIdempotency) Complete(ctx context.Context, id string, createdAt time.Time, status int, contentType string, body []byte) error {
+	return r.update(isReservation(id, createdAt), func(rec *model.IdempotencyRecord) error {
+		rec.Status = model.IdempotencyCompleted
+		rec.ResponseStatus = status
+		rec.ResponseType = contentType
+		rec.ResponseBody = append([]byte(nil), body...)
+		return nil
+	})
+}
+
+func (r *memoryIdempotency) Release(ctx context.Context, id string, createdAt time.Time) error {
+	return r.remove(isReservation(id, createdAt))
+}
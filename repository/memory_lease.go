@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryLeases struct {
+	mu     sync.Mutex
+	leases map[string]model.Lease
+}
+
+func (r *memoryLeases) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if lease, ok := r.leases[name]; ok && lease.Owner != owner && lease.ExpiresAt.After(now) {
+		return false, nil
+	}
+	if r.leases == nil {
+		r.leases = map[string]model.Lease{}
+	}
+	r.leases[name] = model.Lease{Name: name, Owner: owner, ExpiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (r *memoryLeases) Release(ctx context.Context, name, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if lease, ok := r.leases[name]; ok && lease.Owner == owner {
+		delete(r.leases, name)
+	}
+	return nil
+}
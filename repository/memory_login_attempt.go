@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryLoginAttempts struct {
+	table[model.LoginAttempt]
+	record sync.Mutex // Cek dan insert di RecordFailure harus atomik
+}
+
+func (r *memoryLoginAttempts) LockedUntil(ctx context.Context, keys ...string) (time.Time, error) {
+	attempts, err := r.all(func(a *model.LoginAttempt) bool {
+		for _, key := range keys {
+			if a.Key == key {
+				return true
+			}
+		}
+		return false
+	})
+	return latestLock(attempts), err
+}
+
+func (r *memoryLoginAttempts) RecordFailure(ctx context.Context, key string, now, windowStart, expiresAt time.Time) (model.LoginAttempt, error) {
+	r.record.Lock()
+	defer r.record.Unlock()
+
+	var attempt model.LoginAttempt
+	err := r.update(func(a *model.LoginAttempt) bool { return a.Key == key }, func(a *model.LoginAttempt) error {
+		if a.LastFailure.Before(windowStart) {
+			a.Failures = 0
+		}
+		a.Failures++
+		a.LastFailure, a.ExpiresAt = now, expiresAt
+		attempt = *a
+		return nil
+	})
+	if err != ErrNotFound {
+		return attempt, err
+	}
+	attempt = model.LoginAttempt{Key: key, Failures: 1, LastFailure: now, ExpiresAt: expiresAt}
+	return attempt, r.insert(attempt)
+}
+
+func (r *memoryLoginAttempts) Lock(ctx context.Context, key string, until time.Time) error {
+	return r.update(func(a *model.LoginAttempt) bool { return a.Key == key }, func(a *model.LoginAttempt) error {
+		if until.After(a.LockedUntil) {
+			a.LockedUntil = until
+		}
+		return nil
+	})
+}
+
+func (r *memoryLoginAttempts) Reset(ctx context.Context, keys ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rows := r.rows[:0]
+	for _, attempt := range r.rows {
+		keep := true
+		for _, key := range keys {
+			if attempt.Key == key {
+				keep = false
+			}
+		}
+		if keep {
+			rows = append(rows, attempt)
+		}
+	}
+	r.rows = rows
+	return nil
+}
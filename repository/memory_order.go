@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryOrders struct {
+	table[model.Order]
+}
+
+func (r *memoryOrders) Create(ctx context.Context, order *model.Order) error {
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	return r.insert(*order)
+}
+
+func (r *memoryOrders) FindByID(ctx context.Context, id primitive.ObjectID) (model.Order, error) {
+	return r.first(func(o *model.Order) bool { return o.ID == id })
+}
+
+func (r *memoryOrders) FindForSeller(ctx context.Context, id, sellerID primitive.ObjectID) (model.Order, error) {
+	return r.first(sellerOrder(id, sellerID))
+}
+
+func (r *memoryOrders) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]model.Order, error) {
+	return r.all(func(o *model.Order) bool { return o.UserID == userID })
+}
+
+func (r *memoryOrders) ListBySeller(ctx context.Context, sellerID primitive.ObjectID) ([]model.Order, error) {
+	return r.all(func(o *model.Order) bool { return o.SellerID == sellerID && o.ArchivedAt == nil })
+}
+
+func (r *memoryOrders) ListByCheckout(ctx context.Context, checkoutID primitive.ObjectID) ([]model.Order, error) {
+	return r.all(func(o *model.Order) bool { return o.CheckoutID == checkoutID })
+}
+
+func (r *memoryOrders) Update(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return r.update(func(o *model.Order) bool { return o.ID == id }, func(o *model.Order) error {
+		return applyFields(o, fields)
+	})
+}
+
+func (r *memoryOrders) UpdateForSeller(ctx context.Context, id, sellerID primitive.ObjectID, fields Fields) error {
+	return r.update(sellerOrder(id, sellerID), func(o *model.Order) error {
+		return applyFields(o, fields)
+	})
+}
+
+func (r *memoryOrders) ArchiveForSeller(ctx context.Context, id, sellerID primitive.ObjectID) error {
+	match := func(o *model.Order) bool { return sellerOrder(id, sellerID)(o) && o.ArchivedAt == nil }
+	return r.update(match, func(o *model.Order) error {
+		now := time.Now()
+		o.ArchivedAt = &now
+		return nil
+	})
+}
+
+func (r *memoryOrders) AddRefund(ctx context.Context, id primitive.ObjectID, refundedBefore int, refund model.Refund) error {
+	match := func(o *model.Order) bool { return o.ID == id && o.RefundedAmount == refundedBefore }
+	return r.update(match, func(o *model.Order) error {
+		o.Refunds = append(o.Refunds, refund)
+		o.RefundedAmount += refund.Amount
+		return nil
+	})
+}
+
+func (r *memoryOrders) UpdateRefund(ctx context.Context, id, refundID primitive.ObjectID, status, errMessage string) error {
+	found := false
+	err := r.update(func(o *model.Order) bool { return o.ID == id }, func(o *model.Order) error {
+		for i := range o.Refunds {
+			if o.Refunds[i].ID == refundID {
+				o.Refunds[i].Status = status
+				o.Refunds[i].Error = errMessage
+				found = true
+			}
+		}
+		return nil
+	})
+	if err == nil && !found {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *memoryOrders) SellerStats(ctx context.Context, sellerID primitive.ObjectID) (OrderStats, error) {
+	var stats OrderStats
+	orders, err := r.all(func(o *model.Order) bool { return o.SellerID == sellerID })
+	if err != nil {
+		return stats, err
+	}
+	for _, order := range orders {
+		stats.Revenue += order.TotalAmount
+		for _, item := range order.Items {
+			stats.ItemsSold += item.Quantity
+		}
+		for _, status := range pendingOrderStatuses {
+			if order.Status == status {
+				stats.PendingOrders++
+			}
+		}
+	}
+	return stats, nil
+}
+
+func sellerOrder(id, sellerID primitive.ObjectID) func(*model.Order) bool {
+	return func(o *model.Order) bool { return o.ID == id && o.SellerID == sellerID }
+}
+
+func (r *memoryOrders) Transition(ctx context.Context, id primitive.ObjectID, change model.StatusChange, fields Fields) error {
+	match := func(o *model.Order) bool { return o.ID == id && o.Status == change.From }
+	return r.update(match, func(o *model.Order) error {
+		if err := applyFields(o, fields); err != nil {
+			return err
+		}
+		o.Status = change.To
+		o.StatusHistory = append(o.StatusHistory, change)
+		return nil
+	})
+}
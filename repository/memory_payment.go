@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryPaymentNotifications struct {
+	table[model.PaymentNotification]
+}
+
+func (r *memoryPaymentNotifications) Create(ctx context.Context, notification *model.PaymentNotification) error {
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	return r.insert(*notification)
+}
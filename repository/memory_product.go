@@ -0,0 +1,314 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryProducts struct {
+	table[model.Product]
+	users      *memoryUsers
+	categories *memoryCategories
+}
+
+func (r *memoryProducts) FindByID(ctx context.Context, id primitive.ObjectID) (model.Product, error) {
+	return r.first(func(p *model.Product) bool { return p.ID == id })
+}
+
+func (r *memoryProducts) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Product, error) {
+	wanted := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	return r.all(func(p *model.Product) bool { return wanted[p.ID] })
+}
+
+func (r *memoryProducts) Find(ctx context.Context, filter ProductFilter) ([]model.Product, error) {
+	return r.all(func(p *model.Product) bool {
+		if filter.SellerID != nil && p.SellerID != *filter.SellerID {
+			return false
+		}
+		if filter.MaxPrice != nil && p.Price > *filter.MaxPrice {
+			return false
+		}
+		if filter.MinRating != nil && p.Rating <= *filter.MinRating {
+			return false
+		}
+		return filter.MinReviews == nil || p.ReviewCount > *filter.MinReviews
+	})
+}
+
+func (r *memoryProducts) Search(ctx context.Context, query ProductQuery) (ProductPage, error) {
+	page := ProductPage{Products: []bson.M{}}
+	suspended, err := r.suspendedStores()
+	if err != nil {
+		return page, err
+	}
+
+	terms := strings.Fields(strings.ToLower(query.Text))
+	var ids map[primitive.ObjectID]bool
+	if query.IDs != nil {
+		ids = map[primitive.ObjectID]bool{}
+		for _, id := range query.IDs {
+			ids[id] = true
+		}
+	}
+	products, err := r.all(func(p *model.Product) bool {
+		switch {
+		case suspended[p.SellerID],
+			ids != nil && !ids[p.ID],
+			query.SellerID != nil && p.SellerID != *query.SellerID,
+			query.CategoryID != nil && p.CategoryID != *query.CategoryID,
+			query.SubCategoryID != nil && p.SubCategoryID != *query.SubCategoryID,
+			query.MinPrice != nil && p.Price < *query.MinPrice,
+			query.MaxPrice != nil && p.Price > *query.MaxPrice,
+			query.DiscountOnly && p.Discount <= 0,
+			query.InStock && p.Stock <= 0,
+			query.MinRating != nil && p.Rating < *query.MinRating:
+			return false
+		}
+		return len(terms) == 0 || matchesAnyTerm(p, terms)
+	})
+	if err != nil {
+		return page, err
+	}
+	page.Total = int64(len(products))
+
+	field, dir := productSortKey(query.Sort)
+	key := func(p model.Product) ProductCursor {
+		return ProductCursor{Value: productSortValue(p, field), ID: p.ID}
+	}
+	// before melaporkan apakah a berada sebelum b dalam urutan (field, _id)
+	before := func(a, b ProductCursor) bool {
+		if a.Value != b.Value {
+			return (a.Value < b.Value) == (dir == 1)
+		}
+		if a.ID == b.ID {
+			return false
+		}
+		return (a.ID.Hex() < b.ID.Hex()) == (dir == 1)
+	}
+	sort.Slice(products, func(i, j int) bool { return before(key(products[i]), key(products[j])) })
+
+	if query.After != nil {
+		start := sort.Search(len(products), func(i int) bool { return before(*query.After, key(products[i])) })
+		products = products[start:]
+	}
+	if len(products) > query.Limit {
+		products = products[:query.Limit]
+		next := key(products[len(products)-1])
+		page.Next = &next
+	}
+
+	page.Products, err = r.withCategories(ctx, products)
+	return page, err
+}
+
+// matchesAnyTerm meniru $text MongoDB secara sederhana: cocok jika salah satu kata ada di nama atau deskripsi
+func matchesAnyTerm(p *model.Product, terms []string) bool {
+	text := strings.ToLower(p.Name + " " + p.Description)
+	for _, term := range terms {
+		if strings.Contains(text, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// productSortValue mengembalikan nilai field sort; untuk _id urutan ditentukan ID saja
+func productSortValue(p model.Product, field string) float64 {
+	switch field {
+	case "price":
+		return float64(p.Price)
+	case "rating":
+		return p.Rating
+	case "sold":
+		return float64(p.Sold)
+	}
+	return 0
+}
+
+// suspendedStores mengembalikan toko yang pemiliknya di-suspend; users.seller_id berisi _id toko
+func (r *memoryProducts) suspendedStores() (map[primitive.ObjectID]bool, error) {
+	suspended := map[primitive.ObjectID]bool{}
+	owners, err := r.users.all(func(u *model.User) bool { return u.SellerID != nil && u.IsSuspended() })
+	if err != nil {
+		return nil, err
+	}
+	for _, owner := range owners {
+		suspended[*owner.SellerID] = true
+	}
+	return suspended, nil
+}
+
+// withCategories mengubah produk menjadi dokumen BSON dengan objek category dan sub_category
+func (r *memoryProducts) withCategories(ctx context.Context, products []model.Product) ([]bson.M, error) {
+	result := []bson.M{}
+	for _, product := range products {
+		raw, err := bson.Marshal(product)
+		if err != nil {
+			return nil, err
+		}
+		var doc bson.M
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		if category, err := r.categories.FindByID(ctx, product.CategoryID); err == nil {
+			doc["category"] = category
+		}
+		if subCategory, err := r.categories.FindByID(ctx, product.SubCategoryID); err == nil {
+			doc["sub_category"] = subCategory
+		}
+		result = append(result, doc)
+	}
+	return result, nil
+}
+
+func (r *memoryProducts) Create(ctx context.Context, product *model.Product) error {
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+	return r.insert(*product)
+}
+
+func (r *memoryProducts) Update(ctx context.Context, id primitive.ObjectID, sellerID *primitive.ObjectID, fields Fields) error {
+	return r.update(ownedProduct(id, sellerID), func(p *model.Product) error {
+		return applyFields(p, fields)
+	})
+}
+
+func (r *memoryProducts) ImageInUse(ctx context.Context, hash string) (bool, error) {
+	products, err := r.all(func(p *model.Product) bool {
+		for _, image := range p.Images {
+			if image.Hash == hash {
+				return true
+			}
+		}
+		return false
+	})
+	return len(products) > 0, err
+}
+
+func (r *memoryProducts) SetVariants(ctx context.Context, id primitive.ObjectID, sellerID *primitive.ObjectID, options []model.ProductOption, variants []model.ProductVariant) error {
+	// Meniru unique index variants.sku di MongoDB
+	skus := map[string]bool{}
+	for _, variant := range variants {
+		skus[variant.SKU] = true
+	}
+	return r.update(ownedProduct(id, sellerID), func(p *model.Product) error {
+		for _, other := range r.rows {
+			for _, variant := range other.Variants {
+				if other.ID != id && skus[variant.SKU] {
+					return ErrDuplicateSKU
+				}
+			}
+		}
+		if len(variants) == 0 {
+			p.Options, p.Variants, p.Stock = nil, nil, 0
+			return nil
+		}
+		fields := variantSummary(variants)
+		fields["options"] = options
+		fields["variants"] = variants
+		return applyFields(p, fields)
+	})
+}
+
+func (r *memoryProducts) Delete(ctx context.Context, id primitive.ObjectID, sellerID *primitive.ObjectID) error {
+	return r.remove(ownedProduct(id, sellerID))
+}
+
+func ownedProduct(id primitive.ObjectID, sellerID *primitive.ObjectID) func(*model.Product) bool {
+	return func(p *model.Product) bool {
+		return p.ID == id && (sellerID == nil || p.SellerID == *sellerID)
+	}
+}
+
+// DecrementStock di memori selalu all-or-nothing: stok baru dikurangi jika semua produk cukup
+func (r *memoryProducts) DecrementStock(ctx context.Context, items []StockItem) error {
+	items = mergeStockItems(items)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	outOfStock := &OutOfStockError{}
+	rows := make([]*model.Product, len(items))
+	variants := make([]*model.ProductVariant, len(items))
+	for i, item := range items {
+		missing := OutOfStockItem{ProductID: item.ProductID, VariantID: item.VariantID, Requested: item.Quantity}
+		for j := range r.rows {
+			if r.rows[j].ID == item.ProductID {
+				rows[i] = &r.rows[j]
+				break
+			}
+		}
+		if rows[i] == nil {
+			outOfStock.Items = append(outOfStock.Items, missing)
+			continue
+		}
+		missing.Name = rows[i].Name
+		missing.Available = rows[i].Stock
+
+		if !item.VariantID.IsZero() {
+			variants[i] = findVariant(rows[i], item.VariantID)
+			missing.Available = 0
+			if variants[i] != nil {
+				missing.SKU = variants[i].SKU
+				missing.Available = variants[i].Stock
+			}
+		}
+		if (!item.VariantID.IsZero() && variants[i] == nil) || missing.Available < item.Quantity {
+			outOfStock.Items = append(outOfStock.Items, missing)
+		}
+	}
+	if len(outOfStock.Items) > 0 {
+		return outOfStock
+	}
+
+	for i, item := range items {
+		rows[i].Stock -= item.Quantity
+		rows[i].Sold += item.Quantity
+		if variants[i] != nil {
+			variants[i].Stock -= item.Quantity
+			variants[i].Sold += item.Quantity
+		}
+	}
+	return nil
+}
+
+// findVariant mengembalikan pointer ke varian di dalam produk agar bisa diubah langsung
+func findVariant(p *model.Product, id primitive.ObjectID) *model.ProductVariant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+func (r *memoryProducts) RestoreStock(ctx context.Context, items []StockItem) error {
+	for _, item := range mergeStockItems(items) {
+		err := r.update(func(p *model.Product) bool { return p.ID == item.ProductID }, func(p *model.Product) error {
+			if !item.VariantID.IsZero() {
+				variant := findVariant(p, item.VariantID)
+				if variant == nil {
+					return nil
+				}
+				variant.Stock += item.Quantity
+				variant.Sold -= item.Quantity
+			}
+			p.Stock += item.Quantity
+			p.Sold -= item.Quantity
+			return nil
+		})
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
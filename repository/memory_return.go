@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryReturns struct {
+	table[model.ReturnRequest]
+}
+
+func (r *memoryReturns) Create(ctx context.Context, ret *model.ReturnRequest) error {
+	if ret.ID.IsZero() {
+		ret.ID = primitive.NewObjectID()
+	}
+	return r.insert(*ret)
+}
+
+func (r *memoryReturns) FindByID(ctx context.Context, id primitive.ObjectID) (model.ReturnRequest, error) {
+	return r.first(func(ret *model.ReturnRequest) bool { return ret.ID == id })
+}
+
+func (r *memoryReturns) Find(ctx context.Context, filter ReturnFilter) ([]model.ReturnRequest, error) {
+	return r.all(func(ret *model.ReturnRequest) bool {
+		return (filter.OrderID == nil || ret.OrderID == *filter.OrderID) &&
+			(filter.UserID == nil || ret.UserID == *filter.UserID) &&
+			(filter.SellerID == nil || ret.SellerID == *filter.SellerID) &&
+			(filter.Status == "" || ret.Status == filter.Status)
+	})
+}
+
+func (r *memoryReturns) Transition(ctx context.Context, id primitive.ObjectID, change model.StatusChange, fields Fields) error {
+	match := func(ret *model.ReturnRequest) bool { return ret.ID == id && ret.Status == change.From }
+	return r.update(match, func(ret *model.ReturnRequest) error {
+		if err := applyFields(ret, fields); err != nil {
+			return err
+		}
+		ret.Status = change.To
+		ret.StatusHistory = append(ret.StatusHistory, change)
+		return nil
+	})
+}
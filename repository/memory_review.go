@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryReviews struct {
+	table[model.Review]
+}
+
+func (r *memoryReviews) Create(ctx context.Context, review *model.Review) error {
+	if review.ID.IsZero() {
+		review.ID = primitive.NewObjectID()
+	}
+	return r.insert(*review)
+}
+
+func (r *memoryReviews) FindByID(ctx context.Context, id primitive.ObjectID) (model.Review, error) {
+	return r.first(func(rv *model.Review) bool { return rv.ID == id })
+}
+
+func (r *memoryReviews) ListByProduct(ctx context.Context, productID primitive.ObjectID) ([]model.Review, error) {
+	return r.all(func(rv *model.Review) bool { return rv.ProductID == productID })
+}
+
+func (r *memoryReviews) RatingSummary(ctx context.Context, productID primitive.ObjectID) (float64, int, error) {
+	reviews, err := r.ListByProduct(ctx, productID)
+	if err != nil || len(reviews) == 0 {
+		return 0, 0, err
+	}
+	var total float64
+	for _, review := range reviews {
+		total += review.Rating
+	}
+	return total / float64(len(reviews)), len(reviews), nil
+}
+
+func (r *memoryReviews) Update(ctx context.Context, id, userID primitive.ObjectID, rating float64, comment string) error {
+	return r.update(func(rv *model.Review) bool { return rv.ID == id && rv.UserID == userID }, func(rv *model.Review) error {
+		rv.Rating = rating
+		rv.Comment = comment
+		return nil
+	})
+}
+
+func (r *memoryReviews) Delete(ctx context.Context, id primitive.ObjectID, userID *primitive.ObjectID) error {
+	return r.remove(func(rv *model.Review) bool {
+		return rv.ID == id && (userID == nil || rv.UserID == *userID)
+	})
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryStores struct {
+	table[model.Store]
+	save sync.Mutex // Cek dan insert di Save harus atomik
+}
+
+func (r *memoryStores) FindByID(ctx context.Context, id primitive.ObjectID) (model.Store, error) {
+	return r.first(func(s *model.Store) bool { return s.ID == id })
+}
+
+func (r *memoryStores) FindByIDOrOwner(ctx context.Context, id primitive.ObjectID) (model.Store, error) {
+	return r.first(func(s *model.Store) bool { return s.ID == id || s.OwnerID == id })
+}
+
+func (r *memoryStores) List(ctx context.Context) ([]model.Store, error) {
+	return r.all(func(*model.Store) bool { return true })
+}
+
+func (r *memoryStores) Save(ctx context.Context, store *model.Store) error {
+	r.save.Lock()
+	defer r.save.Unlock()
+
+	if store.ID.IsZero() {
+		store.ID = primitive.NewObjectID()
+	}
+	now := time.Now()
+	store.UpdatedAt = now
+	err := r.update(func(s *model.Store) bool { return s.ID == store.ID }, func(s *model.Store) error {
+		s.OwnerID, s.Name, s.FullAddress, s.Status, s.UpdatedAt = store.OwnerID, store.Name, store.FullAddress, store.Status, now
+		store.CreatedAt = s.CreatedAt
+		return nil
+	})
+	if err != ErrNotFound {
+		return err
+	}
+	store.CreatedAt = now
+	return r.insert(*store)
+}
+
+func (r *memoryStores) SetStatusByOwner(ctx context.Context, ownerID primitive.ObjectID, status string) error {
+	err := r.update(func(s *model.Store) bool { return s.OwnerID == ownerID }, func(s *model.Store) error {
+		s.Status, s.UpdatedAt = status, time.Now()
+		return nil
+	})
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
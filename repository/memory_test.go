@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryTxRollsBackOnError(t *testing.T) {
+	repos := NewMemory()
+	ctx := context.Background()
+	buyerID := primitive.NewObjectID()
+	product := model.Product{Name: "Product", Price: 1000, Stock: 5}
+	if err := repos.Products.Create(ctx, &product); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Carts.Save(ctx, model.Cart{UserID: "buyer"}); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("fail")
+	err := repos.Tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repos.Products.DecrementStock(ctx, []StockItem{{ProductID: product.ID, Quantity: 2}}); err != nil {
+			return err
+		}
+		if err := repos.Orders.Create(ctx, &model.Order{UserID: buyerID}); err != nil {
+			return err
+		}
+		if err := repos.Carts.Clear(ctx, "buyer"); err != nil {
+			return err
+		}
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("WithTransaction = %v, want %v", err, failed)
+	}
+
+	if got, _ := repos.Products.FindByID(ctx, product.ID); got.Stock != 5 || got.Sold != 0 {
+		t.Errorf("stock = %d, sold = %d, want 5 and 0", got.Stock, got.Sold)
+	}
+	if orders, _ := repos.Orders.ListByUser(ctx, buyerID); len(orders) != 0 {
+		t.Errorf("%d orders kept, want none", len(orders))
+	}
+	if _, err := repos.Carts.Get(ctx, "buyer"); err != nil {
+		t.Errorf("cart was not restored: %v", err)
+	}
+}
+
+func TestMemoryTxKeepsCommittedChanges(t *testing.T) {
+	repos := NewMemory()
+	ctx := context.Background()
+	product := model.Product{Name: "Product", Price: 1000, Stock: 5}
+	if err := repos.Products.Create(ctx, &product); err != nil {
+		t.Fatal(err)
+	}
+
+	err := repos.Tx.WithTransaction(ctx, func(ctx context.Context) error {
+		return repos.Products.DecrementStock(ctx, []StockItem{{ProductID: product.ID, Quantity: 2}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repos.Products.FindByID(ctx, product.ID); got.Stock != 3 {
+		t.Errorf("stock = %d, want 3", got.Stock)
+	}
+}
+
+func TestMemoryIdempotencyIgnoresTakenOverReservation(t *testing.T) {
+	repos := NewMemory()
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	stale := model.IdempotencyRecord{
+		ID: "key", Status: model.IdempotencyProcessing,
+		CreatedAt: now.Add(-2 * time.Minute), LockedUntil: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour),
+	}
+	if _, reserved, err := repos.Idempotency.Reserve(ctx, &stale); err != nil || !reserved {
+		t.Fatalf("Reserve = %v, %v", reserved, err)
+	}
+	fresh := model.IdempotencyRecord{
+		ID: "key", Status: model.IdempotencyProcessing,
+		CreatedAt: now, LockedUntil: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour),
+	}
+	if _, reserved, err := repos.Idempotency.Reserve(ctx, &fresh); err != nil || !reserved {
+		t.Fatalf("Reserve after lock timeout = %v, %v, want the key taken over", reserved, err)
+	}
+
+	// Request lama yang akhirnya selesai tidak boleh menimpa atau menghapus reservasi baru
+	if err := repos.Idempotency.Complete(ctx, "key", stale.CreatedAt, 201, "application/json", []byte("{}")); err != ErrNotFound {
+		t.Errorf("Complete of stale reservation = %v, want ErrNotFound", err)
+	}
+	if err := repos.Idempotency.Release(ctx, "key", stale.CreatedAt); err != ErrNotFound {
+		t.Errorf("Release of stale reservation = %v, want ErrNotFound", err)
+	}
+	if err := repos.Idempotency.Extend(ctx, "key", fresh.CreatedAt, now.Add(2*time.Minute)); err != nil {
+		t.Errorf("Extend = %v", err)
+	}
+	if err := repos.Idempotency.Complete(ctx, "key", fresh.CreatedAt, 201, "application/json", []byte("{}")); err != nil {
+		t.Errorf("Complete = %v", err)
+	}
+}
+
+func TestMemoryUsersRejectDuplicateEmail(t *testing.T) {
+	repos := NewMemory()
+	ctx := context.Background()
+
+	if err := repos.Users.Create(ctx, &model.User{Email: "budi@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Users.Create(ctx, &model.User{Email: "budi@example.com"}); err != ErrDuplicateEmail {
+		t.Errorf("err = %v, want ErrDuplicateEmail", err)
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryRefreshTokens struct {
+	table[model.RefreshToken]
+}
+
+func (r *memoryRefreshTokens) Create(ctx context.Context, token *model.RefreshToken) error {
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	return r.insert(*token)
+}
+
+func (r *memoryRefreshTokens) FindByHash(ctx context.Context, tokenHash string) (model.RefreshToken, error) {
+	return r.first(func(t *model.RefreshToken) bool { return t.TokenHash == tokenHash })
+}
+
+func (r *memoryRefreshTokens) Rotate(ctx context.Context, id, replacedBy primitive.ObjectID) error {
+	return r.update(func(t *model.RefreshToken) bool {
+		return t.ID == id && t.RevokedAt == nil
+	}, func(t *model.RefreshToken) error {
+		now := time.Now()
+		t.RevokedAt, t.ReplacedBy = &now, &replacedBy
+		return nil
+	})
+}
+
+func (r *memoryRefreshTokens) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	return r.update(func(t *model.RefreshToken) bool { return t.ID == id }, func(t *model.RefreshToken) error {
+		now := time.Now()
+		t.RevokedAt = &now
+		return nil
+	})
+}
+
+func (r *memoryRefreshTokens) RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	r.revokeAll(func(t *model.RefreshToken) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+func (r *memoryRefreshTokens) RevokeUser(ctx context.Context, userID primitive.ObjectID) error {
+	r.revokeAll(func(t *model.RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (r *memoryRefreshTokens) revokeAll(match func(*model.RefreshToken) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for i := range r.rows {
+		if match(&r.rows[i]) && r.rows[i].RevokedAt == nil {
+			revokedAt := now
+			r.rows[i].RevokedAt = &revokedAt
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/utils"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUsers struct {
+	table[model.User]
+}
+
+func (r *memoryUsers) FindByID(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	return r.first(func(u *model.User) bool { return u.ID == id })
+}
+
+func (r *memoryUsers) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	email = utils.NormalizeEmail(email)
+	return r.first(func(u *model.User) bool { return u.Email == email })
+}
+
+func (r *memoryUsers) Find(ctx context.Context, filter UserFilter) ([]model.User, error) {
+	return r.all(func(u *model.User) bool {
+		if filter.OrStoreStatus != "" && u.StoreStatus != nil && *u.StoreStatus == filter.OrStoreStatus {
+			return true
+		}
+		for _, role := range filter.AllRoles {
+			if !hasRole(u, role) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (r *memoryUsers) Create(ctx context.Context, user *model.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	doc, err := clone(*user)
+	if err != nil {
+		return err
+	}
+	// Sama seperti unique index users.email di MongoDB
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.rows {
+		if r.rows[i].Email == doc.Email {
+			return ErrDuplicateEmail
+		}
+	}
+	r.rows = append(r.rows, doc)
+	return nil
+}
+
+func (r *memoryUsers) Update(ctx context.Context, id primitive.ObjectID, roles []string, set Fields, unset ...string) error {
+	return r.update(userWithRoles(id, roles), func(u *model.User) error {
+		return applyFields(u, set, unset...)
+	})
+}
+
+func (r *memoryUsers) UpdateIf(ctx context.Context, id primitive.ObjectID, field string, expected interface{}, set Fields, unset ...string) error {
+	return r.update(func(u *model.User) bool {
+		return u.ID == id && fieldEquals(u, field, expected)
+	}, func(u *model.User) error {
+		return applyFields(u, set, unset...)
+	})
+}
+
+func (r *memoryUsers) Increment(ctx context.Context, id primitive.ObjectID, field string, delta int) error {
+	return r.update(func(u *model.User) bool { return u.ID == id }, func(u *model.User) error {
+		return incrementField(u, field, delta)
+	})
+}
+
+func (r *memoryUsers) AdvanceTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) error {
+	return r.update(func(u *model.User) bool {
+		return u.ID == id && u.TOTPLastStep < step
+	}, func(u *model.User) error {
+		u.TOTPLastStep = step
+		return nil
+	})
+}
+
+func (r *memoryUsers) RemoveRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) error {
+	return r.update(func(u *model.User) bool { return u.ID == id }, func(u *model.User) error {
+		for i, h := range u.RecoveryCodeHashes {
+			if h == hash {
+				u.RecoveryCodeHashes = append(u.RecoveryCodeHashes[:i:i], u.RecoveryCodeHashes[i+1:]...)
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+}
+
+func (r *memoryUsers) ConsumeResetAttempt(ctx context.Context, email string, limit int) (model.User, error) {
+	email = utils.NormalizeEmail(email)
+	var user model.User
+	err := r.update(func(u *model.User) bool {
+		return u.Email == email && u.ResetToken != "" && u.ResetAttempts < limit
+	}, func(u *model.User) error {
+		u.ResetAttempts++
+		var err error
+		user, err = clone(*u)
+		return err
+	})
+	if err != ErrNotFound {
+		return user, err
+	}
+	if _, err := r.first(func(u *model.User) bool { return u.Email == email && u.ResetToken != "" }); err == nil {
+		return user, ErrLimitReached
+	}
+	return user, ErrNotFound
+}
+
+func (r *memoryUsers) ReleaseResetAttempt(ctx context.Context, id primitive.ObjectID, tokenHash string) error {
+	return r.update(func(u *model.User) bool {
+		return u.ID == id && u.ResetToken == tokenHash
+	}, func(u *model.User) error {
+		u.ResetAttempts--
+		return nil
+	})
+}
+
+func (r *memoryUsers) Delete(ctx context.Context, id primitive.ObjectID, roles ...string) error {
+	return r.remove(userWithRoles(id, roles))
+}
+
+func userWithRoles(id primitive.ObjectID, roles []string) func(*model.User) bool {
+	return func(u *model.User) bool {
+		if u.ID != id {
+			return false
+		}
+		for _, role := range roles {
+			if !hasRole(u, role) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func hasRole(u *model.User, role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
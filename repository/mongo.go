@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewMongo membuat semua repository di atas database MongoDB
+func NewMongo(db *mongo.Database) Repositories {
+	return Repositories{
+		Products:    &mongoProducts{db: db, col: db.Collection("products")},
+		Orders:      &mongoOrders{col: db.Collection("orders")},
+		Checkouts:   &mongoCheckouts{col: db.Collection("checkouts")},
+		Payments:    &mongoPaymentNotifications{col: db.Collection("payment_notifications")},
+		Returns:     &mongoReturns{col: db.Collection("returns")},
+		Idempotency: &mongoIdempotency{col: db.Collection("idempotency_keys")},
+		Users:       &mongoUsers{col: db.Collection("users")},
+		Carts:       &mongoCarts{col: db.Collection("carts")},
+		Reviews:     &mongoReviews{col: db.Collection("reviews")},
+		Categories:  &mongoCategories{col: db.Collection("categories")},
+		Leases:      &mongoLeases{col: db.Collection("leases")},
+
+		Stores:        &mongoStores{col: db.Collection("stores")},
+		Favorites:     &mongoFavorites{col: db.Collection("favorites")},
+		RefreshTokens: &mongoRefreshTokens{col: db.Collection("refresh_tokens")},
+		LoginAttempts: &mongoLoginAttempts{col: db.Collection("login_attempts")},
+
+		Tx: &mongoTx{client: db.Client()},
+	}
+}
+
+// findOne men-decode satu dokumen dan mengubah mongo.ErrNoDocuments menjadi ErrNotFound
+func findOne(ctx context.Context, col *mongo.Collection, filter interface{}, out interface{}) error {
+	err := col.FindOne(ctx, filter).Decode(out)
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	return err
+}
+
+// findAll men-decode semua dokumen yang cocok ke out (pointer ke slice)
+func findAll(ctx context.Context, col *mongo.Collection, filter interface{}, out interface{}) error {
+	cursor, err := col.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, out)
+}
+
+// updateOne menjalankan update dan mengembalikan ErrNotFound jika tidak ada dokumen yang cocok
+func updateOne(ctx context.Context, col *mongo.Collection, filter, update interface{}) error {
+	result, err := col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// deleteOne menghapus dokumen dan mengembalikan ErrNotFound jika tidak ada yang terhapus
+func deleteOne(ctx context.Context, col *mongo.Collection, filter interface{}) error {
+	result, err := col.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func setFields(fields Fields) bson.M {
+	return bson.M{"$set": bson.M(fields)}
+}
+
+// setUnset membuat update $set dan $unset; bagian yang kosong tidak disertakan
+func setUnset(set Fields, unset []string) bson.M {
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = bson.M(set)
+	}
+	if len(unset) > 0 {
+		fields := bson.M{}
+		for _, field := range unset {
+			fields[field] = ""
+		}
+		update["$unset"] = fields
+	}
+	return update
+}
+
+type mongoTx struct {
+	client *mongo.Client
+}
+
+func (t *mongoTx) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
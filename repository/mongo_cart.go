@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCarts struct {
+	col *mongo.Collection
+}
+
+func (r *mongoCarts) Get(ctx context.Context, userID string) (model.Cart, error) {
+	var cart model.Cart
+	err := findOne(ctx, r.col, bson.M{"user_id": userID}, &cart)
+	return cart, err
+}
+
+func (r *mongoCarts) Save(ctx context.Context, cart model.Cart) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"user_id": cart.UserID},
+		bson.M{"$set": bson.M{"products": cart.Products}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *mongoCarts) RemoveItem(ctx context.Context, userID, productID, variantID string) error {
+	// variant_id null juga cocok dengan item lama yang tidak punya field variant_id
+	var variant interface{}
+	if variantID != "" {
+		variant = variantID
+	}
+	result, err := r.col.UpdateOne(ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$pull": bson.M{"products": bson.M{"product_id": productID, "variant_id": variant}}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoCarts) Clear(ctx context.Context, userID string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"user_id": userID})
+	return err
+}
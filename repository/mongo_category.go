@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoCategories struct {
+	col *mongo.Collection
+}
+
+func (r *mongoCategories) Create(ctx context.Context, category *model.Category) error {
+	if category.ID.IsZero() {
+		category.ID = primitive.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, category)
+	return err
+}
+
+func (r *mongoCategories) FindByID(ctx context.Context, id primitive.ObjectID) (model.Category, error) {
+	var category model.Category
+	err := findOne(ctx, r.col, bson.M{"_id": id}, &category)
+	return category, err
+}
+
+func (r *mongoCategories) FindByName(ctx context.Context, name string) (model.Category, error) {
+	var category model.Category
+	err := findOne(ctx, r.col, bson.M{"name": name}, &category)
+	return category, err
+}
+
+func (r *mongoCategories) List(ctx context.Context) ([]model.Category, error) {
+	categories := []model.Category{}
+	err := findAll(ctx, r.col, bson.M{}, &categories)
+	return categories, err
+}
+
+func (r *mongoCategories) Rename(ctx context.Context, id primitive.ObjectID, name string) error {
+	return updateOne(ctx, r.col, bson.M{"_id": id}, bson.M{"$set": bson.M{"name": name}})
+}
+
+func (r *mongoCategories) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoCategories) AddSubCategory(ctx context.Context, categoryID primitive.ObjectID, sub model.SubCategory) error {
+	return updateOne(ctx, r.col, bson.M{"_id": categoryID}, bson.M{"$push": bson.M{"sub_categories": sub}})
+}
+
+func (r *mongoCategories) RenameSubCategory(ctx context.Context, categoryID, subID primitive.ObjectID, name string) error {
+	return updateOne(ctx, r.col,
+		bson.M{"_id": categoryID, "sub_categories._id": subID},
+		bson.M{"$set": bson.M{"sub_categories.$.name": name}},
+	)
+}
+
+func (r *mongoCategories) RemoveSubCategory(ctx context.Context, categoryID, subID primitive.ObjectID) error {
+	return updateOne(ctx, r.col, bson.M{"_id": categoryID}, bson.M{
+		"$pull": bson.M{"sub_categories": bson.M{"_id": subID}},
+	})
+}
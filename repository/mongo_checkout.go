@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCheckouts struct {
+	col *mongo.Collection
+}
+
+func (r *mongoCheckouts) Create(ctx context.Context, checkout *model.Checkout) error {
+	if checkout.ID.IsZero() {
+		checkout.ID = primitive.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, checkout)
+	return err
+}
+
+func (r *mongoCheckouts) FindByID(ctx context.Context, id primitive.ObjectID) (model.Checkout, error) {
+	var checkout model.Checkout
+	err := findOne(ctx, r.col, bson.M{"_id": id}, &checkout)
+	return checkout, err
+}
+
+func (r *mongoCheckouts) FindForUser(ctx context.Context, id, userID primitive.ObjectID) (model.Checkout, error) {
+	var checkout model.Checkout
+	err := findOne(ctx, r.col, bson.M{"_id": id, "user_id": userID}, &checkout)
+	return checkout, err
+}
+
+func (r *mongoCheckouts) FindByPaymentReference(ctx context.Context, reference string) (model.Checkout, error) {
+	var checkout model.Checkout
+	err := findOne(ctx, r.col, bson.M{"payment_reference": reference}, &checkout)
+	return checkout, err
+}
+
+func (r *mongoCheckouts) ListAwaitingPayment(ctx context.Context, createdBefore time.Time, limit int) ([]model.Checkout, error) {
+	filter := bson.M{"status": model.OrderStatusAwaitingPayment, "created_at": bson.M{"$lt": createdBefore}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	checkouts := []model.Checkout{}
+	err = cursor.All(ctx, &checkouts)
+	return checkouts, err
+}
+
+func (r *mongoCheckouts) Update(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return updateOne(ctx, r.col, bson.M{"_id": id}, setFields(fields))
+}
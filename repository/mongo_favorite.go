@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoFavorites struct {
+	col *mongo.Collection
+}
+
+func (r *mongoFavorites) Get(ctx context.Context, userID string) (model.Favorite, error) {
+	var favorite model.Favorite
+	err := findOne(ctx, r.col, bson.M{"user_id": userID}, &favorite)
+	return favorite, err
+}
+
+func (r *mongoFavorites) Add(ctx context.Context, userID, productID string) (bool, error) {
+	result, err := r.col.UpdateOne(ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$addToSet": bson.M{"product_ids": productID}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0 || result.UpsertedCount > 0, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoIdempotency struct {
+	col *mongo.Collection
+}
+
+func (r *mongoIdempotency) Reserve(ctx context.Context, record *model.IdempotencyRecord) (model.IdempotencyRecord, bool, error) {
+	var existing model.IdempotencyRecord
+	_, err := r.col.InsertOne(ctx, record)
+	if err == nil {
+		return existing, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return existing, false, err
+	}
+
+	// TTL index MongoDB berjalan berkala, jadi record kedaluwarsa bisa masih ada
+	now := time.Now()
+	result, err := r.col.DeleteOne(ctx, bson.M{
+		"_id": record.ID,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$lte": now}},
+			bson.M{"status": model.IdempotencyProcessing, "locked_until": bson.M{"$lte": now}},
+		},
+	})
+	if err != nil {
+		return existing, false, err
+	}
+	if result.DeletedCount == 1 {
+		_, err = r.col.InsertOne(ctx, record)
+		if err == nil {
+			return existing, true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return existing, false, err
+		}
+	}
+
+	err = findOne(ctx, r.col, bson.M{"_id": record.ID}, &existing)
+	return existing, false, err
+}
+
+// reservationFilter mencocokkan reservasi processing tertentu, bukan reservasi baru yang mengambil alih key yang sama
+func reservationFilter(id string, createdAt time.Time) bson.M {
+	return bson.M{"_id": id, "created_at": createdAt, "status": model.IdempotencyProcessing}
+}
+
+func (r *mongoIdempotency) Extend(ctx context.Context, id string, createdAt, lockedUntil time.Time) error {
+	return updateOne(ctx, r.col, reservationFilter(id, createdAt), bson.M{"$set": bson.M{"locked_until": lockedUntil}})
+}
+
+func (r *mongoIdempotency) Complete(ctx context.Context, id string, createdAt time.Time, status int, contentType string, body []byte) error {
+	return updateOne(ctx, r.col, reservationFilter(id, createdAt), bson.M{"$set": bson.M{
+		"status":          model.IdempotencyCompleted,
+		"response_status": status,
+		"response_type":   contentType,
+		"response_body":   body,
+	}})
+}
+
+func (r *mongoIdempotency) Release(ctx context.Context, id string, createdAt time.Time) error {
+	return deleteOne(ctx, r.col, reservationFilter(id, createdAt))
+}
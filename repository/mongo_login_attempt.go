@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoLoginAttempts struct {
+	col *mongo.Collection
+}
+
+func (r *mongoLoginAttempts) LockedUntil(ctx context.Context, keys ...string) (time.Time, error) {
+	attempts := []model.LoginAttempt{}
+	err := findAll(ctx, r.col, bson.M{"_id": bson.M{"$in": keys}, "locked_until": bson.M{"$gt": time.Now()}}, &attempts)
+	return latestLock(attempts), err
+}
+
+func (r *mongoLoginAttempts) RecordFailure(ctx context.Context, key string, now, windowStart, expiresAt time.Time) (model.LoginAttempt, error) {
+	// Update pipeline membaca last_failure dan menaikkan failures dalam satu operasi atomik
+	update := bson.A{bson.M{"$set": bson.M{
+		"failures": bson.M{"$cond": bson.A{
+			bson.M{"$gte": bson.A{"$last_failure", windowStart}},
+			bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+			1,
+		}},
+		"last_failure": now,
+		"expires_at":   expiresAt,
+	}}}
+
+	var attempt model.LoginAttempt
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"_id": key}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempt)
+	return attempt, err
+}
+
+func (r *mongoLoginAttempts) Lock(ctx context.Context, key string, until time.Time) error {
+	return updateOne(ctx, r.col, bson.M{"_id": key}, bson.M{"$max": bson.M{"locked_until": until}})
+}
+
+func (r *mongoLoginAttempts) Reset(ctx context.Context, keys ...string) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	return err
+}
+
+// latestLock mengembalikan locked_until terlama
+func latestLock(attempts []model.LoginAttempt) time.Time {
+	var until time.Time
+	for _, attempt := range attempts {
+		if attempt.LockedUntil.After(until) {
+			until = attempt.LockedUntil
+		}
+	}
+	return until
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoOrders struct {
+	col *mongo.Collection
+}
+
+func (r *mongoOrders) Create(ctx context.Context, order *model.Order) error {
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, order)
+	return err
+}
+
+func (r *mongoOrders) FindByID(ctx context.Context, id primitive.ObjectID) (model.Order, error) {
+	var order model.Order
+	err := findOne(ctx, r.col, bson.M{"_id": id}, &order)
+	return order, err
+}
+
+func (r *mongoOrders) FindForSeller(ctx context.Context, id, sellerID primitive.ObjectID) (model.Order, error) {
+	var order model.Order
+	err := findOne(ctx, r.col, bson.M{"_id": id, "seller_id": sellerID}, &order)
+	return order, err
+}
+
+func (r *mongoOrders) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]model.Order, error) {
+	orders := []model.Order{}
+	err := findAll(ctx, r.col, bson.M{"user_id": userID}, &orders)
+	return orders, err
+}
+
+func (r *mongoOrders) ListBySeller(ctx context.Context, sellerID primitive.ObjectID) ([]model.Order, error) {
+	orders := []model.Order{}
+	err := findAll(ctx, r.col, bson.M{"seller_id": sellerID, "archived_at": bson.M{"$exists": false}}, &orders)
+	return orders, err
+}
+
+func (r *mongoOrders) ListByCheckout(ctx context.Context, checkoutID primitive.ObjectID) ([]model.Order, error) {
+	orders := []model.Order{}
+	err := findAll(ctx, r.col, bson.M{"checkout_id": checkoutID}, &orders)
+	return orders, err
+}
+
+func (r *mongoOrders) Update(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return updateOne(ctx, r.col, bson.M{"_id": id}, setFields(fields))
+}
+
+func (r *mongoOrders) UpdateForSeller(ctx context.Context, id, sellerID primitive.ObjectID, fields Fields) error {
+	return updateOne(ctx, r.col, bson.M{"_id": id, "seller_id": sellerID}, setFields(fields))
+}
+
+func (r *mongoOrders) ArchiveForSeller(ctx context.Context, id, sellerID primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "seller_id": sellerID, "archived_at": bson.M{"$exists": false}}
+	return updateOne(ctx, r.col, filter, setFields(Fields{"archived_at": time.Now()}))
+}
+
+func (r *mongoOrders) AddRefund(ctx context.Context, id primitive.ObjectID, refundedBefore int, refund model.Refund) error {
+	filter := bson.M{"_id": id, "refunded_amount": refundedBefore}
+	if refundedBefore == 0 {
+		// Order lama belum memiliki field refunded_amount
+		filter["refunded_amount"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	update := bson.M{
+		"$push": bson.M{"refunds": refund},
+		"$inc":  bson.M{"refunded_amount": refund.Amount},
+	}
+	return updateOne(ctx, r.col, filter, update)
+}
+
+func (r *mongoOrders) UpdateRefund(ctx context.Context, id, refundID primitive.ObjectID, status, errMessage string) error {
+	update := setFields(Fields{"refunds.$.status": status, "refunds.$.error": errMessage})
+	return updateOne(ctx, r.col, bson.M{"_id": id, "refunds._id": refundID}, update)
+}
+
+func (r *mongoOrders) SellerStats(ctx context.Context, sellerID primitive.ObjectID) (OrderStats, error) {
+	var stats OrderStats
+
+	// Jumlah barang terjual dan total pendapatan
+	cursor, err := r.col.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"seller_id": sellerID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      nil,
+			"revenue":  bson.M{"$sum": "$total_amount"},
+			"quantity": bson.M{"$sum": bson.M{"$sum": "$items.quantity"}},
+		}}},
+	})
+	if err != nil {
+		return stats, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Revenue  int `bson:"revenue"`
+		Quantity int `bson:"quantity"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return stats, err
+		}
+	}
+	stats.Revenue = result.Revenue
+	stats.ItemsSold = result.Quantity
+
+	pending, err := r.col.CountDocuments(ctx, bson.M{"seller_id": sellerID, "status": bson.M{"$in": pendingOrderStatuses}})
+	if err != nil {
+		return stats, err
+	}
+	stats.PendingOrders = int(pending)
+	return stats, nil
+}
+
+func (r *mongoOrders) Transition(ctx context.Context, id primitive.ObjectID, change model.StatusChange, fields Fields) error {
+	set := bson.M{"status": change.To}
+	for key, value := range fields {
+		set[key] = value
+	}
+	update := bson.M{"$set": set, "$push": bson.M{"status_history": change}}
+	return updateOne(ctx, r.col, bson.M{"_id": id, "status": change.From}, update)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoPaymentNotifications struct {
+	col *mongo.Collection
+}
+
+func (r *mongoPaymentNotifications) Create(ctx context.Context, notification *model.PaymentNotification) error {
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, notification)
+	return err
+}
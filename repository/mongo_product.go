@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// productSortField adalah field sementara di pipeline Search berisi rating/sold yang sudah diisi default
+const productSortField = "_sort_value"
+
+type mongoProducts struct {
+	db  *mongo.Database
+	col *mongo.Collection
+}
+
+func (r *mongoProducts) FindByID(ctx context.Context, id primitive.ObjectID) (model.Product, error) {
+	var product model.Product
+	err := findOne(ctx, r.col, bson.M{"_id": id}, &product)
+	return product, err
+}
+
+func (r *mongoProducts) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Product, error) {
+	products := []model.Product{}
+	err := findAll(ctx, r.col, bson.M{"_id": bson.M{"$in": ids}}, &products)
+	return products, err
+}
+
+func (r *mongoProducts) Find(ctx context.Context, filter ProductFilter) ([]model.Product, error) {
+	query := bson.M{}
+	if filter.SellerID != nil {
+		query["seller_id"] = *filter.SellerID
+	}
+	if filter.MaxPrice != nil {
+		query["price"] = bson.M{"$lte": *filter.MaxPrice}
+	}
+	if filter.MinRating != nil {
+		query["rating"] = bson.M{"$gt": *filter.MinRating}
+	}
+	if filter.MinReviews != nil {
+		query["reviews"] = bson.M{"$gt": *filter.MinReviews}
+	}
+
+	products := []model.Product{}
+	err := findAll(ctx, r.col, query, &products)
+	return products, err
+}
+
+// categoryStages menambahkan objek category dan sub_category ke setiap produk
+func categoryStages() mongo.Pipeline {
+	return mongo.Pipeline{
+		// Lookup kategori berdasarkan category_id
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "categories"},        // Koleksi yang di-lookup
+			{Key: "localField", Value: "category_id"}, // Field di koleksi "products"
+			{Key: "foreignField", Value: "_id"},       // Field di koleksi "categories"
+			{Key: "as", Value: "category"},            // Alias hasil lookup
+		}}},
+		// Lookup sub-kategori berdasarkan sub_category_id
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "categories"}, // Koleksi yang di-lookup
+			{Key: "localField", Value: "sub_category_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "sub_category"},
+		}}},
+		// Hilangkan array kategori dan sub-kategori (ubah menjadi objek tunggal)
+		{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$category"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+		{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$sub_category"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+	}
+}
+
+func (r *mongoProducts) Search(ctx context.Context, query ProductQuery) (ProductPage, error) {
+	page := ProductPage{Products: []bson.M{}}
+
+	// Produk dari toko yang pemiliknya di-suspend disaring lewat seller_id, bukan $lookup per produk
+	suspended, err := r.db.Collection("users").Distinct(ctx, "seller_id", bson.M{
+		"$and": bson.A{bson.M{"seller_id": bson.M{"$ne": nil}}, suspendedUserFilter("")},
+	})
+	if err != nil {
+		return page, err
+	}
+	if suspended == nil {
+		suspended = []interface{}{}
+	}
+
+	filter := bson.M{}
+	if query.Text != "" {
+		filter["$text"] = bson.M{"$search": query.Text}
+	}
+	ids := bson.M{}
+	if query.IDs != nil {
+		ids["$in"] = query.IDs
+		filter["_id"] = ids
+	}
+	seller := bson.M{"$nin": suspended}
+	if query.SellerID != nil {
+		seller["$eq"] = *query.SellerID
+	}
+	filter["seller_id"] = seller
+	if query.CategoryID != nil {
+		filter["category_id"] = *query.CategoryID
+	}
+	if query.SubCategoryID != nil {
+		filter["sub_category_id"] = *query.SubCategoryID
+	}
+	price := bson.M{}
+	if query.MinPrice != nil {
+		price["$gte"] = *query.MinPrice
+	}
+	if query.MaxPrice != nil {
+		price["$lte"] = *query.MaxPrice
+	}
+	if len(price) > 0 {
+		filter["price"] = price
+	}
+	if query.DiscountOnly {
+		filter["discount"] = bson.M{"$gt": 0}
+	}
+	if query.InStock {
+		filter["stock"] = bson.M{"$gt": 0}
+	}
+	if query.MinRating != nil {
+		filter["rating"] = bson.M{"$gte": *query.MinRating}
+	}
+
+	page.Total, err = r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return page, err
+	}
+
+	// Keyset pagination: ambil produk setelah cursor dalam urutan (field, _id)
+	field, dir := productSortKey(query.Sort)
+	op := "$lt"
+	if dir == 1 {
+		op = "$gt"
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+	key := field
+	if field == "rating" || field == "sold" {
+		// Produk lama mungkin belum punya rating/sold; nilainya dianggap 0, sama dengan nilai cursor
+		// dari numberValue, agar produk tersebut tidak terlewat atau terulang di halaman berikutnya
+		key = productSortField
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{
+			key: bson.M{"$ifNull": bson.A{"$" + field, 0}},
+		}}})
+	}
+	if after := query.After; after != nil {
+		match := bson.M{"_id": bson.M{op: after.ID}}
+		if field != "_id" {
+			match = bson.M{"$or": bson.A{
+				bson.M{key: bson.M{op: after.Value}},
+				bson.M{key: after.Value, "_id": bson.M{op: after.ID}},
+			}}
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: match}})
+	}
+	sort := bson.D{{Key: "_id", Value: dir}}
+	if field != "_id" {
+		sort = bson.D{{Key: key, Value: dir}, {Key: "_id", Value: dir}}
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: sort}},
+		bson.D{{Key: "$limit", Value: query.Limit + 1}}, // Satu produk ekstra untuk mengetahui ada halaman berikutnya
+	)
+	if key == productSortField {
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.M{productSortField: 0}}})
+	}
+	pipeline = append(pipeline, categoryStages()...)
+
+	cursor, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return page, err
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, &page.Products); err != nil {
+		return page, err
+	}
+
+	if len(page.Products) > query.Limit {
+		page.Products = page.Products[:query.Limit]
+		last := page.Products[len(page.Products)-1]
+		page.Next = &ProductCursor{Value: numberValue(last[field]), ID: last["_id"].(primitive.ObjectID)}
+	}
+	return page, nil
+}
+
+// numberValue mengubah angka hasil decode BSON (int32, int64 atau double) menjadi float64
+func numberValue(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func (r *mongoProducts) Create(ctx context.Context, product *model.Product) error {
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, product)
+	return err
+}
+
+func (r *mongoProducts) Update(ctx context.Context, id primitive.ObjectID, sellerID *primitive.ObjectID, fields Fields) error {
+	return updateOne(ctx, r.col, ownedFilter(id, "seller_id", sellerID), setFields(fields))
+}
+
+func (r *mongoProducts) ImageInUse(ctx context.Context, hash string) (bool, error) {
+	count, err := r.col.CountDocuments(ctx, bson.M{"images.hash": hash}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+func (r *mongoProducts) SetVariants(ctx context.Context, id primitive.ObjectID, sellerID *primitive.ObjectID, options []model.ProductOption, variants []model.ProductVariant) error {
+	update := bson.M{"$unset": bson.M{"options": "", "variants": ""}, "$set": bson.M{"stock": 0}}
+	if len(variants) > 0 {
+		fields := variantSummary(variants)
+		fields["options"] = options
+		fields["variants"] = variants
+		update = setFields(fields)
+	}
+	err := updateOne(ctx, r.col, ownedFilter(id, "seller_id", sellerID), update)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateSKU
+	}
+	return err
+}
+
+func (r *mongoProducts) Delete(ctx context.Context, id primitive.ObjectID, sellerID *primitive.ObjectID) error {
+	return deleteOne(ctx, r.col, ownedFilter(id, "seller_id", sellerID))
+}
+
+// ownedFilter membuat filter _id yang dibatasi pemilik jika owner tidak nil
+func ownedFilter(id primitive.ObjectID, ownerField string, owner *primitive.ObjectID) bson.M {
+	filter := bson.M{"_id": id}
+	if owner != nil {
+		filter[ownerField] = *owner
+	}
+	return filter
+}
+
+// suspendedUserFilter menghasilkan filter untuk user yang sedang di-suspend.
+// prefix dipakai jika dokumen user berada di field hasil $lookup, contoh "seller."
+func suspendedUserFilter(prefix string) bson.M {
+	return bson.M{
+		prefix + "suspension": bson.M{"$exists": true},
+		"$or": []bson.M{
+			{prefix + "suspension.expires_at": bson.M{"$exists": false}},
+			{prefix + "suspension.expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+}
+
+func (r *mongoProducts) DecrementStock(ctx context.Context, items []StockItem) error {
+	outOfStock := &OutOfStockError{}
+	for _, item := range mergeStockItems(items) {
+		filter := bson.M{"_id": item.ProductID, "stock": bson.M{"$gte": item.Quantity}}
+		inc := bson.M{"stock": -item.Quantity, "sold": item.Quantity}
+		if !item.VariantID.IsZero() {
+			// Stok varian yang menentukan; "variants.$" menunjuk varian yang cocok dengan $elemMatch
+			filter = bson.M{"_id": item.ProductID, "variants": bson.M{"$elemMatch": bson.M{"_id": item.VariantID, "stock": bson.M{"$gte": item.Quantity}}}}
+			inc["variants.$.stock"] = -item.Quantity
+			inc["variants.$.sold"] = item.Quantity
+		}
+		result, err := r.col.UpdateOne(ctx, filter, bson.M{"$inc": inc})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 1 {
+			continue
+		}
+
+		// Stok kurang atau produk/varian sudah dihapus; catat stok yang tersedia untuk pesan error
+		missing := OutOfStockItem{ProductID: item.ProductID, VariantID: item.VariantID, Requested: item.Quantity}
+		var product model.Product
+		if err := findOne(ctx, r.col, bson.M{"_id": item.ProductID}, &product); err == nil {
+			missing.Name = product.Name
+			missing.Available = product.Stock
+			if !item.VariantID.IsZero() {
+				variant, _ := product.Variant(item.VariantID)
+				missing.SKU = variant.SKU
+				missing.Available = variant.Stock
+			}
+		} else if err != ErrNotFound {
+			return err
+		}
+		outOfStock.Items = append(outOfStock.Items, missing)
+	}
+
+	if len(outOfStock.Items) > 0 {
+		return outOfStock
+	}
+	return nil
+}
+
+func (r *mongoProducts) RestoreStock(ctx context.Context, items []StockItem) error {
+	for _, item := range mergeStockItems(items) {
+		// Produk atau varian yang sudah dihapus dilewati
+		filter := bson.M{"_id": item.ProductID}
+		inc := bson.M{"stock": item.Quantity, "sold": -item.Quantity}
+		if !item.VariantID.IsZero() {
+			filter["variants._id"] = item.VariantID
+			inc["variants.$.stock"] = item.Quantity
+			inc["variants.$.sold"] = -item.Quantity
+		}
+		_, err := r.col.UpdateOne(ctx, filter, bson.M{"$inc": inc})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeStockItems menjumlahkan quantity untuk produk (atau varian) yang muncul lebih dari sekali
+func mergeStockItems(items []StockItem) []StockItem {
+	type key struct{ product, variant primitive.ObjectID }
+	merged := []StockItem{}
+	index := map[key]int{}
+	for _, item := range items {
+		k := key{item.ProductID, item.VariantID}
+		if i, ok := index[k]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[k] = len(merged)
+		merged = append(merged, item)
+	}
+	return merged
+}
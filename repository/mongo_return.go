@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoReturns struct {
+	col *mongo.Collection
+}
+
+func (r *mongoReturns) Create(ctx context.Context, ret *model.ReturnRequest) error {
+	if ret.ID.IsZero() {
+		ret.ID = primitive.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, ret)
+	return err
+}
+
+func (r *mongoReturns) FindByID(ctx context.Context, id primitive.ObjectID) (model.ReturnRequest, error) {
+	var ret model.ReturnRequest
+	err := findOne(ctx, r.col, bson.M{"_id": id}, &ret)
+	return ret, err
+}
+
+func (r *mongoReturns) Find(ctx context.Context, filter ReturnFilter) ([]model.ReturnRequest, error) {
+	query := bson.M{}
+	if filter.OrderID != nil {
+		query["order_id"] = *filter.OrderID
+	}
+	if filter.UserID != nil {
+		query["user_id"] = *filter.UserID
+	}
+	if filter.SellerID != nil {
+		query["seller_id"] = *filter.SellerID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+
+	returns := []model.ReturnRequest{}
+	err := findAll(ctx, r.col, query, &returns)
+	return returns, err
+}
+
+func (r *mongoReturns) Transition(ctx context.Context, id primitive.ObjectID, change model.StatusChange, fields Fields) error {
+	set := bson.M{"status": change.To}
+	for key, value := range fields {
+		set[key] = value
+	}
+	update := bson.M{"$set": set, "$push": bson.M{"status_history": change}}
+	return updateOne(ctx, r.col, bson.M{"_id": id, "status": change.From}, update)
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoReviews struct {
+	col *mongo.Collection
+}
+
+func (r *mongoReviews) Create(ctx context.Context, review *model.Review) error {
+	if review.ID.IsZero() {
+		review.ID = primitive.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, review)
+	return err
+}
+
+func (r *mongoReviews) FindByID(ctx context.Context, id primitive.ObjectID) (model.Review, error) {
+	var review model.Review
+	err := findOne(ctx, r.col, bson.M{"_id": id}, &review)
+	return review, err
+}
+
+func (r *mongoReviews) ListByProduct(ctx context.Context, productID primitive.ObjectID) ([]model.Review, error) {
+	reviews := []model.Review{}
+	err := findAll(ctx, r.col, bson.M{"product_id": productID}, &reviews)
+	return reviews, err
+}
+
+func (r *mongoReviews) RatingSummary(ctx context.Context, productID primitive.ObjectID) (float64, int, error) {
+	// Agregasi untuk menghitung rata-rata rating dan jumlah ulasan
+	cursor, err := r.col.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"product_id": productID}},
+		bson.M{"$group": bson.M{
+			"_id":          "$product_id",
+			"avg_rating":   bson.M{"$avg": "$rating"},
+			"review_count": bson.M{"$sum": 1},
+		}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		AvgRating   float64 `bson:"avg_rating"`
+		ReviewCount int     `bson:"review_count"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return 0, 0, err
+		}
+	}
+	return result.AvgRating, result.ReviewCount, cursor.Err()
+}
+
+func (r *mongoReviews) Update(ctx context.Context, id, userID primitive.ObjectID, rating float64, comment string) error {
+	return updateOne(ctx, r.col, bson.M{"_id": id, "user_id": userID}, bson.M{
+		"$set": bson.M{"rating": rating, "comment": comment},
+	})
+}
+
+func (r *mongoReviews) Delete(ctx context.Context, id primitive.ObjectID, userID *primitive.ObjectID) error {
+	return deleteOne(ctx, r.col, ownedFilter(id, "user_id", userID))
+}
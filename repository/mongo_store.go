@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoStores struct {
+	col *mongo.Collection
+}
+
+func (r *mongoStores) FindByID(ctx context.Context, id primitive.ObjectID) (model.Store, error) {
+	var store model.Store
+	err := findOne(ctx, r.col, bson.M{"_id": id}, &store)
+	return store, err
+}
+
+func (r *mongoStores) FindByIDOrOwner(ctx context.Context, id primitive.ObjectID) (model.Store, error) {
+	var store model.Store
+	err := findOne(ctx, r.col, bson.M{"$or": []bson.M{{"_id": id}, {"owner_id": id}}}, &store)
+	return store, err
+}
+
+func (r *mongoStores) List(ctx context.Context) ([]model.Store, error) {
+	stores := []model.Store{}
+	err := findAll(ctx, r.col, bson.M{}, &stores)
+	return stores, err
+}
+
+func (r *mongoStores) Save(ctx context.Context, store *model.Store) error {
+	if store.ID.IsZero() {
+		store.ID = primitive.NewObjectID()
+	}
+	now := time.Now()
+	store.UpdatedAt = now
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": store.ID}, bson.M{
+		"$set": bson.M{
+			"owner_id":     store.OwnerID,
+			"store_name":   store.Name,
+			"full_address": store.FullAddress,
+			"status":       store.Status,
+			"updated_at":   now,
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *mongoStores) SetStatusByOwner(ctx context.Context, ownerID primitive.ObjectID, status string) error {
+	// Seller lama yang belum punya dokumen toko bukan error
+	_, err := r.col.UpdateOne(ctx, bson.M{"owner_id": ownerID}, setFields(Fields{"status": status, "updated_at": time.Now()}))
+	return err
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoRefreshTokens struct {
+	col *mongo.Collection
+}
+
+// notRevoked mencocokkan token yang belum dicabut
+var notRevoked = bson.M{"$exists": false}
+
+func (r *mongoRefreshTokens) Create(ctx context.Context, token *model.RefreshToken) error {
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, token)
+	return err
+}
+
+func (r *mongoRefreshTokens) FindByHash(ctx context.Context, tokenHash string) (model.RefreshToken, error) {
+	var token model.RefreshToken
+	err := findOne(ctx, r.col, bson.M{"token_hash": tokenHash}, &token)
+	return token, err
+}
+
+func (r *mongoRefreshTokens) Rotate(ctx context.Context, id, replacedBy primitive.ObjectID) error {
+	return updateOne(ctx, r.col,
+		bson.M{"_id": id, "revoked_at": notRevoked},
+		setFields(Fields{"revoked_at": time.Now(), "replaced_by": replacedBy}),
+	)
+}
+
+func (r *mongoRefreshTokens) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	return updateOne(ctx, r.col, bson.M{"_id": id}, setFields(Fields{"revoked_at": time.Now()}))
+}
+
+func (r *mongoRefreshTokens) RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	return r.revokeMany(ctx, bson.M{"family_id": familyID, "revoked_at": notRevoked})
+}
+
+func (r *mongoRefreshTokens) RevokeUser(ctx context.Context, userID primitive.ObjectID) error {
+	return r.revokeMany(ctx, bson.M{"user_id": userID, "revoked_at": notRevoked})
+}
+
+func (r *mongoRefreshTokens) revokeMany(ctx context.Context, filter bson.M) error {
+	_, err := r.col.UpdateMany(ctx, filter, setFields(Fields{"revoked_at": time.Now()}))
+	return err
+}
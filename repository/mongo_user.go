@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"be_ecommerce/model"
+	"be_ecommerce/utils"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUsers struct {
+	col *mongo.Collection
+}
+
+func (r *mongoUsers) FindByID(ctx context.Context, id primitive.ObjectID) (model.User, error) {
+	var user model.User
+	err := findOne(ctx, r.col, bson.M{"_id": id}, &user)
+	return user, err
+}
+
+func (r *mongoUsers) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	var user model.User
+	err := findOne(ctx, r.col, bson.M{"email": utils.NormalizeEmail(email)}, &user)
+	return user, err
+}
+
+func (r *mongoUsers) Find(ctx context.Context, filter UserFilter) ([]model.User, error) {
+	query := bson.M{}
+	if len(filter.AllRoles) > 0 {
+		query["roles"] = bson.M{"$all": filter.AllRoles}
+	}
+	if filter.OrStoreStatus != "" {
+		query = bson.M{"$or": []bson.M{query, {"store_status": filter.OrStoreStatus}}}
+	}
+
+	users := []model.User{}
+	err := findAll(ctx, r.col, query, &users)
+	return users, err
+}
+
+func (r *mongoUsers) Create(ctx context.Context, user *model.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateEmail
+	}
+	return err
+}
+
+func (r *mongoUsers) Update(ctx context.Context, id primitive.ObjectID, roles []string, set Fields, unset ...string) error {
+	return updateOne(ctx, r.col, rolesFilter(id, roles), setUnset(set, unset))
+}
+
+func (r *mongoUsers) UpdateIf(ctx context.Context, id primitive.ObjectID, field string, expected interface{}, set Fields, unset ...string) error {
+	// Filter {field: nil} juga cocok dengan field yang belum ada
+	return updateOne(ctx, r.col, bson.M{"_id": id, field: expected}, setUnset(set, unset))
+}
+
+func (r *mongoUsers) Increment(ctx context.Context, id primitive.ObjectID, field string, delta int) error {
+	return updateOne(ctx, r.col, bson.M{"_id": id}, bson.M{"$inc": bson.M{field: delta}})
+}
+
+func (r *mongoUsers) AdvanceTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) error {
+	return updateOne(ctx, r.col,
+		bson.M{"_id": id, "$or": bson.A{
+			bson.M{"totp_last_step": bson.M{"$lt": step}},
+			bson.M{"totp_last_step": bson.M{"$exists": false}},
+		}},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+}
+
+func (r *mongoUsers) RemoveRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) error {
+	return updateOne(ctx, r.col,
+		bson.M{"_id": id, "recovery_code_hashes": hash},
+		bson.M{"$pull": bson.M{"recovery_code_hashes": hash}},
+	)
+}
+
+func (r *mongoUsers) ConsumeResetAttempt(ctx context.Context, email string, limit int) (model.User, error) {
+	email = utils.NormalizeEmail(email)
+	activeToken := bson.M{"$exists": true, "$ne": ""}
+
+	var user model.User
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{
+			"email":          email,
+			"reset_token":    activeToken,
+			"reset_attempts": bson.M{"$not": bson.M{"$gte": limit}}, // Juga cocok jika field belum ada
+		},
+		bson.M{"$inc": bson.M{"reset_attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != mongo.ErrNoDocuments {
+		return user, err
+	}
+
+	// Bedakan OTP yang jatahnya habis dari email/OTP yang tidak ada
+	count, err := r.col.CountDocuments(ctx, bson.M{"email": email, "reset_token": activeToken})
+	if err != nil {
+		return user, err
+	}
+	if count > 0 {
+		return user, ErrLimitReached
+	}
+	return user, ErrNotFound
+}
+
+func (r *mongoUsers) ReleaseResetAttempt(ctx context.Context, id primitive.ObjectID, tokenHash string) error {
+	return updateOne(ctx, r.col,
+		bson.M{"_id": id, "reset_token": tokenHash},
+		bson.M{"$inc": bson.M{"reset_attempts": -1}},
+	)
+}
+
+func (r *mongoUsers) Delete(ctx context.Context, id primitive.ObjectID, roles ...string) error {
+	return deleteOne(ctx, r.col, rolesFilter(id, roles))
+}
+
+func rolesFilter(id primitive.ObjectID, roles []string) bson.M {
+	filter := bson.M{"_id": id}
+	if len(roles) > 0 {
+		filter["roles"] = bson.M{"$all": roles}
+	}
+	return filter
+}
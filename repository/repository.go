@@ -0,0 +1,391 @@
+// Package repository memisahkan akses data dari handler.
+// Setiap aggregate memiliki interface sendiri dengan implementasi MongoDB (NewMongo)
+// untuk produksi dan implementasi in-memory (NewMemory) untuk pengujian.
+package repository
+
+import (
+	"be_ecommerce/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound dikembalikan jika dokumen yang dicari (atau yang akan diubah) tidak ada
+var ErrNotFound = errors.New("repository: not found")
+
+// ErrDuplicateEmail dikembalikan Users.Create jika email sudah dipakai akun lain
+var ErrDuplicateEmail = errors.New("repository: duplicate email")
+
+// ErrDuplicateSKU dikembalikan SetVariants jika SKU sudah dipakai varian produk lain
+var ErrDuplicateSKU = errors.New("repository: duplicate SKU")
+
+// ErrLimitReached dikembalikan UserRepository.ConsumeResetAttempt jika jatah percobaan OTP sudah habis
+var ErrLimitReached = errors.New("repository: limit reached")
+
+// Fields berisi perubahan parsial dengan key berupa nama field BSON, contoh: {"status": "Shipped"}
+type Fields map[string]interface{}
+
+// Repositories mengelompokkan semua repository yang dibutuhkan handler
+type Repositories struct {
+	Products    ProductRepository
+	Orders      OrderRepository
+	Checkouts   CheckoutRepository
+	Payments    PaymentNotificationRepository
+	Returns     ReturnRepository
+	Idempotency IdempotencyRepository
+	Users       UserRepository
+	Carts       CartRepository
+	Reviews     ReviewRepository
+	Categories  CategoryRepository
+	Leases      LeaseRepository
+
+	Stores        StoreRepository
+	Favorites     FavoriteRepository
+	RefreshTokens RefreshTokenRepository
+	LoginAttempts LoginAttemptRepository
+
+	// Tx menjalankan beberapa operasi repository sebagai satu transaksi
+	Tx Transactor
+}
+
+// Transactor menjalankan fn di dalam transaksi. Repository yang dipanggil dengan ctx milik fn
+// ikut dalam transaksi tersebut; jika fn mengembalikan error semua perubahan dibatalkan.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// StockItem adalah jumlah stok satu produk (atau satu varian produk) yang diambil atau dikembalikan
+type StockItem struct {
+	ProductID primitive.ObjectID
+	VariantID primitive.ObjectID // Kosong untuk produk tanpa varian
+	Quantity  int
+}
+
+// OutOfStockItem menjelaskan produk atau varian yang stoknya tidak mencukupi
+type OutOfStockItem struct {
+	ProductID primitive.ObjectID `json:"product_id"`
+	VariantID primitive.ObjectID `json:"variant_id,omitempty"`
+	SKU       string             `json:"sku,omitempty"`
+	Name      string             `json:"name"`
+	Requested int                `json:"requested"`
+	Available int                `json:"available"`
+}
+
+// OutOfStockError dikembalikan DecrementStock dan berisi semua produk yang stoknya kurang
+type OutOfStockError struct {
+	Items []OutOfStockItem
+}
+
+func (e *OutOfStockError) Error() string {
+	return fmt.Sprintf("repository: %d item(s) out of stock", len(e.Items))
+}
+
+// ProductFilter membatasi hasil ProductRepository.Find; field kosong diabaikan
+type ProductFilter struct {
+	SellerID *primitive.ObjectID
+	MaxPrice *int
+
+	MinRating  *float64
+	MinReviews *int
+}
+
+// Urutan hasil ProductRepository.Search
+const (
+	ProductSortNewest      = "newest"
+	ProductSortPriceAsc    = "price_asc"
+	ProductSortPriceDesc   = "price_desc"
+	ProductSortRating      = "rating"
+	ProductSortBestSelling = "best_selling"
+)
+
+// ProductQuery adalah parameter pencarian katalog; field kosong diabaikan
+type ProductQuery struct {
+	Text          string               // Dicocokkan dengan nama dan deskripsi produk
+	IDs           []primitive.ObjectID // Hanya produk dengan ID ini, contoh: hasil index pencarian
+	CategoryID    *primitive.ObjectID
+	SubCategoryID *primitive.ObjectID
+	SellerID      *primitive.ObjectID
+	MinPrice      *int
+	MaxPrice      *int
+	DiscountOnly  bool
+	InStock       bool
+	MinRating     *float64
+	Sort          string         // Salah satu ProductSort*, default ProductSortNewest
+	After         *ProductCursor // Lanjutkan setelah produk terakhir dari halaman sebelumnya
+	Limit         int
+}
+
+// ProductCursor menandai posisi produk dalam urutan Sort untuk pagination berbasis cursor
+type ProductCursor struct {
+	Value float64 // Nilai field yang diurutkan; tidak dipakai untuk ProductSortNewest
+	ID    primitive.ObjectID
+}
+
+// ProductPage adalah satu halaman hasil Search beserta kategori tiap produk
+type ProductPage struct {
+	Products []bson.M
+	Total    int64          // Jumlah semua produk yang cocok, tanpa memperhitungkan cursor
+	Next     *ProductCursor // nil jika sudah halaman terakhir
+}
+
+// variantSummary menghitung field ringkasan produk dari variannya: harga dan diskon varian
+// termurah setelah diskon (untuk filter dan urutan katalog) serta jumlah stok semua varian
+func variantSummary(variants []model.ProductVariant) Fields {
+	stock, cheapest := 0, -1
+	for i, variant := range variants {
+		stock += variant.Stock
+		if cheapest < 0 || discounted(variant) < discounted(variants[cheapest]) {
+			cheapest = i
+		}
+	}
+	fields := Fields{"stock": stock}
+	if cheapest >= 0 {
+		fields["price"] = variants[cheapest].Price
+		fields["discount"] = variants[cheapest].Discount
+	}
+	return fields
+}
+
+func discounted(variant model.ProductVariant) int {
+	return variant.Price - variant.Price*variant.Discount/100
+}
+
+// productSortKey mengembalikan field BSON dan arah urutan untuk sort; _id dipakai sebagai pemecah seri
+func productSortKey(sort string) (string, int) {
+	switch sort {
+	case ProductSortPriceAsc:
+		return "price", 1
+	case ProductSortPriceDesc:
+		return "price", -1
+	case ProductSortRating:
+		return "rating", -1
+	case ProductSortBestSelling:
+		return "sold", -1
+	}
+	return "_id", -1
+}
+
+type ProductRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (model.Product, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Product, error)
+	Find(ctx context.Context, filter ProductFilter) ([]model.Product, error)
+	// Search mengembalikan produk beserta kategorinya, tanpa produk dari seller yang di-suspend
+	Search(ctx context.Context, query ProductQuery) (ProductPage, error)
+	Create(ctx context.Context, product *model.Product) error
+	// Update dan Delete hanya mengenai produk milik sellerID jika sellerID tidak nil
+	Update(ctx context.Context, id primitive.ObjectID, sellerID *primitive.ObjectID, fields Fields) error
+	// ImageInUse memeriksa apakah masih ada produk yang galerinya memuat gambar dengan hash ini
+	ImageInUse(ctx context.Context, hash string) (bool, error)
+	// SetVariants mengganti option dan varian produk sekaligus memperbarui ringkasan harga dan stok produk.
+	// Varian kosong membuat produk kembali dijual tanpa varian.
+	SetVariants(ctx context.Context, id primitive.ObjectID, sellerID *primitive.ObjectID, options []model.ProductOption, variants []model.ProductVariant) error
+	Delete(ctx context.Context, id primitive.ObjectID, sellerID *primitive.ObjectID) error
+	// DecrementStock mengurangi stok (dan menambah sold) hanya jika stok masih cukup. Item dengan VariantID
+	// mengurangi stok varian sekaligus ringkasan stok produknya. Jika ada produk yang kurang,
+	// *OutOfStockError dikembalikan; panggil di dalam Tx agar pengurangan produk lain ikut dibatalkan.
+	DecrementStock(ctx context.Context, items []StockItem) error
+	// RestoreStock mengembalikan stok dan mengurangi sold
+	RestoreStock(ctx context.Context, items []StockItem) error
+}
+
+// OrderStats adalah ringkasan order untuk dashboard seller
+type OrderStats struct {
+	ItemsSold     int
+	PendingOrders int // Order yang sudah dibayar dan menunggu diproses seller
+	Revenue       int
+}
+
+// pendingOrderStatuses dihitung sebagai OrderStats.PendingOrders, termasuk status lama "Pending"
+var pendingOrderStatuses = []string{model.OrderStatusPaid, model.OrderStatusLegacyPending}
+
+type OrderRepository interface {
+	Create(ctx context.Context, order *model.Order) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (model.Order, error)
+	FindForSeller(ctx context.Context, id, sellerID primitive.ObjectID) (model.Order, error)
+	ListByUser(ctx context.Context, userID primitive.ObjectID) ([]model.Order, error)
+	// ListBySeller tidak menyertakan order yang sudah diarsipkan
+	ListBySeller(ctx context.Context, sellerID primitive.ObjectID) ([]model.Order, error)
+	ListByCheckout(ctx context.Context, checkoutID primitive.ObjectID) ([]model.Order, error)
+	Update(ctx context.Context, id primitive.ObjectID, fields Fields) error
+	UpdateForSeller(ctx context.Context, id, sellerID primitive.ObjectID, fields Fields) error
+	// Transition mengubah status dari change.From ke change.To beserta fields lain, lalu menambahkan change
+	// ke status_history secara atomik. ErrNotFound jika status order sudah bukan change.From.
+	Transition(ctx context.Context, id primitive.ObjectID, change model.StatusChange, fields Fields) error
+	// ArchiveForSeller menyembunyikan order dari daftar seller tanpa menghapus datanya
+	ArchiveForSeller(ctx context.Context, id, sellerID primitive.ObjectID) error
+	// AddRefund menyimpan refund dan menambah refunded_amount, hanya jika refunded_amount masih
+	// refundedBefore (optimistic lock). ErrNotFound jika order sudah berubah.
+	AddRefund(ctx context.Context, id primitive.ObjectID, refundedBefore int, refund model.Refund) error
+	UpdateRefund(ctx context.Context, id, refundID primitive.ObjectID, status, errMessage string) error
+	SellerStats(ctx context.Context, sellerID primitive.ObjectID) (OrderStats, error)
+}
+
+// CheckoutRepository menyimpan transaksi induk yang menaungi order per toko
+type CheckoutRepository interface {
+	Create(ctx context.Context, checkout *model.Checkout) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (model.Checkout, error)
+	FindForUser(ctx context.Context, id, userID primitive.ObjectID) (model.Checkout, error)
+	FindByPaymentReference(ctx context.Context, reference string) (model.Checkout, error)
+	// ListAwaitingPayment mengembalikan checkout yang belum dibayar dan dibuat sebelum createdBefore, terlama dulu
+	ListAwaitingPayment(ctx context.Context, createdBefore time.Time, limit int) ([]model.Checkout, error)
+	Update(ctx context.Context, id primitive.ObjectID, fields Fields) error
+}
+
+// PaymentNotificationRepository menyimpan log notification payment gateway (append-only)
+type PaymentNotificationRepository interface {
+	Create(ctx context.Context, notification *model.PaymentNotification) error
+}
+
+// ReturnFilter membatasi hasil ReturnRepository.Find; field kosong diabaikan
+type ReturnFilter struct {
+	OrderID  *primitive.ObjectID
+	UserID   *primitive.ObjectID
+	SellerID *primitive.ObjectID
+	Status   string
+}
+
+type ReturnRepository interface {
+	Create(ctx context.Context, ret *model.ReturnRequest) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (model.ReturnRequest, error)
+	Find(ctx context.Context, filter ReturnFilter) ([]model.ReturnRequest, error)
+	// Transition sama seperti OrderRepository.Transition, untuk status retur
+	Transition(ctx context.Context, id primitive.ObjectID, change model.StatusChange, fields Fields) error
+}
+
+type IdempotencyRepository interface {
+	// Reserve menyimpan record baru jika belum ada record aktif dengan ID yang sama.
+	// Jika sudah ada, record tersebut dikembalikan dengan reserved bernilai false.
+	// Record yang kedaluwarsa atau processing yang melewati LockedUntil boleh diambil alih.
+	Reserve(ctx context.Context, record *model.IdempotencyRecord) (existing model.IdempotencyRecord, reserved bool, err error)
+	// Extend memperpanjang LockedUntil record processing selama request yang memegangnya masih berjalan.
+	// Record dikenali dari ID dan CreatedAt reservasi, sehingga reservasi yang sudah diambil alih
+	// request lain tidak ikut diperpanjang; ErrNotFound jika reservasinya sudah tidak ada.
+	Extend(ctx context.Context, id string, createdAt, lockedUntil time.Time) error
+	// Complete menyimpan respons untuk reservasi processing id yang dibuat pada createdAt
+	Complete(ctx context.Context, id string, createdAt time.Time, status int, contentType string, body []byte) error
+	// Release menghapus reservasi processing id yang dibuat pada createdAt agar request bisa diulang
+	Release(ctx context.Context, id string, createdAt time.Time) error
+}
+
+// LeaseRepository membagi job terjadwal antar instance server
+type LeaseRepository interface {
+	// Acquire mengambil atau memperpanjang lease name untuk owner selama ttl.
+	// false jika lease masih dipegang owner lain.
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// Release melepas lease jika masih dipegang owner
+	Release(ctx context.Context, name, owner string) error
+}
+
+// UserFilter membatasi hasil UserRepository.Find.
+// User cocok jika memiliki semua AllRoles, atau store_status-nya sama dengan OrStoreStatus.
+type UserFilter struct {
+	AllRoles      []string
+	OrStoreStatus string
+}
+
+type UserRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (model.User, error)
+	FindByEmail(ctx context.Context, email string) (model.User, error)
+	Find(ctx context.Context, filter UserFilter) ([]model.User, error)
+	Create(ctx context.Context, user *model.User) error
+	// Update dan Delete hanya mengenai user yang memiliki semua roles (kosong berarti tanpa syarat role)
+	Update(ctx context.Context, id primitive.ObjectID, roles []string, set Fields, unset ...string) error
+	// UpdateIf seperti Update, tetapi hanya jika field masih bernilai expected (nil berarti field belum ada).
+	// ErrNotFound jika nilainya sudah berubah, sehingga bisa dipakai sebagai optimistic lock.
+	UpdateIf(ctx context.Context, id primitive.ObjectID, field string, expected interface{}, set Fields, unset ...string) error
+	// Increment menambah field angka sebesar delta secara atomik, contoh: token_version
+	Increment(ctx context.Context, id primitive.ObjectID, field string, delta int) error
+	// AdvanceTOTPStep menyimpan step sebagai totp_last_step hanya jika lebih besar dari yang tersimpan.
+	// ErrNotFound berarti kode TOTP untuk step itu (atau yang lebih baru) sudah pernah dipakai.
+	AdvanceTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) error
+	// RemoveRecoveryCode menghapus hash kode cadangan; ErrNotFound jika kode sudah dipakai
+	RemoveRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) error
+	// ConsumeResetAttempt memakai satu jatah percobaan OTP reset password secara atomik dan mengembalikan
+	// user setelah reset_attempts dinaikkan. ErrNotFound jika email tidak punya OTP aktif,
+	// ErrLimitReached jika reset_attempts sudah mencapai limit.
+	ConsumeResetAttempt(ctx context.Context, email string, limit int) (model.User, error)
+	// ReleaseResetAttempt mengembalikan satu jatah percobaan, hanya jika OTP-nya masih tokenHash
+	ReleaseResetAttempt(ctx context.Context, id primitive.ObjectID, tokenHash string) error
+	Delete(ctx context.Context, id primitive.ObjectID, roles ...string) error
+}
+
+// StoreRepository menyimpan toko seller
+type StoreRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (model.Store, error)
+	// FindByIDOrOwner mencari toko dengan _id atau owner_id sama dengan id; tautan lama memakai ID pemilik
+	FindByIDOrOwner(ctx context.Context, id primitive.ObjectID) (model.Store, error)
+	List(ctx context.Context) ([]model.Store, error)
+	// Save membuat toko baru atau memperbarui pemilik, nama, alamat dan status toko dengan _id yang sama.
+	// CreatedAt hanya diisi saat toko dibuat.
+	Save(ctx context.Context, store *model.Store) error
+	// SetStatusByOwner mengubah status toko milik ownerID; user yang belum punya toko diabaikan
+	SetStatusByOwner(ctx context.Context, ownerID primitive.ObjectID, status string) error
+}
+
+// FavoriteRepository menyimpan daftar produk favorit per user dengan user_id berupa hex string
+type FavoriteRepository interface {
+	Get(ctx context.Context, userID string) (model.Favorite, error)
+	// Add menambahkan produk ke favorit; false jika produk sudah ada di daftar
+	Add(ctx context.Context, userID, productID string) (bool, error)
+}
+
+// RefreshTokenRepository menyimpan refresh token (dalam bentuk hash) per sesi login
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *model.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (model.RefreshToken, error)
+	// Rotate mencabut token id dan mencatat penggantinya. ErrNotFound jika token sudah dicabut,
+	// sehingga dua request tidak bisa merotasi token yang sama.
+	Rotate(ctx context.Context, id, replacedBy primitive.ObjectID) error
+	Revoke(ctx context.Context, id primitive.ObjectID) error
+	// RevokeFamily mencabut semua token hasil rotasi dari satu login
+	RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error
+	// RevokeUser mencabut semua sesi milik user
+	RevokeUser(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// LoginAttemptRepository mencatat kegagalan login/OTP per kunci (akun atau IP)
+type LoginAttemptRepository interface {
+	// LockedUntil mengembalikan locked_until terlama dari kunci-kunci yang diberikan
+	LockedUntil(ctx context.Context, keys ...string) (time.Time, error)
+	// RecordFailure menaikkan failures secara atomik dan mengembalikan hasilnya. Jika kegagalan terakhir
+	// terjadi sebelum windowStart, hitungan dimulai lagi dari 1. expiresAt dipakai TTL index.
+	RecordFailure(ctx context.Context, key string, now, windowStart, expiresAt time.Time) (model.LoginAttempt, error)
+	// Lock mengunci kunci sampai until; kunci yang sudah lebih lama tidak diperpendek
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, keys ...string) error
+}
+
+// Keranjang disimpan per user dengan user_id berupa hex string
+type CartRepository interface {
+	Get(ctx context.Context, userID string) (model.Cart, error)
+	Save(ctx context.Context, cart model.Cart) error
+	// RemoveItem menghapus item produk dari keranjang; variantID kosong untuk produk tanpa varian
+	RemoveItem(ctx context.Context, userID, productID, variantID string) error
+	Clear(ctx context.Context, userID string) error
+}
+
+type ReviewRepository interface {
+	Create(ctx context.Context, review *model.Review) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (model.Review, error)
+	ListByProduct(ctx context.Context, productID primitive.ObjectID) ([]model.Review, error)
+	RatingSummary(ctx context.Context, productID primitive.ObjectID) (avg float64, count int, err error)
+	Update(ctx context.Context, id, userID primitive.ObjectID, rating float64, comment string) error
+	// Delete hanya mengenai review milik userID jika userID tidak nil
+	Delete(ctx context.Context, id primitive.ObjectID, userID *primitive.ObjectID) error
+}
+
+type CategoryRepository interface {
+	Create(ctx context.Context, category *model.Category) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (model.Category, error)
+	FindByName(ctx context.Context, name string) (model.Category, error)
+	List(ctx context.Context) ([]model.Category, error)
+	Rename(ctx context.Context, id primitive.ObjectID, name string) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	AddSubCategory(ctx context.Context, categoryID primitive.ObjectID, sub model.SubCategory) error
+	RenameSubCategory(ctx context.Context, categoryID, subID primitive.ObjectID, name string) error
+	RemoveSubCategory(ctx context.Context, categoryID, subID primitive.ObjectID) error
+}
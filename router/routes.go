@@ -2,99 +2,170 @@ package router
 
 import (
 	"be_ecommerce/handler"
+	"be_ecommerce/imaging"
+	"be_ecommerce/middleware"
+	"be_ecommerce/repository"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupRoutes(app *fiber.App) {
+// SetupRoutes mendaftarkan semua route; h berisi handler, repos dipakai middleware autentikasi
+func SetupRoutes(app *fiber.App, h *handler.Handler, repos repository.Repositories) {
+	// Guard yang dipakai ulang di banyak route
+	auth := middleware.Protected(repos.Users)
+	adminOnly := middleware.RequireRole("admin")
+	sellerOnly := middleware.RequireRole("seller")
+	verified := middleware.RequireVerifiedEmail()
+
+	// Route upload multipart didaftarkan sebelum RejectLargeBody: hanya route ini yang menerima body
+	// lebih besar dari middleware.DefaultBodyLimit, dibatasi jumlah file dan imaging.MaxFileSize per file
+	productUpload := middleware.MultipartLimit(handler.MaxProductImages, imaging.MaxFileSize)
+	app.Post("/products", auth, adminOnly, productUpload, h.CreateProduct)
+	app.Put("/products/:id", auth, adminOnly, productUpload, h.UpdateProductByID)
+	app.Post("/products/:id/images", auth, adminOnly, productUpload, h.AddProductImages) // Galeri produk; gambar pertama menjadi sampul
+	app.Post("/seller/products", auth, sellerOnly, productUpload, h.CreateProductForSeller)
+	app.Put("/seller/products/:id", auth, sellerOnly, productUpload, h.UpdateProductForSeller)
+	app.Post("/seller/products/:id/images", auth, sellerOnly, productUpload, h.AddSellerProductImages)
+	app.Post("/returns", auth, middleware.MultipartLimit(handler.MaxReturnPhotos, imaging.MaxFileSize), h.CreateReturnHandler) // Ajukan retur dengan foto bukti
+	app.Post("/become-seller", auth, verified, middleware.MultipartLimit(1, imaging.MaxFileSize), h.BecomeSeller)
+	app.Use(middleware.RejectLargeBody)
+
 	// Auth routes
-	app.Post("/register", handler.Register)
-	app.Post("/login", handler.Login)
-	app.Get("/users/me", handler.GetUserProfile)
-	app.Put("/users/update-profile", handler.EditProfile)
-	app.Put("/users/reset-password", handler.ResetPassword)
-	app.Post("/users/send-password-reset-email", handler.SendPasswordResetEmail)
-	app.Post("/users/verify-otp", handler.VerifyOTP)
+	app.Post("/register", h.Register)
+	app.Post("/login", h.Login)
+	app.Post("/login/2fa", h.LoginTwoFactor)
+	app.Post("/token/refresh", h.RefreshToken)
+	app.Post("/logout", auth, h.Logout)
+	app.Post("/logout-all", auth, h.LogoutAll)
+	app.Post("/2fa/setup", auth, h.SetupTwoFactor)
+	app.Post("/2fa/enable", auth, h.EnableTwoFactor)
+	app.Post("/2fa/disable", auth, h.DisableTwoFactor)
+	app.Post("/2fa/recovery-codes", auth, h.RegenerateRecoveryCodes)
+	app.Get("/users/me", auth, h.GetUserProfile)
+	app.Put("/users/update-profile", auth, h.EditProfile)
+	app.Put("/users/reset-password", h.ResetPassword)
+	app.Post("/users/send-password-reset-email", h.SendPasswordResetEmail)
+	app.Post("/users/verify-otp", h.VerifyOTP)
+	app.Post("/users/verify-email", h.VerifyEmail)
+	app.Post("/users/resend-verification", h.ResendVerificationEmail)
 	// Product routes
-	app.Post("/products", handler.CreateProduct)
-	app.Get("/products", handler.GetAllProducts)
-	app.Get("/products/:id", handler.GetProductDetail)
-	app.Get("/products/:product_id/rating", handler.GetProductRating)
-	// Endpoint untuk mendapatkan produk berdasarkan ID
-	app.Get("/products/:id", handler.GetProductByID)
-	app.Put("/products/:id", handler.UpdateProductByID)
-	app.Delete("/products/:id", handler.DeleteProductByID)
-
-	app.Static("/uploads", "./uploads")
-
-	app.Post("/categories", handler.AddCategory)                 // Tambahkan kategori baru
-	app.Post("/categories/sub", handler.AddSubCategory)          // Tambahkan sub-kategori ke kategori
-	app.Get("/categories", handler.GetCategories)                // Dapatkan semua kategori dan sub-kategori
-	app.Put("/categories/:id", handler.UpdateCategory)           // Update kategori berdasarkan ID
-	app.Put("/categories/sub/:id", handler.UpdateSubCategory)    // Update sub-kategori berdasarkan ID
-	app.Delete("/categories/:id", handler.DeleteCategory)        // Hapus kategori berdasarkan ID
-	app.Delete("/categories/sub/:id", handler.DeleteSubCategory) // Hapus sub-kategori berdasarkan ID
-
-	app.Post("/reviews", handler.AddReview)                 // Tambahkan review baru
-	app.Get("/reviews/:product_id", handler.GetReviews)     // Ambil semua review untuk produk
-	app.Put("/reviews/:review_id", handler.UpdateReview)    // Perbarui review
-	app.Delete("/reviews/:review_id", handler.DeleteReview) // Hapus review
-
-	app.Post("/cart", handler.AddToCart)
-	app.Get("/cart", handler.FetchCart)
-	app.Post("/cart/update", handler.UpdateCartItem)
-	app.Post("/cart/delete", handler.RemoveFromCart)
+	app.Get("/products", h.GetAllProducts)
+	app.Get("/products/:id", h.GetProductDetail)
+	app.Get("/products/:product_id/rating", h.GetProductRating)
+	app.Get("/search", h.SearchHandler)          // Pencarian produk berdasarkan relevansi
+	app.Get("/search/suggest", h.SuggestHandler) // Autocomplete kata yang sedang diketik
+	app.Put("/products/:id/variants", auth, adminOnly, h.UpdateProductVariants) // Option dan varian (SKU, harga, stok)
+	app.Put("/products/:id/images/order", auth, adminOnly, h.ReorderProductImages)
+	app.Delete("/products/:id/images/:hash", auth, adminOnly, h.DeleteProductImage)
+	app.Delete("/products/:id", auth, adminOnly, h.DeleteProductByID)
+
+	// Bucket publik backend local. Foto KYC lama di uploads/seller_photos tidak ikut disajikan;
+	// foto KYC hanya bisa dibuka lewat signed URL di /files/private. Path yang dicek adalah path
+	// yang sudah di-decode dan dinormalisasi, sama dengan yang dibaca file server.
+	app.Static("/uploads", "./uploads", fiber.Static{
+		Next: func(c *fiber.Ctx) bool {
+			return strings.HasPrefix(string(c.Request().URI().Path()), "/uploads/seller_photos")
+		},
+	})
+	app.Get("/files/private/*", h.ServePrivateFile)
+
+	app.Post("/categories", auth, adminOnly, h.AddCategory)                 // Tambahkan kategori baru
+	app.Post("/categories/sub", auth, adminOnly, h.AddSubCategory)          // Tambahkan sub-kategori ke kategori
+	app.Get("/categories", h.GetCategories)                                 // Dapatkan semua kategori dan sub-kategori
+	app.Put("/categories/:id", auth, adminOnly, h.UpdateCategory)           // Update kategori berdasarkan ID
+	app.Put("/categories/sub/:id", auth, adminOnly, h.UpdateSubCategory)    // Update sub-kategori berdasarkan ID
+	app.Delete("/categories/:id", auth, adminOnly, h.DeleteCategory)        // Hapus kategori berdasarkan ID
+	app.Delete("/categories/sub/:id", auth, adminOnly, h.DeleteSubCategory) // Hapus sub-kategori berdasarkan ID
+
+	app.Post("/reviews", auth, h.AddReview)                 // Tambahkan review baru
+	app.Get("/reviews/:product_id", h.GetReviews)           // Ambil semua review untuk produk
+	app.Put("/reviews/:review_id", auth, h.UpdateReview)    // Perbarui review
+	app.Delete("/reviews/:review_id", auth, h.DeleteReview) // Hapus review
+
+	cart := app.Group("/cart", auth)
+	cart.Post("/", h.AddToCart)
+	cart.Get("/", h.FetchCart)
+	cart.Post("/update", h.UpdateCartItem)
+	cart.Post("/delete", h.RemoveFromCart)
+
+	app.Post("/favorites", auth, h.AddToFavorites)
+	app.Get("/favorites", auth, h.GetFavorites)
 
 	// Customer applies as seller
-	app.Post("/apply-as-seller", handler.ApplyAsSeller)
+	app.Post("/apply-as-seller", auth, verified, h.ApplyAsSeller)
 
 	// Admin approves/rejects seller application
-	app.Post("/admin/approve-seller", handler.ApproveSeller)
-	app.Post("/admin/reject-seller", handler.RejectSeller)
-
-	app.Get("/users/:id", handler.GetUserByID)
+	admin := app.Group("/admin", auth, adminOnly)
+	admin.Post("/approve-seller", h.ApproveSeller)
+	admin.Post("/reject-seller", h.RejectSeller)
+	admin.Post("/users", h.ProvisionUser)
+	admin.Post("/users/:id/suspend", h.SuspendUser)
+	admin.Post("/users/:id/unsuspend", h.UnsuspendUser)
+	admin.Post("/sellers/:id/suspend", h.SuspendSeller)
+	admin.Post("/sellers/:id/unsuspend", h.UnsuspendSeller)
+	admin.Put("/orders/:order_id/status", h.AdminUpdateOrderStatusHandler)
+	admin.Post("/orders/:order_id/refunds", h.AdminCreateRefundHandler)
+	admin.Post("/orders/:order_id/refunds/:refund_id/retry", h.RetryRefundHandler)
+	admin.Get("/returns", h.GetAdminReturnsHandler)                          // Default retur yang dieskalasi
+	admin.Put("/returns/:return_id/resolve", h.ResolveReturnHandler)         // Putuskan sengketa retur
+	admin.Put("/returns/:return_id/receive", h.AdminReceiveReturnHandler)    // Terima barang retur atas nama seller
+
+	app.Get("/users/:id", auth, adminOnly, h.GetUserByID)
 
 	// Customer Routes
-	app.Get("/customers", handler.GetCustomers)
-	app.Post("/customers", handler.CreateCustomer)
-	app.Put("/customers/update", handler.UpdateCustomer)
-	app.Delete("/customers/:id", handler.DeleteCustomer)
+	customers := app.Group("/customers", auth, adminOnly)
+	customers.Get("/", h.GetCustomers)
+	customers.Post("/", h.CreateCustomer)
+	customers.Put("/update", h.UpdateCustomer)
+	customers.Delete("/:id", h.DeleteCustomer)
 
 	// seller Routes
-	app.Get("/sellers", handler.GetSellers)
-	app.Post("/sellers", handler.CreateSeller)
-	app.Put("/sellers/:id", handler.UpdateSeller)
-	app.Delete("/sellers/:id", handler.DeleteSeller)
-	app.Get("/seller/products", handler.GetProductsByUserID)
-	app.Post("/seller/products", handler.CreateProductForSeller)
-	app.Put("/seller/products/:id", handler.UpdateProductForSeller)
-	app.Delete("/seller/products/:id", handler.DeleteProductForSeller)
+	app.Get("/sellers", auth, adminOnly, h.GetSellers)
+	app.Put("/sellers/:id", auth, adminOnly, h.UpdateSeller)
+	app.Delete("/sellers/:id", auth, adminOnly, h.DeleteSeller)
+	app.Get("/seller/products", auth, sellerOnly, h.GetProductsByUserID)
+	app.Put("/seller/products/:id/variants", auth, sellerOnly, h.UpdateSellerProductVariants)
+	app.Put("/seller/products/:id/images/order", auth, sellerOnly, h.ReorderSellerProductImages)
+	app.Delete("/seller/products/:id/images/:hash", auth, sellerOnly, h.DeleteSellerProductImage)
+	app.Delete("/seller/products/:id", auth, sellerOnly, h.DeleteProductForSeller)
 
 	// Customer-Seller Routes
-	app.Get("/customer-sellers", handler.GetCustomerSellers)
-	app.Post("/customer-sellers", handler.CreateCustomerSeller)
-	app.Put("/customer-sellers/:id", handler.UpdateCustomerSeller)
-	app.Delete("/customer-sellers/:id", handler.DeleteCustomerSeller)
-
-	app.Post("/checkout", handler.CheckoutHandler)
-	app.Get("/orders", handler.GetOrdersBySellerHandler)                      // Get all orders for a user
-	app.Get("/orders/:order_id", handler.GetSellerOrderDetailsHandler)    // Get order details          
-	app.Put("/orders/:order_id", handler.UpdateSellerOrderHandler)    // Update order status
-	app.Put("/orders/status/:order_id", handler.UpdateOrderStatusHandler)    // Update order status
-	app.Delete("/orders/:order_id", handler.DeleteSellerOrderHandler) // Delete an order
-	app.Post("/payment", handler.CreatePaymentHandler)
-
-	app.Get("/orders", handler.GetOrdersHandler)    // Untuk customer
+	customerSellers := app.Group("/customer-sellers", auth, adminOnly)
+	customerSellers.Get("/", h.GetCustomerSellers)
+	customerSellers.Put("/:id", h.UpdateCustomerSeller)
+	customerSellers.Delete("/:id", h.DeleteCustomerSeller)
+
+	app.Post("/checkout", auth, verified, h.Idempotent, h.CheckoutHandler)
+	app.Get("/checkouts/:checkout_id", auth, h.GetCheckoutHandler) // Checkout beserta order per toko
+	orders := app.Group("/orders", auth)
+	orders.Get("/", h.GetOrdersHandler)                                          // Untuk customer
+	orders.Get("/:order_id", sellerOnly, h.GetSellerOrderDetailsHandler)         // Get order details
+	orders.Put("/:order_id", sellerOnly, h.UpdateSellerOrderHandler)             // Update order status
+	orders.Put("/status/:order_id", sellerOnly, h.UpdateOrderStatusHandler)      // Update order status
+	orders.Delete("/:order_id", sellerOnly, h.DeleteSellerOrderHandler)          // Delete an order
+	orders.Put("/:order_id/status", h.UpdateCustomerOrderStatusHandler)          // Customer cancel/complete
+	orders.Post("/:order_id/refunds", sellerOnly, h.CreateSellerRefundHandler)   // Refund sebagian item
+	app.Post("/payment", auth, verified, h.Idempotent, h.CreatePaymentHandler)
+	app.Post("/payments/:provider/notification", h.PaymentNotificationHandler) // Dipanggil payment gateway, diverifikasi oleh provider
+
+	// Retur setelah order diterima
+	returns := app.Group("/returns", auth)
+	returns.Get("/", h.GetCustomerReturnsHandler)                       // Retur milik customer
+	returns.Put("/:return_id/shipment", h.ShipReturnHandler)            // Isi resi pengiriman balik
+	returns.Put("/:return_id/cancel", h.CancelReturnHandler)            // Batalkan retur
+	returns.Post("/:return_id/escalate", h.EscalateReturnHandler)       // Minta admin meninjau penolakan
+	app.Get("/seller/returns", auth, sellerOnly, h.GetSellerReturnsHandler)
+	app.Put("/seller/returns/:return_id", auth, sellerOnly, h.ReviewReturnHandler)          // Approve/reject
+	app.Put("/seller/returns/:return_id/receive", auth, sellerOnly, h.ReceiveReturnHandler) // Barang diterima, refund
 
 	// Seller melihat order yang berisi produknya
-	app.Get("/seller/orders", handler.GetOrdersBySellerHandler)
-
-	app.Get("/sellers/:id", handler.GetSellerByID)
+	app.Get("/seller/orders", auth, sellerOnly, h.GetOrdersBySellerHandler)
 
-	app.Post("/become-seller", handler.BecomeSeller)
+	app.Get("/sellers/:id", auth, adminOnly, h.GetSellerByID)
 
 	// Endpoint untuk store
-	app.Get("/stores/:id", handler.GetStoreDetails) // Mendapatkan detail store dan produk terkait
+	app.Get("/stores/:id", h.GetStoreDetails) // Mendapatkan detail store dan produk terkait
 
-	app.Get("/dashboard-data", handler.GetDashboardData)
+	app.Get("/dashboard-data", auth, sellerOnly, h.GetDashboardData)
 }
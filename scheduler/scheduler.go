@@ -0,0 +1,104 @@
+// Package scheduler menjalankan job berkala di dalam proses server.
+// Setiap putaran job dilindungi lease di MongoDB sehingga saat server dijalankan
+// di beberapa instance, satu putaran hanya dikerjakan oleh satu instance,
+// kecuali job PerInstance yang mengurus state lokal instance itu sendiri.
+package scheduler
+
+import (
+	"be_ecommerce/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Job adalah pekerjaan yang dijalankan setiap Interval
+type Job struct {
+	Name     string // Juga dipakai sebagai nama lease
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+
+	// PerInstance menjalankan job di setiap instance tanpa lease, contoh: cache di memori
+	PerInstance bool
+}
+
+// Scheduler menjalankan job yang didaftarkan lewat Add setelah Start dipanggil
+type Scheduler struct {
+	leases repository.LeaseRepository
+	owner  string
+	jobs   []Job
+}
+
+// New membuat scheduler dengan ID instance acak sebagai pemilik lease
+func New(leases repository.LeaseRepository) *Scheduler {
+	return &Scheduler{leases: leases, owner: instanceID()}
+}
+
+// instanceID menggabungkan hostname, PID dan nilai acak agar unik antar container
+func instanceID() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// Add mendaftarkan job; job dengan Interval <= 0 dianggap dimatikan
+func (s *Scheduler) Add(job Job) {
+	if job.Interval <= 0 {
+		log.Printf("Scheduler: job %s is disabled", job.Name)
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Start menjalankan semua job sampai ctx dibatalkan, lalu menunggu putaran yang sedang berjalan selesai
+func (s *Scheduler) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		s.runOnce(ctx, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce menjalankan job jika lease berhasil diambil. Lease tidak dilepas setelah selesai
+// agar instance lain tidak mengulang job yang sama pada Interval yang sama.
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	if !job.PerInstance {
+		acquired, err := s.leases.Acquire(ctx, job.Name, s.owner, job.Interval)
+		if err != nil {
+			log.Printf("Scheduler: failed to acquire lease for %s: %v", job.Name, err)
+			return
+		}
+		if !acquired {
+			return
+		}
+	}
+
+	// Job harus selesai sebelum lease habis agar tidak berjalan ganda di instance lain
+	runCtx, cancel := context.WithTimeout(ctx, job.Interval)
+	defer cancel()
+	if err := job.Run(runCtx); err != nil {
+		log.Printf("Scheduler: job %s failed: %v", job.Name, err)
+	}
+}
@@ -0,0 +1,53 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token adalah satu kata hasil Analyze
+type Token struct {
+	Word string // Kata asli dalam huruf kecil, dipakai untuk autocomplete dan "did you mean"
+	Stem string // Kata dasar yang disimpan di index
+	Stop bool   // Stop word tidak diindex dan tidak ikut dicari
+}
+
+// stopWords berisi kata umum bahasa Indonesia (dan sedikit bahasa Inggris) yang tidak membantu pencarian
+var stopWords = toSet(
+	"ada", "adalah", "agar", "akan", "aku", "anda", "antara", "apa", "atau", "bagi", "bahwa", "banyak",
+	"bisa", "dalam", "dan", "dari", "dengan", "di", "dia", "hanya", "harus", "ia", "ini", "itu", "jadi",
+	"jika", "juga", "kalau", "kami", "karena", "ke", "kita", "lagi", "lain", "lebih", "maka", "masih",
+	"mereka", "namun", "oleh", "pada", "para", "saat", "saja", "sangat", "sebagai", "sebuah", "secara",
+	"sedang", "sehingga", "sekali", "semua", "seperti", "serta", "setelah", "sudah", "tanpa", "tapi",
+	"telah", "tentang", "tetapi", "untuk", "yang",
+	"a", "an", "and", "for", "in", "of", "the", "to", "with",
+)
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+// splitWords memecah teks menjadi kata huruf kecil; selain huruf dan angka dianggap pemisah
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Analyze memecah teks menjadi token dengan kata dasar dan penanda stop word
+func Analyze(text string) []Token {
+	words := splitWords(text)
+	tokens := make([]Token, 0, len(words))
+	for _, word := range words {
+		if stopWords[word] {
+			tokens = append(tokens, Token{Word: word, Stem: word, Stop: true})
+			continue
+		}
+		tokens = append(tokens, Token{Word: word, Stem: Stem(word)})
+	}
+	return tokens
+}
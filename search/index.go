@@ -0,0 +1,335 @@
+// Package search adalah mesin pencari produk in-memory: inverted index dengan skor BM25,
+// stemming dan stop word bahasa Indonesia, toleransi salah ketik, autocomplete dan "did you mean".
+// Index tidak disimpan ke database; isinya dibangun ulang dari MongoDB saat server start.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Parameter BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Pengali skor untuk kata yang hanya cocok setelah salah ketiknya dikoreksi
+var typoPenalty = map[int]float64{1: 0.6, 2: 0.35}
+
+// Field adalah satu bagian teks dokumen; Weight lebih besar membuat kecocokan di field ini lebih relevan
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Document adalah satu entitas yang bisa dicari, contoh: produk dengan nama, deskripsi, kategori dan toko
+type Document struct {
+	ID     string
+	Fields []Field
+}
+
+// Hit adalah dokumen yang cocok dengan query beserta skor relevansinya
+type Hit struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// Result adalah hasil Search; Total adalah jumlah semua dokumen yang cocok sebelum limit/offset
+type Result struct {
+	Hits       []Hit
+	Total      int
+	DidYouMean string // Query yang sudah dikoreksi, kosong jika tidak ada kata yang salah ketik
+}
+
+// Index aman dipakai dari banyak goroutine
+type Index struct {
+	mu       sync.RWMutex
+	postings map[string]map[string]float64 // stem → dokumen → frekuensi berbobot
+	docTerms map[string]map[string]float64 // dokumen → stem → frekuensi berbobot, untuk Delete
+	docLen   map[string]float64
+	totalLen float64
+
+	docWords    map[string][]string // dokumen → kata asli unik, untuk Delete
+	words       map[string]int      // kata asli → jumlah dokumen yang memuatnya
+	sortedWords []string            // Kata asli terurut untuk autocomplete, nil jika perlu dibangun ulang
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: map[string]map[string]float64{},
+		docTerms: map[string]map[string]float64{},
+		docLen:   map[string]float64{},
+		docWords: map[string][]string{},
+		words:    map[string]int{},
+	}
+}
+
+// Len mengembalikan jumlah dokumen di index
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docLen)
+}
+
+// Put menambahkan dokumen atau menggantinya jika ID sudah ada
+func (ix *Index) Put(doc Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(doc.ID)
+	ix.add(doc)
+}
+
+// Delete menghapus dokumen dari index; ID yang tidak ada diabaikan
+func (ix *Index) Delete(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+// Replace mengganti seluruh isi index sekaligus, dipakai saat membangun ulang dari database
+func (ix *Index) Replace(docs []Document) {
+	fresh := NewIndex()
+	for _, doc := range docs {
+		fresh.add(doc)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.postings, ix.docTerms, ix.docLen, ix.totalLen = fresh.postings, fresh.docTerms, fresh.docLen, fresh.totalLen
+	ix.docWords, ix.words, ix.sortedWords = fresh.docWords, fresh.words, nil
+}
+
+func (ix *Index) add(doc Document) {
+	terms := map[string]float64{}
+	seen := map[string]bool{}
+	var length float64
+	for _, field := range doc.Fields {
+		for _, token := range Analyze(field.Text) {
+			if token.Stop {
+				continue
+			}
+			terms[token.Stem] += field.Weight
+			length += field.Weight
+			seen[token.Word] = true
+		}
+	}
+	if len(terms) == 0 {
+		return
+	}
+
+	for term, tf := range terms {
+		if ix.postings[term] == nil {
+			ix.postings[term] = map[string]float64{}
+		}
+		ix.postings[term][doc.ID] = tf
+	}
+	ix.docTerms[doc.ID] = terms
+	ix.docLen[doc.ID] = length
+	ix.totalLen += length
+
+	words := make([]string, 0, len(seen))
+	for word := range seen {
+		if ix.words[word] == 0 {
+			ix.sortedWords = nil
+		}
+		ix.words[word]++
+		words = append(words, word)
+	}
+	ix.docWords[doc.ID] = words
+}
+
+func (ix *Index) remove(id string) {
+	terms, ok := ix.docTerms[id]
+	if !ok {
+		return
+	}
+	for term := range terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	ix.totalLen -= ix.docLen[id]
+	delete(ix.docTerms, id)
+	delete(ix.docLen, id)
+
+	for _, word := range ix.docWords[id] {
+		ix.words[word]--
+		if ix.words[word] <= 0 {
+			delete(ix.words, word)
+			ix.sortedWords = nil
+		}
+	}
+	delete(ix.docWords, id)
+}
+
+// Search mencari dokumen yang memuat salah satu kata query, diurutkan dari yang paling relevan.
+// Kata yang tidak ada di index dicocokkan dengan kata terdekat (salah ketik) dengan skor lebih rendah.
+func (ix *Index) Search(query string, limit, offset int) Result {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var result Result
+	tokens := Analyze(query)
+	searchable := 0
+	scores := map[string]float64{}
+	matched := map[string]int{} // Jumlah kata query yang cocok per dokumen
+
+	n := float64(len(ix.docLen))
+	avgLen := 1.0
+	if n > 0 {
+		avgLen = ix.totalLen / n
+	}
+
+	for _, token := range tokens {
+		if token.Stop {
+			continue
+		}
+		searchable++
+
+		found := map[string]bool{}
+		for term, factor := range ix.expand(token) {
+			docs := ix.postings[term]
+			idf := math.Log(1 + (n-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+			for id, tf := range docs {
+				norm := tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*ix.docLen[id]/avgLen))
+				scores[id] += factor * idf * norm
+				found[id] = true
+			}
+		}
+		for id := range found {
+			matched[id]++
+		}
+	}
+	result.DidYouMean = ix.didYouMean(tokens)
+	if searchable == 0 {
+		return result
+	}
+
+	// Dokumen yang memuat semua kata query didahulukan
+	for id := range scores {
+		result.Hits = append(result.Hits, Hit{ID: id, Score: scores[id] * float64(matched[id]) / float64(searchable)})
+	}
+	sort.Slice(result.Hits, func(i, j int) bool {
+		if result.Hits[i].Score != result.Hits[j].Score {
+			return result.Hits[i].Score > result.Hits[j].Score
+		}
+		return result.Hits[i].ID < result.Hits[j].ID
+	})
+
+	result.Total = len(result.Hits)
+	if offset >= len(result.Hits) {
+		result.Hits = []Hit{}
+		return result
+	}
+	result.Hits = result.Hits[offset:]
+	if limit > 0 && len(result.Hits) > limit {
+		result.Hits = result.Hits[:limit]
+	}
+	return result
+}
+
+// expand mengembalikan stem yang dipakai untuk satu kata query beserta pengali skornya
+func (ix *Index) expand(token Token) map[string]float64 {
+	if _, ok := ix.postings[token.Stem]; ok {
+		return map[string]float64{token.Stem: 1}
+	}
+
+	// Kata yang salah ketik sering terpotong stemmer secara berbeda ("sepatuu" → "patuu"),
+	// jadi jaraknya dihitung pada kata asli lalu dipetakan ke stem kata yang benar
+	terms := map[string]float64{}
+	max := maxTypos(token.Word)
+	if max == 0 {
+		return terms
+	}
+	for word := range ix.words {
+		d, ok := withinTypos(token.Word, word, max)
+		if !ok {
+			continue
+		}
+		term := Stem(word)
+		if typoPenalty[d] > terms[term] {
+			terms[term] = typoPenalty[d]
+		}
+	}
+	return terms
+}
+
+// didYouMean mengganti kata query yang tidak dikenal dengan kata terdekat yang paling sering muncul
+func (ix *Index) didYouMean(tokens []Token) string {
+	corrected := make([]string, len(tokens))
+	changed := false
+	for i, token := range tokens {
+		corrected[i] = token.Word
+		if token.Stop || ix.words[token.Word] > 0 {
+			continue
+		}
+		if _, ok := ix.postings[token.Stem]; ok {
+			continue
+		}
+
+		best, bestDistance, bestCount := "", maxTypos(token.Word)+1, 0
+		for word, count := range ix.words {
+			d, ok := withinTypos(token.Word, word, maxTypos(token.Word))
+			if !ok {
+				continue
+			}
+			if d < bestDistance || (d == bestDistance && (count > bestCount || (count == bestCount && word < best))) {
+				best, bestDistance, bestCount = word, d, count
+			}
+		}
+		if best != "" {
+			corrected[i] = best
+			changed = true
+		}
+	}
+	if !changed {
+		return ""
+	}
+	return strings.Join(corrected, " ")
+}
+
+// Suggest melengkapi kata terakhir dari input untuk autocomplete, contoh: "sepatu la" → "sepatu lari".
+// Kata dengan jumlah dokumen terbanyak didahulukan.
+func (ix *Index) Suggest(input string, limit int) []string {
+	words := splitWords(input)
+	suggestions := []string{}
+	if len(words) == 0 || limit <= 0 {
+		return suggestions
+	}
+	prefix := words[len(words)-1]
+	lead := strings.Join(words[:len(words)-1], " ")
+
+	ix.mu.Lock() // sortedWords mungkin perlu dibangun ulang
+	defer ix.mu.Unlock()
+	if ix.sortedWords == nil {
+		ix.sortedWords = make([]string, 0, len(ix.words))
+		for word := range ix.words {
+			ix.sortedWords = append(ix.sortedWords, word)
+		}
+		sort.Strings(ix.sortedWords)
+	}
+
+	var candidates []string
+	for i := sort.SearchStrings(ix.sortedWords, prefix); i < len(ix.sortedWords); i++ {
+		if !strings.HasPrefix(ix.sortedWords[i], prefix) {
+			break
+		}
+		candidates = append(candidates, ix.sortedWords[i])
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return ix.words[candidates[i]] > ix.words[candidates[j]] })
+
+	for _, word := range candidates {
+		if len(suggestions) == limit {
+			break
+		}
+		if lead != "" {
+			word = lead + " " + word
+		}
+		suggestions = append(suggestions, word)
+	}
+	return suggestions
+}
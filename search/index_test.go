@@ -0,0 +1,174 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		// Imbuhan dibuang
+		{"membeli", "beli"},
+		{"dibeli", "beli"},
+		{"pembelian", "beli"},
+		{"menulis", "tulis"},
+		{"memakai", "pakai"},
+		{"menyapu", "sapu"},
+		{"berlari", "lari"},
+		{"bermain", "main"},
+		{"sepatunya", "sepatu"},
+		{"bajumu", "baju"},
+		// Huruf k yang luluh dipulihkan untuk kata dasar yang dikenal
+		{"pengering", "kering"},
+		{"pengeringan", "kering"},
+		// Kata dasar yang kebetulan diawali imbuhan tidak terpotong
+		{"merah", "merah"},
+		{"kering", "kering"},
+		{"keringat", "keringat"},
+		{"keyboard", "keyboard"},
+		{"senang", "senang"},
+		{"sepatu", "sepatu"},
+		{"kemeja", "kemeja"},
+		{"makan", "makan"},
+		// Kata pendek dan kata yang memuat angka tidak diubah
+		{"baju", "baju"},
+		{"iphone15", "iphone15"},
+	}
+	for _, tt := range tests {
+		if got := Stem(tt.word); got != tt.want {
+			t.Errorf("Stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func newTestIndex() *Index {
+	ix := NewIndex()
+	ix.Replace([]Document{
+		{ID: "kaos", Fields: []Field{
+			{Text: "Kaos Olahraga Dry Fit", Weight: 3},
+			{Text: "Kaos menyerap keringat, cepat kering", Weight: 1},
+		}},
+		{ID: "pengering", Fields: []Field{
+			{Text: "Pengering Rambut 350W", Weight: 3},
+			{Text: "Hair dryer lipat untuk traveling", Weight: 1},
+		}},
+		{ID: "sepatu-merah", Fields: []Field{
+			{Text: "Sepatu Lari Merah", Weight: 3},
+			{Text: "Sepatu ringan untuk berlari setiap hari", Weight: 1},
+		}},
+		{ID: "sepatu-hitam", Fields: []Field{
+			{Text: "Sepatu Kulit Hitam", Weight: 3},
+			{Text: "Sepatu formal, cocok dengan celana merah", Weight: 1},
+		}},
+		{ID: "keyboard", Fields: []Field{
+			{Text: "Keyboard Mechanical", Weight: 3},
+			{Text: "Keyboard dengan lampu merah", Weight: 1},
+		}},
+	})
+	return ix
+}
+
+func hitIDs(result Result) []string {
+	ids := make([]string, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestSearchRanking(t *testing.T) {
+	ix := newTestIndex()
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Kecocokan di nama (bobot 3) lebih relevan dari kecocokan di deskripsi
+		{"merah", []string{"sepatu-merah", "keyboard", "sepatu-hitam"}},
+		// Dokumen yang memuat semua kata query didahulukan
+		{"sepatu merah", []string{"sepatu-merah", "sepatu-hitam", "keyboard"}},
+		// Bentuk berimbuhan bertemu dengan kata dasarnya
+		{"kering", []string{"pengering", "kaos"}},
+		{"lari", []string{"sepatu-merah"}},
+		// Stop word saja tidak mencari apa pun
+		{"dengan", []string{}},
+	}
+	for _, tt := range tests {
+		result := ix.Search(tt.query, 10, 0)
+		if got := hitIDs(result); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+		if result.Total != len(tt.want) {
+			t.Errorf("Search(%q).Total = %d, want %d", tt.query, result.Total, len(tt.want))
+		}
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	ix := newTestIndex()
+	result := ix.Search("merah", 1, 1)
+	if got := hitIDs(result); !reflect.DeepEqual(got, []string{"keyboard"}) || result.Total != 3 {
+		t.Errorf("hits = %v, total = %d, want [keyboard] and 3", got, result.Total)
+	}
+	if result := ix.Search("merah", 10, 5); len(result.Hits) != 0 {
+		t.Errorf("hits past the end = %v, want none", hitIDs(result))
+	}
+}
+
+func TestSearchTypoExpansion(t *testing.T) {
+	ix := newTestIndex()
+	tests := []struct {
+		query      string
+		want       []string
+		didYouMean string
+	}{
+		{"keybord", []string{"keyboard"}, "keyboard"},
+		{"sepatuu lari", []string{"sepatu-merah", "sepatu-hitam"}, "sepatu lari"},
+		{"rambot", []string{"pengering"}, "rambut"},
+		// Kata pendek tidak dikoreksi
+		{"kao", []string{}, ""},
+	}
+	for _, tt := range tests {
+		result := ix.Search(tt.query, 10, 0)
+		if got := hitIDs(result); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+		if result.DidYouMean != tt.didYouMean {
+			t.Errorf("Search(%q).DidYouMean = %q, want %q", tt.query, result.DidYouMean, tt.didYouMean)
+		}
+	}
+
+	// Kecocokan tepat lebih relevan dari kecocokan hasil koreksi salah ketik
+	exact := ix.Search("keyboard", 1, 0).Hits[0].Score
+	typo := ix.Search("keybord", 1, 0).Hits[0].Score
+	if typo >= exact {
+		t.Errorf("typo score %v >= exact score %v", typo, exact)
+	}
+}
+
+func TestIndexPutAndDelete(t *testing.T) {
+	ix := newTestIndex()
+	ix.Put(Document{ID: "keyboard", Fields: []Field{{Text: "Mouse Wireless", Weight: 3}}})
+	if got := hitIDs(ix.Search("keyboard", 10, 0)); len(got) != 0 {
+		t.Errorf("replaced document still matches old text: %v", got)
+	}
+	ix.Delete("keyboard")
+	if ix.Len() != 4 {
+		t.Errorf("Len = %d, want 4", ix.Len())
+	}
+	if got := ix.Suggest("mou", 5); len(got) != 0 {
+		t.Errorf("Suggest after Delete = %v, want none", got)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	ix := newTestIndex()
+	if got, want := ix.Suggest("sepatu la", 5), []string{"sepatu lampu", "sepatu lari"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suggest = %v, want %v", got, want)
+	}
+	// "sepatu" ada di dua dokumen, jadi didahulukan dari "setiap"
+	if got := ix.Suggest("se", 1); !reflect.DeepEqual(got, []string{"sepatu"}) {
+		t.Errorf("Suggest = %v, want [sepatu]", got)
+	}
+}
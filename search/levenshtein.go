@@ -0,0 +1,57 @@
+package search
+
+import "unicode/utf8"
+
+// levenshtein menghitung jumlah minimum sisip, hapus atau ganti huruf untuk mengubah a menjadi b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// maxTypos adalah jumlah salah ketik yang ditoleransi sesuai panjang kata
+func maxTypos(word string) int {
+	switch n := utf8.RuneCountInString(word); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// withinTypos memeriksa apakah a dan b berjarak paling banyak max, dengan penyaringan panjang lebih dulu
+func withinTypos(a, b string, max int) (int, bool) {
+	diff := utf8.RuneCountInString(a) - utf8.RuneCountInString(b)
+	if diff > max || -diff > max {
+		return 0, false
+	}
+	d := levenshtein(a, b)
+	return d, d <= max
+}
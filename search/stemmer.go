@@ -0,0 +1,130 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// rootWords adalah kata dasar umum di katalog yang kebetulan diawali imbuhan (se-, ke-, pe-, me-, ...).
+// Stemmer ini tidak memakai kamus lengkap, jadi kata-kata ini dikecualikan agar tidak terpotong.
+var rootWords = toSet(
+	"sepatu", "sepeda", "selimut", "sendok", "sendal", "senter", "setrika", "serum", "seprai", "sepre",
+	"kemeja", "kerudung", "kerupuk", "kecap", "keju", "kertas", "kelambu", "keripik", "keranjang", "kemben",
+	"kebaya", "kenari", "kering", "keringat", "keyboard", "kipas", "kursi",
+	"celana", "meja", "beras", "bedak", "bantal", "baterai", "peci", "pena", "pensil", "perak", "permen",
+	"terigu", "terompet", "tenda", "teko", "telur", "televisi", "dompet", "dinding", "diapers",
+	"mentega", "merica", "minyak",
+)
+
+const (
+	minStemLength       = 3 // Sisa kata minimum setelah partikel atau kata ganti dibuang
+	minRootLength       = 4 // Sisa kata minimum setelah awalan dibuang, agar "merah" tidak menjadi "rah"
+	minDerivationLength = 4 // Sisa kata minimum setelah akhiran -kan/-an, agar "makan" tidak menjadi "mak"
+)
+
+// Stem mengembalikan kata dasar bahasa Indonesia dengan aturan sederhana ala Nazief-Adriani:
+// partikel (-lah, -kah, -pun), kata ganti (-ku, -mu, -nya), awalan (me-, di-, ber-, ter-, pe-, ke-, se-)
+// dan akhiran (-kan, -an). Tanpa kamus hasilnya tidak selalu kata dasar yang benar, tetapi
+// konsisten untuk index dan query sehingga "membeli", "dibeli" dan "pembelian" tetap bertemu.
+func Stem(word string) string {
+	if rootWords[word] || utf8.RuneCountInString(word) <= 4 || strings.IndexFunc(word, unicode.IsDigit) >= 0 {
+		return word
+	}
+
+	stem := trimSuffix(word, minStemLength, "lah", "kah", "tah", "pun")
+	stem = trimSuffix(stem, minStemLength, "nya", "ku", "mu")
+	if rootWords[stem] {
+		return stem
+	}
+
+	for i := 0; i < 2; i++ { // Awalan bisa bertumpuk, contoh: diper-, memper-
+		next, ok := trimPrefix(stem)
+		if !ok {
+			break
+		}
+		stem = next
+		if knownRoot(stem) {
+			return trimSuffix(stem, minDerivationLength, "kan", "an")
+		}
+	}
+
+	// Akhiran -i tidak dibuang karena terlalu sering bagian dari kata dasar (kopi, lari, dasi)
+	return trimSuffix(stem, minDerivationLength, "kan", "an")
+}
+
+// knownRoot memeriksa apakah word, dengan atau tanpa akhiran -kan/-an, ada di rootWords
+func knownRoot(word string) bool {
+	return rootWords[word] || rootWords[trimSuffix(word, minDerivationLength, "kan", "an")]
+}
+
+// rootLike memeriksa apakah sisa kata setelah awalan dibuang masih layak menjadi kata dasar:
+// minimal minRootLength huruf dan dua suku kata (dua huruf vokal). Kata dasar bahasa Indonesia
+// hampir selalu bersuku dua atau lebih, jadi "kering" tidak dipotong menjadi "ring".
+func rootLike(stem string) bool {
+	if utf8.RuneCountInString(stem) < minRootLength {
+		return false
+	}
+	vowels := 0
+	for _, r := range stem {
+		if strings.ContainsRune("aiueo", r) {
+			vowels++
+		}
+	}
+	return vowels >= 2
+}
+
+// trimSuffix membuang akhiran pertama yang cocok selama sisa kata minimal min huruf
+func trimSuffix(word string, min int, suffixes ...string) string {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(word, suffix) && utf8.RuneCountInString(word)-len(suffix) >= min {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// trimPrefix membuang satu awalan beserta perubahan bunyinya, contoh: menulis → tulis, memakai → pakai
+func trimPrefix(word string) (string, bool) {
+	rest := func(prefix string) string { return strings.TrimPrefix(word, prefix) }
+	vowelAfter := func(prefix string) bool {
+		r, _ := utf8.DecodeRuneInString(rest(prefix))
+		return strings.ContainsRune("aiueo", r)
+	}
+
+	var stem string
+	switch {
+	case strings.HasPrefix(word, "meny"), strings.HasPrefix(word, "peny"):
+		stem = "s" + word[4:]
+	case strings.HasPrefix(word, "meng"), strings.HasPrefix(word, "peng"):
+		stem = word[4:]
+		// Huruf k luluh sebelum vokal (pengering → kering); tanpa kamus hanya dipulihkan untuk rootWords
+		if vowelAfter(word[:4]) && knownRoot("k"+stem) {
+			stem = "k" + stem
+		}
+	case strings.HasPrefix(word, "mem"), strings.HasPrefix(word, "pem"):
+		if vowelAfter(word[:3]) {
+			stem = "p" + word[3:]
+		} else {
+			stem = word[3:]
+		}
+	case strings.HasPrefix(word, "men"), strings.HasPrefix(word, "pen"):
+		if vowelAfter(word[:3]) {
+			stem = "t" + word[3:]
+		} else {
+			stem = word[3:]
+		}
+	case strings.HasPrefix(word, "ber"), strings.HasPrefix(word, "ter"), strings.HasPrefix(word, "per"):
+		stem = word[3:]
+	case strings.HasPrefix(word, "me"), strings.HasPrefix(word, "pe"),
+		strings.HasPrefix(word, "di"), strings.HasPrefix(word, "ke"), strings.HasPrefix(word, "se"):
+		stem = word[2:]
+	default:
+		return word, false
+	}
+
+	if !rootLike(stem) {
+		return word, false
+	}
+	return stem, true
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/veritrans/go-midtrans"
+)
+
+// MidtransProvider memakai Snap untuk membuat pembayaran dan Core API untuk status dan refund
+type MidtransProvider struct {
+	client    midtrans.Client
+	serverKey string
+}
+
+// NewMidtrans membuat provider Midtrans; production false berarti memakai Sandbox
+func NewMidtrans(serverKey, clientKey string, production bool) *MidtransProvider {
+	client := midtrans.NewClient()
+	client.ServerKey = serverKey
+	client.ClientKey = clientKey
+	client.APIEnvType = midtrans.Sandbox
+	if production {
+		client.APIEnvType = midtrans.Production
+	}
+	return &MidtransProvider{client: client, serverKey: serverKey}
+}
+
+// MidtransFromEnv membaca MIDTRANS_SERVER_KEY, MIDTRANS_CLIENT_KEY dan MIDTRANS_ENV ("production" atau sandbox)
+func MidtransFromEnv() (*MidtransProvider, error) {
+	serverKey := os.Getenv("MIDTRANS_SERVER_KEY")
+	if serverKey == "" {
+		return nil, errors.New("MIDTRANS_SERVER_KEY is not set (use PAYMENT_PROVIDER=mock for local development)")
+	}
+	production := os.Getenv("MIDTRANS_ENV") == "production"
+	return NewMidtrans(serverKey, os.Getenv("MIDTRANS_CLIENT_KEY"), production), nil
+}
+
+func (p *MidtransProvider) Name() string { return "midtrans" }
+
+func (p *MidtransProvider) CreateCharge(ctx context.Context, req ChargeRequest) (Charge, error) {
+	items := make([]midtrans.ItemDetail, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, midtrans.ItemDetail{
+			ID:    item.ID,
+			Name:  item.Name,
+			Qty:   int32(item.Quantity),
+			Price: int64(item.Price),
+		})
+	}
+
+	snapReq := &midtrans.SnapReq{
+		TransactionDetails: midtrans.TransactionDetails{
+			OrderID:  req.Reference,
+			GrossAmt: int64(req.Amount),
+		},
+		Items: &items,
+	}
+	if minutes := int64(req.ExpiresIn / time.Minute); minutes > 0 {
+		snapReq.Expiry = &midtrans.ExpiryDetail{Unit: "minute", Duration: minutes}
+	}
+
+	gateway := midtrans.SnapGateway{Client: p.client}
+	resp, err := gateway.GetToken(snapReq)
+	if err != nil {
+		return Charge{}, err
+	}
+	if resp.Token == "" {
+		return Charge{}, fmt.Errorf("midtrans: %v", resp.ErrorMessages)
+	}
+	return Charge{Token: resp.Token, RedirectURL: resp.RedirectURL}, nil
+}
+
+func (p *MidtransProvider) GetStatus(ctx context.Context, reference string) (PaymentStatus, error) {
+	gateway := midtrans.CoreGateway{Client: p.client}
+	resp, err := gateway.Status(reference)
+	if err != nil {
+		return PaymentStatus{}, err
+	}
+	if resp.StatusCode == "404" {
+		return PaymentStatus{}, ErrPaymentNotFound
+	}
+	return midtransStatus(resp.OrderID, resp.TransactionID, resp.TransactionStatus, resp.FraudStatus, resp.GrossAmount), nil
+}
+
+func (p *MidtransProvider) Refund(ctx context.Context, req RefundRequest) error {
+	gateway := midtrans.CoreGateway{Client: p.client}
+	resp, err := gateway.Refund(req.Reference, &midtrans.RefundReq{
+		RefundKey: req.Key,
+		Amount:    int64(req.Amount),
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != "200" {
+		return fmt.Errorf("midtrans refund failed: %s %s", resp.StatusCode, resp.StatusMessage)
+	}
+	return nil
+}
+
+func (p *MidtransProvider) Expire(ctx context.Context, reference string) error {
+	gateway := midtrans.CoreGateway{Client: p.client}
+	resp, err := gateway.Expire(reference)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case "200", "407": // 407: transaksi berhasil dibuat kedaluwarsa
+		return nil
+	case "404":
+		return ErrPaymentNotFound
+	}
+	return fmt.Errorf("midtrans expire failed: %s %s", resp.StatusCode, resp.StatusMessage)
+}
+
+// MidtransNotification adalah body HTTP notification yang dikirim Midtrans setelah status transaksi berubah
+type MidtransNotification struct {
+	TransactionID     string `json:"transaction_id"`
+	TransactionStatus string `json:"transaction_status"`
+	TransactionTime   string `json:"transaction_time"`
+	FraudStatus       string `json:"fraud_status"`
+	PaymentType       string `json:"payment_type"`
+	OrderID           string `json:"order_id"`
+	StatusCode        string `json:"status_code"`
+	GrossAmount       string `json:"gross_amount"`
+	SignatureKey      string `json:"signature_key"`
+}
+
+// MidtransSignature menghitung signature_key: SHA512(order_id + status_code + gross_amount + server key)
+func MidtransSignature(serverKey, orderID, statusCode, grossAmount string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func (p *MidtransProvider) ParseNotification(body []byte) (PaymentStatus, error) {
+	var n MidtransNotification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return PaymentStatus{}, err
+	}
+
+	// Tolak notification yang tidak ditandatangani dengan server key kita
+	expected := MidtransSignature(p.serverKey, n.OrderID, n.StatusCode, n.GrossAmount)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(n.SignatureKey)) != 1 {
+		return PaymentStatus{Reference: n.OrderID, RawStatus: n.TransactionStatus}, ErrInvalidSignature
+	}
+	return midtransStatus(n.OrderID, n.TransactionID, n.TransactionStatus, n.FraudStatus, n.GrossAmount), nil
+}
+
+// midtransStatus memetakan transaction_status/fraud_status Midtrans ke status yang dinormalisasi
+func midtransStatus(orderID, transactionID, transactionStatus, fraudStatus, grossAmount string) PaymentStatus {
+	status := PaymentStatus{
+		Reference:     orderID,
+		TransactionID: transactionID,
+		RawStatus:     transactionStatus,
+		FraudStatus:   fraudStatus,
+		Status:        PaymentPending,
+	}
+	if gross, err := strconv.ParseFloat(grossAmount, 64); err == nil {
+		status.GrossAmount = int(math.Round(gross))
+	}
+
+	switch transactionStatus {
+	case "capture":
+		switch fraudStatus {
+		case "accept", "":
+			status.Status = PaymentPaid
+		case "challenge":
+			status.Status = PaymentChallenge
+		default:
+			status.Status = PaymentFailed
+		}
+	case "settlement":
+		status.Status = PaymentPaid
+	case "deny", "cancel", "failure":
+		status.Status = PaymentFailed
+	case "expire":
+		status.Status = PaymentExpired
+	case "refund":
+		status.Status = PaymentRefunded
+	case "partial_refund":
+		status.Status = PaymentPartiallyRefunded
+	}
+	return status
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Hasil pembayaran yang disimulasikan MockProvider (MOCK_PAYMENT_OUTCOME)
+const (
+	MockOutcomeSuccess = "success" // Dibayar setelah Delay
+	MockOutcomeFailure = "failure" // Ditolak setelah Delay
+	MockOutcomeExpire  = "expire"  // Kedaluwarsa setelah Delay
+	MockOutcomePending = "pending" // Tetap pending sampai ada notification manual
+	MockOutcomeError   = "error"   // CreateCharge langsung gagal
+)
+
+// MockProvider mensimulasikan payment gateway sepenuhnya di memori untuk pengembangan dan pengujian offline.
+// Setelah Delay, hasil sesuai Outcome dikirim ke notifier seperti notification dari gateway sungguhan.
+// Notification manual bisa dikirim ke /payments/mock/notification dengan body
+// {"order_id": "<reference>", "status": "paid"} tanpa tanda tangan.
+type MockProvider struct {
+	outcome string
+	delay   time.Duration
+
+	mu       sync.Mutex
+	payments map[string]*PaymentStatus
+	refunds  map[string]int  // Total refund per reference
+	refunded map[string]bool // Refund key yang sudah diproses
+	notifier func(PaymentStatus)
+}
+
+// NewMockProvider membuat mock dengan outcome default "success"
+func NewMockProvider(outcome string, delay time.Duration) (*MockProvider, error) {
+	switch outcome {
+	case "":
+		outcome = MockOutcomeSuccess
+	case MockOutcomeSuccess, MockOutcomeFailure, MockOutcomeExpire, MockOutcomePending, MockOutcomeError:
+	default:
+		return nil, fmt.Errorf("unknown MOCK_PAYMENT_OUTCOME %q", outcome)
+	}
+	return &MockProvider{
+		outcome:  outcome,
+		delay:    delay,
+		payments: map[string]*PaymentStatus{},
+		refunds:  map[string]int{},
+		refunded: map[string]bool{},
+	}, nil
+}
+
+// SetNotifier mendaftarkan fungsi yang menerima perubahan status hasil simulasi
+func (p *MockProvider) SetNotifier(notifier func(PaymentStatus)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.notifier = notifier
+}
+
+func (p *MockProvider) Name() string { return "mock" }
+
+func (p *MockProvider) CreateCharge(ctx context.Context, req ChargeRequest) (Charge, error) {
+	if p.outcome == MockOutcomeError {
+		return Charge{}, errors.New("mock: charge declined")
+	}
+
+	p.mu.Lock()
+	p.payments[req.Reference] = &PaymentStatus{
+		Reference:     req.Reference,
+		TransactionID: "mock-" + req.Reference,
+		Status:        PaymentPending,
+		RawStatus:     PaymentPending,
+		GrossAmount:   req.Amount,
+	}
+	p.mu.Unlock()
+
+	var final string
+	switch p.outcome {
+	case MockOutcomeSuccess:
+		final = PaymentPaid
+	case MockOutcomeFailure:
+		final = PaymentFailed
+	case MockOutcomeExpire:
+		final = PaymentExpired
+	}
+	if final != "" {
+		// Pembayaran yang sudah di-Expire sebelum Delay tidak ikut berubah
+		time.AfterFunc(p.delay, func() { p.settle(req.Reference, final, PaymentPending) })
+	}
+
+	return Charge{Token: "mock-" + req.Reference}, nil
+}
+
+// settle mengubah status pembayaran lalu memberi tahu notifier.
+// Jika from diisi, status hanya diubah selama pembayaran masih berstatus from.
+func (p *MockProvider) settle(reference, status, from string) {
+	p.mu.Lock()
+	payment, ok := p.payments[reference]
+	if !ok || (from != "" && payment.Status != from) {
+		p.mu.Unlock()
+		return
+	}
+	payment.Status = status
+	payment.RawStatus = status
+	snapshot := *payment
+	notifier := p.notifier
+	p.mu.Unlock()
+
+	if notifier != nil {
+		notifier(snapshot)
+	} else {
+		log.Println("mock payment", reference, "is now", status, "but no notifier is set")
+	}
+}
+
+func (p *MockProvider) GetStatus(ctx context.Context, reference string) (PaymentStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	payment, ok := p.payments[reference]
+	if !ok {
+		return PaymentStatus{}, ErrPaymentNotFound
+	}
+	return *payment, nil
+}
+
+func (p *MockProvider) Refund(ctx context.Context, req RefundRequest) error {
+	p.mu.Lock()
+	payment, ok := p.payments[req.Reference]
+	if !ok {
+		p.mu.Unlock()
+		return ErrPaymentNotFound
+	}
+	if req.Key != "" && p.refunded[req.Key] {
+		p.mu.Unlock()
+		return nil
+	}
+	if payment.Status != PaymentPaid && payment.Status != PaymentPartiallyRefunded {
+		p.mu.Unlock()
+		return fmt.Errorf("mock: cannot refund payment in status %s", payment.Status)
+	}
+	if req.Amount <= 0 || p.refunds[req.Reference]+req.Amount > payment.GrossAmount {
+		p.mu.Unlock()
+		return fmt.Errorf("mock: invalid refund amount %d", req.Amount)
+	}
+	p.refunds[req.Reference] += req.Amount
+	if req.Key != "" {
+		p.refunded[req.Key] = true
+	}
+	status := PaymentPartiallyRefunded
+	if p.refunds[req.Reference] == payment.GrossAmount {
+		status = PaymentRefunded
+	}
+	p.mu.Unlock()
+
+	go p.settle(req.Reference, status, "")
+	return nil
+}
+
+func (p *MockProvider) Expire(ctx context.Context, reference string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	payment, ok := p.payments[reference]
+	if !ok {
+		return ErrPaymentNotFound
+	}
+	if payment.Status != PaymentPending && payment.Status != PaymentChallenge {
+		return fmt.Errorf("mock: cannot expire payment in status %s", payment.Status)
+	}
+	payment.Status = PaymentExpired
+	payment.RawStatus = PaymentExpired
+	return nil
+}
+
+func (p *MockProvider) ParseNotification(body []byte) (PaymentStatus, error) {
+	var n struct {
+		OrderID string `json:"order_id"`
+		Status  string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &n); err != nil {
+		return PaymentStatus{}, err
+	}
+	switch n.Status {
+	case PaymentPending, PaymentChallenge, PaymentPaid, PaymentFailed, PaymentExpired, PaymentRefunded, PaymentPartiallyRefunded:
+	default:
+		return PaymentStatus{}, fmt.Errorf("mock: unknown status %q", n.Status)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	payment, ok := p.payments[n.OrderID]
+	if !ok {
+		return PaymentStatus{}, ErrPaymentNotFound
+	}
+	payment.Status = n.Status
+	payment.RawStatus = n.Status
+	return *payment, nil
+}
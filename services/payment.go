@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Status pembayaran yang sudah dinormalisasi dari istilah masing-masing payment gateway
+const (
+	PaymentPending           = "pending"
+	PaymentChallenge         = "challenge" // Ditahan untuk review fraud
+	PaymentPaid              = "paid"
+	PaymentFailed            = "failed"
+	PaymentExpired           = "expired"
+	PaymentRefunded          = "refunded"
+	PaymentPartiallyRefunded = "partial_refund"
+)
+
+var (
+	// ErrInvalidSignature dikembalikan ParseNotification jika notification tidak ditandatangani gateway
+	ErrInvalidSignature = errors.New("payment: invalid notification signature")
+	// ErrPaymentNotFound dikembalikan jika gateway tidak mengenal reference tersebut
+	ErrPaymentNotFound = errors.New("payment: transaction not found")
+)
+
+// PaymentProvider adalah payment gateway yang dipakai checkout.
+// Reference adalah ID transaksi dari sisi kita (payment_reference checkout), bukan ID dari gateway.
+type PaymentProvider interface {
+	// Name dipakai di URL notification: /payments/<name>/notification
+	Name() string
+	CreateCharge(ctx context.Context, req ChargeRequest) (Charge, error)
+	GetStatus(ctx context.Context, reference string) (PaymentStatus, error)
+	Refund(ctx context.Context, req RefundRequest) error
+	// Expire membatalkan transaksi yang masih pending agar tidak bisa dibayar lagi
+	Expire(ctx context.Context, reference string) error
+	// ParseNotification memverifikasi lalu membaca body HTTP notification dari gateway
+	ParseNotification(body []byte) (PaymentStatus, error)
+}
+
+// ChargeItem adalah satu baris tagihan; jumlah Price*Quantity semua item harus sama dengan Amount
+type ChargeItem struct {
+	ID       string
+	Name     string
+	Quantity int
+	Price    int
+}
+
+type ChargeRequest struct {
+	Reference string
+	Amount    int
+	Items     []ChargeItem
+	ExpiresIn time.Duration // Batas waktu pembayaran di gateway; 0 memakai default gateway
+}
+
+// Charge berisi data yang dibutuhkan frontend untuk membuka halaman pembayaran
+type Charge struct {
+	Token       string
+	RedirectURL string
+}
+
+// RefundRequest mengembalikan sebagian atau seluruh pembayaran. Key membuat refund idempotent di gateway.
+type RefundRequest struct {
+	Reference string
+	Key       string
+	Amount    int
+	Reason    string
+}
+
+// PaymentStatus adalah status transaksi dari GetStatus atau ParseNotification
+type PaymentStatus struct {
+	Reference     string
+	TransactionID string
+	Status        string // Salah satu konstanta Payment*
+	RawStatus     string // Status asli dari gateway, contoh: transaction_status Midtrans
+	FraudStatus   string
+	GrossAmount   int
+}
+
+// NewPaymentProvider memilih payment gateway dari PAYMENT_PROVIDER: "midtrans" (default) atau "mock".
+// Midtrans dikonfigurasi lewat MIDTRANS_SERVER_KEY, MIDTRANS_CLIENT_KEY dan MIDTRANS_ENV;
+// mock lewat MOCK_PAYMENT_OUTCOME dan MOCK_PAYMENT_DELAY.
+func NewPaymentProvider() (PaymentProvider, error) {
+	switch provider := os.Getenv("PAYMENT_PROVIDER"); provider {
+	case "", "midtrans":
+		return MidtransFromEnv()
+	case "mock":
+		delay := 5 * time.Second
+		if value := os.Getenv("MOCK_PAYMENT_DELAY"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid MOCK_PAYMENT_DELAY: %w", err)
+			}
+			delay = parsed
+		}
+		return NewMockProvider(os.Getenv("MOCK_PAYMENT_OUTCOME"), delay)
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q", provider)
+	}
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PrivateFilesURL adalah prefix URL tempat bucket privat local disajikan (GET /files/private/*)
+const PrivateFilesURL = "files/private"
+
+// LocalStore menyimpan file di direktori lokal. Tanpa signingKey bucket-nya publik dan URL-nya
+// berada di bawah baseURL (disajikan app.Static); dengan signingKey URL-nya ditandatangani HMAC.
+type LocalStore struct {
+	dir        string
+	baseURL    string
+	signingKey []byte
+	now        func() time.Time
+}
+
+// NewLocalStore membuat bucket di dir; signingKey nil berarti bucket publik
+func NewLocalStore(dir, baseURL string, signingKey []byte) *LocalStore {
+	return &LocalStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/"), signingKey: signingKey, now: time.Now}
+}
+
+// path mengubah key menjadi path file di dalam dir
+func (s *LocalStore) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put menulis ke file sementara lalu me-rename-nya, sehingga file yang terlihat selalu lengkap
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// Permission default CreateTemp 0600; file publik harus bisa dibaca web server lain
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// URL untuk bucket privat berbentuk <baseURL>/<key>?expires=<unix>&signature=<hmac>
+func (s *LocalStore) URL(key string, ttl time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	location := s.baseURL + "/" + (&url.URL{Path: key}).EscapedPath()
+	if s.signingKey == nil {
+		return location, nil
+	}
+
+	expires := strconv.FormatInt(s.now().Add(ttl).Unix(), 10)
+	query := url.Values{"expires": {expires}, "signature": {s.sign(key, expires)}}
+	return location + "?" + query.Encode(), nil
+}
+
+// Verify memeriksa expires dan signature dari URL yang dibuat URL
+func (s *LocalStore) Verify(key, expires, signature string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || s.signingKey == nil || s.now().Unix() > unix {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *LocalStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir(), "/uploads/", nil)
+
+	if err := store.Put(ctx, "products/a.jpg", []byte("jpeg"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	file, err := store.Get(ctx, "products/a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "jpeg" {
+		t.Errorf("data = %q, want jpeg", data)
+	}
+	if ok, err := store.Exists(ctx, "products/a.jpg"); !ok || err != nil {
+		t.Errorf("Exists = %v, %v, want true", ok, err)
+	}
+
+	if err := store.Delete(ctx, "products/a.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "products/a.jpg"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+	if _, err := store.Get(ctx, "products/a.jpg"); err != ErrNotFound {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if ok, err := store.Exists(ctx, "products/a.jpg"); ok || err != nil {
+		t.Errorf("Exists after Delete = %v, %v, want false", ok, err)
+	}
+}
+
+func TestLocalStoreRejectsKeysOutsideTheBucket(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir(), "/uploads", nil)
+	for _, key := range []string{"", ".", "..", "../secret", "products/../../secret", "/etc/passwd", `products\..\secret`} {
+		if err := store.Put(ctx, key, []byte("x"), ""); err != ErrInvalidKey {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := store.URL(key, time.Minute); err != ErrInvalidKey {
+			t.Errorf("URL(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestLocalStorePublicURL(t *testing.T) {
+	store := NewLocalStore(t.TempDir(), "/uploads/", nil)
+	location, err := store.URL("products/a b.jpg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location != "/uploads/products/a%20b.jpg" {
+		t.Errorf("URL = %q, want a permanent URL without signature", location)
+	}
+	if err := store.Verify("products/a b.jpg", "9999999999", ""); err != ErrInvalidSignature {
+		t.Errorf("Verify on a public bucket = %v, want ErrInvalidSignature", err)
+	}
+}
+
+// signedQuery membuat signed URL untuk key dan mengembalikan expires dan signature-nya
+func signedQuery(t *testing.T, store *LocalStore, key string, ttl time.Duration) (string, url.Values) {
+	t.Helper()
+	location, err := store.URL(key, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Path, u.Query()
+}
+
+func TestLocalStoreSignedURL(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewLocalStore(t.TempDir(), "/"+PrivateFilesURL, []byte("secret"))
+	store.now = func() time.Time { return now }
+
+	path, query := signedQuery(t, store, "seller_photos/ktp.jpg", 10*time.Minute)
+	if path != "/files/private/seller_photos/ktp.jpg" {
+		t.Errorf("path = %q", path)
+	}
+	expires, signature := query.Get("expires"), query.Get("signature")
+	if expires != "1700000600" || signature == "" {
+		t.Fatalf("query = %v, want expires 10 minutes from now and a signature", query)
+	}
+	if err := store.Verify("seller_photos/ktp.jpg", expires, signature); err != nil {
+		t.Errorf("Verify = %v, want nil", err)
+	}
+	// Key yang setara setelah dinormalisasi memakai signature yang sama
+	if err := store.Verify("seller_photos/./ktp.jpg", expires, signature); err != nil {
+		t.Errorf("Verify of a normalized key = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name, key, expires, signature string
+	}{
+		{"other key", "seller_photos/other.jpg", expires, signature},
+		{"extended expiry", "seller_photos/ktp.jpg", "1800000000", signature},
+		{"tampered signature", "seller_photos/ktp.jpg", expires, strings.Repeat("0", len(signature))},
+		{"missing signature", "seller_photos/ktp.jpg", expires, ""},
+		{"invalid expiry", "seller_photos/ktp.jpg", "soon", signature},
+	}
+	for _, tt := range tests {
+		if err := store.Verify(tt.key, tt.expires, tt.signature); err != ErrInvalidSignature {
+			t.Errorf("%s: Verify = %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+
+	// Signature dari key lain tidak berlaku
+	other := NewLocalStore(t.TempDir(), "/"+PrivateFilesURL, []byte("another-secret"))
+	other.now = store.now
+	if err := other.Verify("seller_photos/ktp.jpg", expires, signature); err != ErrInvalidSignature {
+		t.Errorf("Verify with another key = %v, want ErrInvalidSignature", err)
+	}
+
+	now = now.Add(11 * time.Minute)
+	if err := store.Verify("seller_photos/ktp.jpg", expires, signature); err != ErrInvalidSignature {
+		t.Errorf("Verify after expiry = %v, want ErrInvalidSignature", err)
+	}
+}
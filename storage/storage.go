@@ -0,0 +1,125 @@
+// Package storage menyimpan file unggahan (gambar produk, foto bukti retur, foto KYC seller)
+// di luar proses aplikasi, sehingga beberapa instance bisa berbagi file yang sama.
+//
+// File dialamatkan dengan key berbentuk path relatif, contoh "products/<hash>.jpg". Bucket publik
+// bisa dibaca siapa saja lewat URL permanen; bucket privat hanya lewat signed URL yang kedaluwarsa.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound dikembalikan Get jika key tidak ada
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrInvalidKey dikembalikan untuk key kosong, absolut atau yang keluar dari bucket ("..")
+	ErrInvalidKey = errors.New("storage: invalid key")
+	// ErrInvalidSignature dikembalikan Verify jika signed URL palsu atau sudah kedaluwarsa
+	ErrInvalidSignature = errors.New("storage: invalid or expired signature")
+)
+
+// BlobStore adalah satu bucket penyimpanan file
+type BlobStore interface {
+	// Put menyimpan data di key; key yang sudah ada ditimpa
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete menghapus key; key yang tidak ada bukan error
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	// URL mengembalikan alamat file untuk client. Di bucket publik URL-nya permanen dan ttl diabaikan;
+	// di bucket privat URL ditandatangani dan hanya berlaku selama ttl.
+	URL(key string, ttl time.Duration) (string, error)
+}
+
+// Stores adalah bucket yang dipakai aplikasi
+type Stores struct {
+	Public  BlobStore // Gambar produk dan foto bukti retur
+	Private BlobStore // Foto KYC seller, hanya lewat signed URL
+}
+
+// DefaultSignedURLTTL adalah masa berlaku signed URL jika STORAGE_SIGNED_URL_TTL tidak diisi
+const DefaultSignedURLTTL = 15 * time.Minute
+
+// SignedURLTTL membaca masa berlaku signed URL dari STORAGE_SIGNED_URL_TTL, contoh "10m"
+func SignedURLTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("STORAGE_SIGNED_URL_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return DefaultSignedURLTTL
+}
+
+// FromEnv memilih backend dari STORAGE_BACKEND: "local" (default) atau "s3".
+//
+// Local menyimpan bucket publik di STORAGE_PUBLIC_DIR (default "uploads", disajikan app.Static di
+// STORAGE_PUBLIC_URL, default "uploads") dan bucket privat di STORAGE_PRIVATE_DIR (default
+// "storage/private") yang hanya bisa dibaca lewat GET /files/private/* dengan signature dari
+// STORAGE_SIGNING_KEY (default JWT_SECRET_KEY). Backend local hanya cocok untuk satu instance.
+//
+// S3 dikonfigurasi lewat S3_ENDPOINT, S3_REGION, S3_ACCESS_KEY, S3_SECRET_KEY, S3_PUBLIC_BUCKET,
+// S3_PRIVATE_BUCKET, S3_PUBLIC_URL (opsional, contoh CDN) dan S3_PATH_STYLE (default true, dibutuhkan MinIO).
+// Bucket publik harus diberi policy baca anonim di sisi S3/MinIO; bucket privat tidak.
+func FromEnv() (Stores, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		signingKey := os.Getenv("STORAGE_SIGNING_KEY")
+		if signingKey == "" {
+			signingKey = os.Getenv("JWT_SECRET_KEY")
+		}
+		if signingKey == "" {
+			return Stores{}, errors.New("STORAGE_SIGNING_KEY or JWT_SECRET_KEY is required for local private storage")
+		}
+		return Stores{
+			Public:  NewLocalStore(envOr("STORAGE_PUBLIC_DIR", "uploads"), envOr("STORAGE_PUBLIC_URL", "uploads"), nil),
+			Private: NewLocalStore(envOr("STORAGE_PRIVATE_DIR", "storage/private"), PrivateFilesURL, []byte(signingKey)),
+		}, nil
+	case "s3":
+		base := S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    envOr("S3_REGION", "us-east-1"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PathStyle: os.Getenv("S3_PATH_STYLE") != "false",
+		}
+		public, private := base, base
+		public.Bucket, public.PublicURL = os.Getenv("S3_PUBLIC_BUCKET"), os.Getenv("S3_PUBLIC_URL")
+		private.Bucket, private.Private = os.Getenv("S3_PRIVATE_BUCKET"), true
+
+		publicStore, err := NewS3Store(public)
+		if err != nil {
+			return Stores{}, fmt.Errorf("public bucket: %w", err)
+		}
+		privateStore, err := NewS3Store(private)
+		if err != nil {
+			return Stores{}, fmt.Errorf("private bucket: %w", err)
+		}
+		return Stores{Public: publicStore, Private: privateStore}, nil
+	default:
+		return Stores{}, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// cleanKey menormalkan key dan menolak key yang bisa keluar dari bucket
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,9 @@
+package utils
+
+import "strings"
+
+// NormalizeEmail menyeragamkan email sebelum disimpan atau dicari, sehingga "A@x.com" dan
+// " a@x.com" merujuk ke akun yang sama
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 )
 
@@ -17,3 +19,9 @@ func GenerateRandomToken(length int) string {
 
 	return base64.RawURLEncoding.EncodeToString(token)[:length]
 }
+
+// HashToken menghasilkan hash SHA-256 dari token agar token tidak disimpan dalam bentuk asli
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
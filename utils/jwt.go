@@ -4,13 +4,10 @@ import (
 	"errors"
 	"log"
 	"os"
-	"strings"
 	"time"
 
-	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/joho/godotenv"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func init() {
@@ -24,14 +21,30 @@ func init() {
 // Inisialisasi JWT secret dari environment
 var jwtSecret = []byte(os.Getenv("JWT_SECRET_KEY"))
 
-const tokenExpiry = 24 * time.Hour // Token valid selama 24 jam
+const tokenExpiry = 15 * time.Minute // Access token hanya berlaku 15 menit, diperpanjang lewat refresh token
+
+const (
+	challengeExpiry  = 5 * time.Minute // Batas waktu memasukkan kode 2FA setelah password benar
+	challengePurpose = "2fa_challenge"
+)
+
+// GenerateJWT membuat dan menandatangani JWT token.
+// tokenVersion dicocokkan dengan users.token_version agar token bisa dicabut sebelum expired.
+// mfa menandai bahwa sesi ini sudah lolos verifikasi 2FA.
+func GenerateJWT(userID string, roles []string, sellerID string, tokenVersion int, mfa bool) (string, error) {
+    // Klaim "role" tetap diisi role pertama agar FE lama tetap berjalan
+    var role string
+    if len(roles) > 0 {
+        role = roles[0]
+    }
 
-// GenerateJWT membuat dan menandatangani JWT token
-func GenerateJWT(userID string, role string, sellerID string) (string, error) {
     claims := jwt.MapClaims{
         "user_id": userID,
         "role":    role,
-        "exp":     time.Now().Add(tokenExpiry).Unix(), // Token expired dalam 24 jam
+        "roles":   roles,
+        "ver":     tokenVersion,
+        "mfa":     mfa,
+        "exp":     time.Now().Add(tokenExpiry).Unix(), // Token expired dalam 15 menit
     }
 
     // Tambahkan seller_id jika ada
@@ -47,6 +60,32 @@ func GenerateJWT(userID string, role string, sellerID string) (string, error) {
     return tokenString, nil
 }
 
+// GenerateChallengeJWT membuat token sementara untuk langkah kedua login 2FA.
+// Token ini tidak bisa dipakai sebagai access token.
+func GenerateChallengeJWT(userID string, tokenVersion, challengeVersion int) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"purpose": challengePurpose,
+		"ver":     tokenVersion,
+		"cver":    challengeVersion,
+		"exp":     time.Now().Add(challengeExpiry).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// ValidateChallengeJWT memverifikasi token challenge 2FA dan mengembalikan klaimnya
+func ValidateChallengeJWT(tokenString string) (jwt.MapClaims, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != challengePurpose {
+		return nil, errors.New("invalid challenge token")
+	}
+	return claims, nil
+}
 
 // ValidateJWT memverifikasi JWT token dan mengembalikan klaim jika valid
 func ValidateJWT(tokenString string) (jwt.MapClaims, error) {
@@ -62,8 +101,11 @@ func ValidateJWT(tokenString string) (jwt.MapClaims, error) {
 		return nil, err
 	}
 
-	// Return klaim jika valid
+	// Return klaim jika valid; token dengan purpose khusus (challenge 2FA) bukan access token
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		if _, special := claims["purpose"]; special {
+			return nil, jwt.NewValidationError("not an access token", jwt.ValidationErrorClaimsInvalid)
+		}
 		return claims, nil
 	}
 	return nil, jwt.NewValidationError("invalid token", jwt.ValidationErrorClaimsInvalid)
@@ -89,36 +131,3 @@ func ParseToken(tokenString string) (jwt.MapClaims, error) {
 	}
 	return nil, errors.New("invalid token")
 }
-
-func GetUserIDFromToken(c *fiber.Ctx) (primitive.ObjectID, error) {
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return primitive.NilObjectID, errors.New("authorization header missing")
-	}
-
-	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte("SECRET_KEY"), nil // Ganti dengan SECRET KEY JWT Anda
-	})
-
-	if err != nil || !token.Valid {
-		return primitive.NilObjectID, errors.New("invalid token")
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return primitive.NilObjectID, errors.New("invalid token claims")
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		return primitive.NilObjectID, errors.New("user ID not found in token")
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		return primitive.NilObjectID, errors.New("invalid user ID format")
-	}
-
-	return userID, nil
-}
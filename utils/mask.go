@@ -0,0 +1,12 @@
+package utils
+
+import "strings"
+
+// MaskEmail menyamarkan email untuk keperluan log, contoh: "john@mail.com" -> "j***@mail.com"
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// Secret RFC 6238 Appendix B untuk SHA-1: ASCII "12345678901234567890"
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// Vektor uji RFC 6238 Appendix B (SHA-1), dipotong menjadi 6 digit terakhir sesuai totpDigits
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		code, err := TOTPCode(rfc6238Secret, v.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", v.unix, err)
+		}
+		if code != v.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", v.unix, code, v.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		now := time.Unix(v.unix, 0)
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, now)
+		if !ok || step != v.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%s at %d) = %d, %v; want %d, true", v.code, v.unix, step, ok, v.unix/totpPeriod)
+		}
+	}
+
+	now := time.Unix(1111111111, 0)
+	// Kode dari periode sebelum/sesudah masih diterima (toleransi jam), dua periode tidak
+	for offset, want := range map[int64]bool{-totpPeriod: true, totpPeriod: true, -2 * totpPeriod: false, 2 * totpPeriod: false} {
+		code, _ := TOTPCode(rfc6238Secret, (now.Unix()+offset)/totpPeriod)
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now); ok != want {
+			t.Errorf("ValidateTOTP with offset %ds = %v, want %v", offset, ok, want)
+		}
+	}
+
+	for _, code := range []string{"", "12345", "1234567", "000000"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("ValidateTOTP(%q) accepted an invalid code", code)
+		}
+	}
+}
+
+func TestValidateTOTPAfterRejectsReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code := "005924"
+
+	step, ok := ValidateTOTPAfter(rfc6238Secret, code, 0, now)
+	if !ok {
+		t.Fatal("first use of a valid code was rejected")
+	}
+	// Setelah login berhasil step disimpan sebagai totp_last_step; kode yang sama ditolak
+	// selama masih dalam jendela toleransi
+	for _, later := range []time.Time{now, now.Add(totpPeriod * time.Second)} {
+		if _, ok := ValidateTOTPAfter(rfc6238Secret, code, step, later); ok {
+			t.Errorf("code replayed at %d was accepted", later.Unix())
+		}
+	}
+
+	// Kode periode berikutnya tetap diterima
+	next, _ := TOTPCode(rfc6238Secret, step+1)
+	if got, ok := ValidateTOTPAfter(rfc6238Secret, next, step, now.Add(totpPeriod*time.Second)); !ok || got != step+1 {
+		t.Errorf("next code = %d, %v; want %d, true", got, ok, step+1)
+	}
+	// Kode dari periode sebelumnya yang belum dipakai juga ditolak setelah step yang lebih baru dipakai
+	previous, _ := TOTPCode(rfc6238Secret, step-1)
+	if _, ok := ValidateTOTPAfter(rfc6238Secret, previous, step, now); ok {
+		t.Error("code older than totp_last_step was accepted")
+	}
+}